@@ -0,0 +1,159 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package colors holds the named color presets used by the shortcut routes.
+package colors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// defaults are the presets shipped with the server
+var defaults = map[string]govee.Color{
+	"red":      {R: 255, G: 0, B: 0},
+	"yellow":   {R: 255, G: 255, B: 0},
+	"orange":   {R: 139, G: 64, B: 0},
+	"dark-red": {R: 255, G: 11, B: 0},
+}
+
+// Table maps preset names to colors. It is read-only once built.
+type Table struct {
+	colors map[string]govee.Color
+}
+
+// rgb is the JSON form of a color in COLORS_FILE
+type rgb struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// Default returns a table containing only the built-in presets
+func Default() *Table {
+	t := &Table{colors: make(map[string]govee.Color, len(defaults))}
+	for name, color := range defaults {
+		t.colors[name] = color
+	}
+	return t
+}
+
+// Load builds a table from the defaults, then applies entries from the JSON file at path
+// (if set), then the inline overrides (if set). Later sources win.
+//
+// The file is a JSON object such as {"orange": {"r": 255, "g": 140, "b": 0}}.
+// Inline overrides use the form "orange=255,140,0;teal=0,128,128".
+func Load(overrides string, path string) (*Table, error) {
+	t := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read colors file: %w", err)
+		}
+		var entries map[string]rgb
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse colors file: %w", err)
+		}
+		for name, c := range entries {
+			if err := t.set(name, c.R, c.G, c.B); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if overrides != "" {
+		for _, entry := range strings.Split(overrides, ";") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid color override %q: expected name=r,g,b", entry)
+			}
+			parts := strings.Split(value, ",")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("invalid color override %q: expected name=r,g,b", entry)
+			}
+			var values [3]int
+			for i, part := range parts {
+				v, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return nil, fmt.Errorf("invalid color override %q: %w", entry, err)
+				}
+				values[i] = v
+			}
+			if err := t.set(name, values[0], values[1], values[2]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// set validates and stores a color under a normalized name
+func (t *Table) set(name string, r, g, b int) error {
+	name = normalize(name)
+	if name == "" {
+		return fmt.Errorf("color name must not be empty")
+	}
+	for _, v := range []int{r, g, b} {
+		if v < 0 || v > 255 {
+			return fmt.Errorf("color %q: RGB values must be between 0 and 255", name)
+		}
+	}
+	t.colors[name] = govee.Color{R: uint(r), G: uint(g), B: uint(b)}
+	return nil
+}
+
+// Lookup returns the color registered under name
+func (t *Table) Lookup(name string) (govee.Color, bool) {
+	color, ok := t.colors[normalize(name)]
+	return color, ok
+}
+
+// Names returns the registered preset names in sorted order
+func (t *Table) Names() []string {
+	names := make([]string, 0, len(t.colors))
+	for name := range t.colors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseHex parses a color written as "#rrggbb"
+func ParseHex(value string) (govee.Color, error) {
+	hex, ok := strings.CutPrefix(strings.TrimSpace(value), "#")
+	if !ok || len(hex) != 6 {
+		return govee.Color{}, fmt.Errorf("invalid hex color %q: expected #rrggbb", value)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return govee.Color{}, fmt.Errorf("invalid hex color %q: expected #rrggbb", value)
+	}
+	return govee.Color{R: uint(n >> 16 & 0xff), G: uint(n >> 8 & 0xff), B: uint(n & 0xff)}, nil
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
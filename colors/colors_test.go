@@ -0,0 +1,116 @@
+package colors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestDefault(t *testing.T) {
+	table := Default()
+
+	tests := []struct {
+		name     string
+		expected govee.Color
+	}{
+		{"red", govee.Color{R: 255, G: 0, B: 0}},
+		{"yellow", govee.Color{R: 255, G: 255, B: 0}},
+		{"orange", govee.Color{R: 139, G: 64, B: 0}},
+		{"dark-red", govee.Color{R: 255, G: 11, B: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			color, ok := table.Lookup(tt.name)
+			if !ok {
+				t.Fatalf("expected %s to be defined", tt.name)
+			}
+			if color != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, color)
+			}
+		})
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	table, err := Load("orange=255,140,0; Teal=0,128,128", "")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if color, _ := table.Lookup("orange"); color != (govee.Color{R: 255, G: 140, B: 0}) {
+		t.Errorf("expected orange override, got %v", color)
+	}
+	if color, ok := table.Lookup("teal"); !ok || color != (govee.Color{R: 0, G: 128, B: 128}) {
+		t.Errorf("expected teal to be added, got %v", color)
+	}
+	if _, ok := table.Lookup("red"); !ok {
+		t.Errorf("expected defaults to be kept")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "colors.json")
+	if err := os.WriteFile(path, []byte(`{"purple": {"r": 128, "g": 0, "b": 128}, "red": {"r": 200, "g": 0, "b": 0}}`), 0o600); err != nil {
+		t.Fatalf("failed to write colors file: %v", err)
+	}
+
+	// Inline overrides take precedence over the file
+	table, err := Load("red=180,0,0", path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if color, _ := table.Lookup("purple"); color != (govee.Color{R: 128, G: 0, B: 128}) {
+		t.Errorf("expected purple from file, got %v", color)
+	}
+	if color, _ := table.Lookup("red"); color != (govee.Color{R: 180, G: 0, B: 0}) {
+		t.Errorf("expected inline override to win, got %v", color)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+	}{
+		{"out of range", "orange=256,0,0"},
+		{"negative", "orange=-1,0,0"},
+		{"missing component", "orange=255,0"},
+		{"not a number", "orange=a,b,c"},
+		{"missing equals", "orange"},
+		{"empty name", "=1,2,3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.overrides, ""); err == nil {
+				t.Errorf("expected error for %q", tt.overrides)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load("", filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	color, err := ParseHex("#FF8000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if color != (govee.Color{R: 255, G: 128, B: 0}) {
+		t.Errorf("expected rgb(255,128,0), got %+v", color)
+	}
+
+	for _, value := range []string{"ff8000", "#ff80", "#gg8000", "#ff800000"} {
+		if _, err := ParseHex(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
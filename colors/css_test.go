@@ -0,0 +1,60 @@
+package colors
+
+import (
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestCSS(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  govee.Color
+		canonical string
+		ok        bool
+	}{
+		{"tomato", govee.Color{R: 255, G: 99, B: 71}, "tomato", true},
+		{"CornflowerBlue", govee.Color{R: 100, G: 149, B: 237}, "cornflowerblue", true},
+		{"cornflower blue", govee.Color{R: 100, G: 149, B: 237}, "cornflowerblue", true},
+		{"light-slate_grey", govee.Color{R: 119, G: 136, B: 153}, "lightslategrey", true},
+		{"rebeccapurple", govee.Color{R: 102, G: 51, B: 153}, "rebeccapurple", true},
+		{"notacolor", govee.Color{}, "notacolor", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			color, canonical, ok := CSS(tt.name)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %v, got %v", tt.ok, ok)
+			}
+			if color != tt.expected || canonical != tt.canonical {
+				t.Errorf("expected %s %v, got %s %v", tt.canonical, tt.expected, canonical, color)
+			}
+		})
+	}
+
+	if len(css) != 148 {
+		t.Errorf("expected the 148 CSS color names, got %d", len(css))
+	}
+}
+
+func TestSuggestCSS(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"tomatoe", "tomato"},
+		{"cornflowerblu", "cornflowerblue"},
+		{"Turqoise", "turquoise"},
+		{"xyzzyplugh", ""},
+		{"abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestCSS(tt.name); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -16,23 +16,209 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"net/url"
 	"os"
-
-	"github.com/joho/godotenv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// maxDeviceOpDelay caps DEVICE_OP_DELAY so queued commands can't stall for long
+const maxDeviceOpDelay = 2 * time.Second
+
+// maxPowerOnFadeMs caps POWER_ON_FADE_MS, matching the limit on fades requested through the API
+const maxPowerOnFadeMs = 10000
+
+// maxDeviceOpRetries caps DEVICE_OP_RETRIES; backoff doubles with every attempt
+const maxDeviceOpRetries = 5
+
 // Config holds server and auth configuration
 type Config struct {
-	Host        string
-	Port        string
-	MetricsPort string
-	BearerToken string
+	Host           string
+	Port           string
+	MetricsHost    string
+	MetricsPort    string
+	BearerToken    string
+	StreamInterval time.Duration
+
+	// ReadTimeout bounds reading a request, headers included
+	ReadTimeout time.Duration
+
+	// WriteTimeout bounds writing a response; streams lift it for their connection
+	WriteTimeout time.Duration
+
+	// IdleTimeout is how long a keep-alive connection may sit idle
+	IdleTimeout time.Duration
+
+	// StatusCacheTTL is how long a device's status is reused by status reads; 0 disables the cache
+	StatusCacheTTL time.Duration
+
+	// BearerTokenScopes maps extra tokens to the one scope each grants, "read" or "write";
+	// BearerToken keeps full access
+	BearerTokenScopes map[string]string
+
+	// MetricsBearerToken, when set, is required as a bearer token on /metrics. It is
+	// independent of BearerToken.
+	MetricsBearerToken string
+
+	// MetricsNamespace prefixes every metric name, e.g. "lights" for lights_http_requests_total
+	MetricsNamespace string
+	// InstanceName, when set, labels every metric so deployments can be told apart
+	InstanceName string
+
+	// EnablePprof serves net/http/pprof under /debug/pprof/ on the metrics server
+	EnablePprof bool
+
+	// AllowBasicAuth also accepts HTTP Basic credentials with the bearer token as the password
+	AllowBasicAuth bool
+
+	// HMACSecret, when set, authenticates requests signed with an X-Signature header
+	HMACSecret string
+
+	// IdempotencyTTL is how long responses are kept for replay by Idempotency-Key
+	IdempotencyTTL time.Duration
+
+	// DeviceOpDelay is the gap between consecutive commands sent to any of the devices
+	DeviceOpDelay time.Duration
+	// CoalesceCommands drops a color, color temperature or brightness command still waiting
+	// for a device when a newer one of the same kind arrives
+	CoalesceCommands bool
+
+	// DeviceOpRetries is how many times a failed device command is retried
+	DeviceOpRetries int
+
+	// BreakerThreshold is how many commands in a row must fail before a device's circuit
+	// breaker opens; 0 disables the breakers
+	BreakerThreshold int
+	// BreakerCooldown is how long an open breaker short-circuits commands before letting
+	// one through to probe the device
+	BreakerCooldown time.Duration
+
+	// ControllerStartAttempts is how many times starting the govee controller is tried
+	ControllerStartAttempts int
+
+	// ControllerStartBackoff is the pause after the first failed start; it doubles after each failure
+	ControllerStartBackoff time.Duration
+
+	// WarmupTimeout holds /ready at 503 after startup until a device is discovered or this
+	// long has passed; 0 disables the wait
+	WarmupTimeout time.Duration
+
+	// HealthCacheTTL is how long the health check reuses its device reachability result;
+	// 0 probes the devices on every check
+	HealthCacheTTL time.Duration
+
+	// DryRun logs light operations instead of sending them to the devices
+	DryRun bool
+
+	// AllowNoDevices lets light operations succeed while no devices have been discovered
+	AllowNoDevices bool
+
+	// DisabledDevices are device IDs skipped by every operation and status read at startup;
+	// they can be re-enabled at runtime
+	DisabledDevices []string
+
+	// TurnOffOnShutdown turns every device off when the server stops
+	TurnOffOnShutdown bool
+
+	// Latitude and Longitude locate the lights for sunrise/sunset mode; nil when unset.
+	// SunAuto turns the mode on at startup.
+	Latitude  *float64
+	Longitude *float64
+	SunAuto   bool
+
+	// DefaultState ("off", "on", "warm", "#rrggbb" or a color preset) is applied to every
+	// device after discovery at startup; empty leaves the lights alone
+	DefaultState string
+
+	// PollInterval is how often device status is refreshed in the background; 0 disables polling
+	PollInterval time.Duration
+
+	// BasePath prefixes every API route, e.g. "/api/v1"; BasePathExemptHealth keeps the
+	// health probes at the root as well
+	BasePath             string
+	BasePathExemptHealth bool
+
+	// NotFoundRedirectURL is where browsers are sent for unknown routes
+	NotFoundRedirectURL string
+
+	// LogOutput is "stdout", "file" or "both"; LogFile is rotated once it reaches
+	// LogMaxSizeMB, keeping LogMaxBackups old files for up to LogMaxAgeDays
+	LogOutput     string
+	LogFile       string
+	LogMaxSizeMB  int
+	LogMaxBackups int
+	LogMaxAgeDays int
+
+	// LogLevel is the lowest level logged; at debug, request bodies are logged too
+	LogLevel slog.Level
+
+	// LogSampleRate is how many successful requests are logged per second before only a
+	// sample of them is; 0 logs every request
+	LogSampleRate int
+
+	// ExcludedPaths are served without request logs or HTTP metrics
+	ExcludedPaths []string
+
+	// TrustedProxies are the IPs or CIDRs of reverse proxies whose X-Forwarded-For header is
+	// believed when working out a request's client IP
+	TrustedProxies []string
+
+	// TLSCertFile and TLSKeyFile enable HTTPS on the API port; both must be set together
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ACMEDomains enables automatic Let's Encrypt certificates for these hosts; certificates
+	// are cached in ACMECacheDir. Mutually exclusive with TLSCertFile/TLSKeyFile.
+	ACMEDomains  []string
+	ACMECacheDir string
+	ACMEEmail    string
+
+	// MQTT bridge settings; the bridge is disabled when MQTTBroker is empty
+	MQTTBroker      string
+	MQTTClientID    string
+	MQTTUsername    string
+	MQTTPassword    string
+	MQTTTopicPrefix string
+
+	// HADiscovery publishes Home Assistant MQTT discovery configs for each device
+	HADiscovery       bool
+	HADiscoveryPrefix string
+
+	// WebhookURL receives a POST for every light operation; disabled when empty
+	WebhookURL string
+
+	// ColorOverrides ("name=r,g,b;...") and ColorsFile (JSON) customize the named color presets
+	ColorOverrides string
+	ColorsFile     string
+
+	// TransitionSteps is how many intermediate colors a fade sends
+	TransitionSteps int
+
+	// PowerOnFadeMs is how long turning lights on ramps brightness up by default; 0 snaps on
+	PowerOnFadeMs int
+
+	// Groups defines named device subsets, e.g. "desk=id1,id2;shelf=id3"
+	Groups string
+
+	// DeviceAliases names devices, e.g. "A1:B2:...=desk-lamp,C3:D4:...=shelf"; the names
+	// can be used wherever a device ID is accepted
+	DeviceAliases string
+
+	// SchedulesFile is where schedules are saved; empty keeps them in memory only
+	SchedulesFile string
 }
 
 // Load loads configuration from environment variables and .env (if not production)
 func Load() (*Config, error) {
 	if os.Getenv("GO_ENV") != "production" {
-		_ = godotenv.Load()
+		loadDotenv()
 	}
 
 	host := os.Getenv("HOSTNAME")
@@ -43,19 +229,621 @@ func Load() (*Config, error) {
 	if port == "" {
 		port = "8080"
 	}
+	metricsHost := os.Getenv("METRICS_HOST")
+	if metricsHost == "" {
+		metricsHost = host
+	}
 	metricsPort := os.Getenv("METRICS_PORT")
 	if metricsPort == "" {
 		metricsPort = "9090"
 	}
-	token := os.Getenv("BEARER_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("BEARER_TOKEN is required. Please set it in your environment or .env file")
+	metricsNamespace := os.Getenv("METRICS_NAMESPACE")
+	if metricsNamespace == "" {
+		metricsNamespace = "lights"
+	}
+	allowBasicAuth, err := boolEnv("ALLOW_BASIC_AUTH", false)
+	if err != nil {
+		return nil, err
+	}
+	enablePprof, err := boolEnv("ENABLE_PPROF", false)
+	if err != nil {
+		return nil, err
+	}
+	streamInterval, err := durationEnv("STREAM_INTERVAL", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	readTimeout, err := durationEnv("READ_TIMEOUT", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	writeTimeout, err := durationEnv("WRITE_TIMEOUT", 60*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := durationEnv("IDLE_TIMEOUT", 120*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	statusCacheTTL, err := durationEnv("STATUS_CACHE_TTL", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	idempotencyTTL, err := durationEnv("IDEMPOTENCY_TTL", 60*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	bearerTokenScopes, err := scopesEnv("BEARER_TOKEN_SCOPES")
+	if err != nil {
+		return nil, err
+	}
+	// The gap between commands helps avoid "channel blocked or closed" errors
+	deviceOpDelay, err := durationEnv("DEVICE_OP_DELAY", 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	coalesceCommands, err := boolEnv("COALESCE_COMMANDS", false)
+	if err != nil {
+		return nil, err
+	}
+	deviceOpRetries, err := intEnv("DEVICE_OP_RETRIES", 2)
+	if err != nil {
+		return nil, err
+	}
+	breakerThreshold, err := intEnv("CIRCUIT_BREAKER_THRESHOLD", 5)
+	if err != nil {
+		return nil, err
+	}
+	breakerCooldown, err := durationEnv("CIRCUIT_BREAKER_COOLDOWN", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	controllerStartAttempts, err := intEnv("CONTROLLER_START_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	controllerStartBackoff, err := durationEnv("CONTROLLER_START_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	warmupTimeout, err := durationEnv("WARMUP_TIMEOUT", 0)
+	if err != nil {
+		return nil, err
+	}
+	healthCacheTTL, err := durationEnv("HEALTH_CACHE_TTL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	dryRun, err := boolEnv("DRY_RUN", false)
+	if err != nil {
+		return nil, err
+	}
+	allowNoDevices, err := boolEnv("ALLOW_NO_DEVICES", false)
+	if err != nil {
+		return nil, err
+	}
+	// An explicitly empty SCHEDULES_FILE keeps schedules in memory only
+	schedulesFile, ok := os.LookupEnv("SCHEDULES_FILE")
+	if !ok {
+		schedulesFile = "schedules.json"
+	}
+	latitude, err := floatEnv("LATITUDE")
+	if err != nil {
+		return nil, err
+	}
+	longitude, err := floatEnv("LONGITUDE")
+	if err != nil {
+		return nil, err
+	}
+	sunAuto, err := boolEnv("SUN_AUTO", false)
+	if err != nil {
+		return nil, err
+	}
+	turnOffOnShutdown, err := boolEnv("TURN_OFF_ON_SHUTDOWN", false)
+	if err != nil {
+		return nil, err
+	}
+	pollInterval, err := durationEnv("POLL_INTERVAL", 0)
+	if err != nil {
+		return nil, err
+	}
+	basePathExemptHealth, err := boolEnv("BASE_PATH_EXEMPT_HEALTH", false)
+	if err != nil {
+		return nil, err
+	}
+	notFoundRedirectURL := os.Getenv("NOT_FOUND_REDIRECT_URL")
+	if notFoundRedirectURL == "" {
+		notFoundRedirectURL = "https://xkcd.com/random/"
+	}
+	logFile := os.Getenv("LOG_FILE")
+	logOutput := os.Getenv("LOG_OUTPUT")
+	if logOutput == "" {
+		logOutput = "stdout"
+		if logFile != "" {
+			logOutput = "both"
+		}
+	}
+	logMaxSizeMB, err := intEnv("LOG_MAX_SIZE_MB", 100)
+	if err != nil {
+		return nil, err
+	}
+	logMaxBackups, err := intEnv("LOG_MAX_BACKUPS", 3)
+	if err != nil {
+		return nil, err
+	}
+	logMaxAgeDays, err := intEnv("LOG_MAX_AGE_DAYS", 28)
+	if err != nil {
+		return nil, err
+	}
+	var logLevel slog.Level
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := logLevel.UnmarshalText([]byte(v)); err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", v)
+		}
+	}
+	logSampleRate, err := intEnv("LOG_SAMPLE_RATE", 0)
+	if err != nil {
+		return nil, err
+	}
+	// An explicitly empty EXCLUDED_PATHS logs and measures every path
+	excludedPaths := []string{"/health", "/ready", "/live"}
+	if _, ok := os.LookupEnv("EXCLUDED_PATHS"); ok {
+		excludedPaths = listEnv("EXCLUDED_PATHS")
+	}
+	transitionSteps, err := intEnv("TRANSITION_STEPS", 20)
+	if err != nil {
+		return nil, err
+	}
+	powerOnFadeMs, err := intEnv("POWER_ON_FADE_MS", 0)
+	if err != nil {
+		return nil, err
+	}
+	acmeCacheDir := os.Getenv("ACME_CACHE_DIR")
+	if acmeCacheDir == "" {
+		acmeCacheDir = "acme-cache"
+	}
+	mqttTopicPrefix := os.Getenv("MQTT_TOPIC_PREFIX")
+	if mqttTopicPrefix == "" {
+		mqttTopicPrefix = "lights"
+	}
+	haDiscovery, err := boolEnv("HA_DISCOVERY", false)
+	if err != nil {
+		return nil, err
+	}
+	haDiscoveryPrefix := os.Getenv("HA_DISCOVERY_PREFIX")
+	if haDiscoveryPrefix == "" {
+		haDiscoveryPrefix = "homeassistant"
+	}
+
+	cfg := &Config{
+		Host:                    host,
+		Port:                    port,
+		MetricsHost:             metricsHost,
+		MetricsPort:             metricsPort,
+		MetricsBearerToken:      os.Getenv("METRICS_BEARER_TOKEN"),
+		MetricsNamespace:        metricsNamespace,
+		InstanceName:            os.Getenv("INSTANCE_NAME"),
+		EnablePprof:             enablePprof,
+		BearerToken:             os.Getenv("BEARER_TOKEN"),
+		BearerTokenScopes:       bearerTokenScopes,
+		AllowBasicAuth:          allowBasicAuth,
+		HMACSecret:              os.Getenv("HMAC_SECRET"),
+		StreamInterval:          streamInterval,
+		ReadTimeout:             readTimeout,
+		WriteTimeout:            writeTimeout,
+		IdleTimeout:             idleTimeout,
+		StatusCacheTTL:          statusCacheTTL,
+		IdempotencyTTL:          idempotencyTTL,
+		DeviceOpDelay:           deviceOpDelay,
+		CoalesceCommands:        coalesceCommands,
+		DeviceOpRetries:         deviceOpRetries,
+		BreakerThreshold:        breakerThreshold,
+		BreakerCooldown:         breakerCooldown,
+		ControllerStartAttempts: controllerStartAttempts,
+		ControllerStartBackoff:  controllerStartBackoff,
+		WarmupTimeout:           warmupTimeout,
+		HealthCacheTTL:          healthCacheTTL,
+		DryRun:                  dryRun,
+		AllowNoDevices:          allowNoDevices,
+		DisabledDevices:         listEnv("DISABLED_DEVICES"),
+		TurnOffOnShutdown:       turnOffOnShutdown,
+		DefaultState:            strings.TrimSpace(os.Getenv("DEFAULT_STATE")),
+		Latitude:                latitude,
+		Longitude:               longitude,
+		SunAuto:                 sunAuto,
+		PollInterval:            pollInterval,
+
+		BasePath:             strings.TrimRight(os.Getenv("BASE_PATH"), "/"),
+		BasePathExemptHealth: basePathExemptHealth,
+
+		NotFoundRedirectURL: notFoundRedirectURL,
+
+		LogOutput:     logOutput,
+		LogFile:       logFile,
+		LogMaxSizeMB:  logMaxSizeMB,
+		LogMaxBackups: logMaxBackups,
+		LogMaxAgeDays: logMaxAgeDays,
+		LogLevel:      logLevel,
+		LogSampleRate: logSampleRate,
+		ExcludedPaths: excludedPaths,
+
+		TrustedProxies: listEnv("TRUSTED_PROXIES"),
+
+		TLSCertFile: os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:  os.Getenv("TLS_KEY_FILE"),
+
+		ACMEDomains:  listEnv("ACME_DOMAINS"),
+		ACMECacheDir: acmeCacheDir,
+		ACMEEmail:    os.Getenv("ACME_EMAIL"),
+
+		MQTTBroker:      os.Getenv("MQTT_BROKER"),
+		MQTTClientID:    os.Getenv("MQTT_CLIENT_ID"),
+		MQTTUsername:    os.Getenv("MQTT_USERNAME"),
+		MQTTPassword:    os.Getenv("MQTT_PASSWORD"),
+		MQTTTopicPrefix: mqttTopicPrefix,
+
+		HADiscovery:       haDiscovery,
+		HADiscoveryPrefix: haDiscoveryPrefix,
+
+		WebhookURL: os.Getenv("WEBHOOK_URL"),
+
+		ColorOverrides: os.Getenv("COLOR_OVERRIDES"),
+		ColorsFile:     os.Getenv("COLORS_FILE"),
+
+		TransitionSteps: transitionSteps,
+		PowerOnFadeMs:   powerOnFadeMs,
+
+		Groups:        os.Getenv("GROUPS"),
+		DeviceAliases: os.Getenv("DEVICE_ALIASES"),
+
+		SchedulesFile: schedulesFile,
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// metricsNamespacePattern is a valid Prometheus metric name prefix
+var metricsNamespacePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Validate checks that the configuration is complete and every value is in range
+func (c *Config) Validate() error {
+	if c.BearerToken == "" {
+		return fmt.Errorf("BEARER_TOKEN is required. Please set it in your environment or .env file")
+	}
+	for token, scope := range c.BearerTokenScopes {
+		if scope != "read" && scope != "write" {
+			return fmt.Errorf("BEARER_TOKEN_SCOPES scopes must be read or write, got %q", scope)
+		}
+		if token == c.BearerToken {
+			return fmt.Errorf("BEARER_TOKEN_SCOPES must not list BEARER_TOKEN, which already has full access")
+		}
+	}
+	if !metricsNamespacePattern.MatchString(c.MetricsNamespace) {
+		return fmt.Errorf("METRICS_NAMESPACE must start with a letter or underscore and contain only letters, digits and underscores, got %q", c.MetricsNamespace)
+	}
+	if !utf8.ValidString(c.InstanceName) {
+		return fmt.Errorf("INSTANCE_NAME must be valid UTF-8, got %q", c.InstanceName)
+	}
+	if c.StreamInterval <= 0 {
+		return fmt.Errorf("STREAM_INTERVAL must be a positive duration (e.g. 5s), got %s", c.StreamInterval)
+	}
+	if c.ReadTimeout <= 0 {
+		return fmt.Errorf("READ_TIMEOUT must be a positive duration (e.g. 15s), got %s", c.ReadTimeout)
+	}
+	if c.WriteTimeout <= 0 {
+		return fmt.Errorf("WRITE_TIMEOUT must be a positive duration (e.g. 60s), got %s", c.WriteTimeout)
+	}
+	if c.IdleTimeout <= 0 {
+		return fmt.Errorf("IDLE_TIMEOUT must be a positive duration (e.g. 120s), got %s", c.IdleTimeout)
+	}
+	if c.StatusCacheTTL < 0 {
+		return fmt.Errorf("STATUS_CACHE_TTL must be a non-negative duration (e.g. 5s), got %s", c.StatusCacheTTL)
+	}
+	if c.IdempotencyTTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must be a positive duration (e.g. 60s), got %s", c.IdempotencyTTL)
+	}
+	if c.DeviceOpDelay < 0 || c.DeviceOpDelay > maxDeviceOpDelay {
+		return fmt.Errorf("DEVICE_OP_DELAY must be a duration between 0 and %s (e.g. 100ms), got %s", maxDeviceOpDelay, c.DeviceOpDelay)
+	}
+	if c.DeviceOpRetries < 0 || c.DeviceOpRetries > maxDeviceOpRetries {
+		return fmt.Errorf("DEVICE_OP_RETRIES must be between 0 and %d, got %d", maxDeviceOpRetries, c.DeviceOpRetries)
+	}
+	if c.BreakerThreshold < 0 {
+		return fmt.Errorf("CIRCUIT_BREAKER_THRESHOLD must be non-negative, got %d", c.BreakerThreshold)
+	}
+	if c.BreakerThreshold > 0 && c.BreakerCooldown <= 0 {
+		return fmt.Errorf("CIRCUIT_BREAKER_COOLDOWN must be a positive duration (e.g. 30s), got %s", c.BreakerCooldown)
+	}
+	if c.ControllerStartAttempts < 1 {
+		return fmt.Errorf("CONTROLLER_START_ATTEMPTS must be at least 1, got %d", c.ControllerStartAttempts)
+	}
+	if c.ControllerStartBackoff <= 0 {
+		return fmt.Errorf("CONTROLLER_START_BACKOFF must be a positive duration (e.g. 1s), got %s", c.ControllerStartBackoff)
+	}
+	if c.WarmupTimeout < 0 {
+		return fmt.Errorf("WARMUP_TIMEOUT must be a non-negative duration (e.g. 30s), got %s", c.WarmupTimeout)
+	}
+	if c.HealthCacheTTL < 0 {
+		return fmt.Errorf("HEALTH_CACHE_TTL must be a non-negative duration (e.g. 10s), got %s", c.HealthCacheTTL)
+	}
+	if c.PollInterval < 0 {
+		return fmt.Errorf("POLL_INTERVAL must be a non-negative duration (e.g. 30s), got %s", c.PollInterval)
+	}
+	if (c.Latitude == nil) != (c.Longitude == nil) {
+		return fmt.Errorf("LATITUDE and LONGITUDE must be set together")
+	}
+	if c.Latitude != nil && (*c.Latitude < -90 || *c.Latitude > 90 || *c.Longitude < -180 || *c.Longitude > 180) {
+		return fmt.Errorf("LATITUDE must be between -90 and 90 and LONGITUDE between -180 and 180, got %g, %g", *c.Latitude, *c.Longitude)
+	}
+	if c.SunAuto && c.Latitude == nil {
+		return fmt.Errorf("SUN_AUTO requires LATITUDE and LONGITUDE to be set")
+	}
+	if c.BasePath != "" && (!strings.HasPrefix(c.BasePath, "/") || strings.ContainsAny(c.BasePath, " ?#{}")) {
+		return fmt.Errorf("BASE_PATH must be a path starting with / (e.g. /api/v1), got %q", c.BasePath)
+	}
+	if u, err := url.Parse(c.NotFoundRedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("NOT_FOUND_REDIRECT_URL must be an absolute URL, got %q", c.NotFoundRedirectURL)
+	}
+	switch c.LogOutput {
+	case "stdout":
+	case "file", "both":
+		if c.LogFile == "" {
+			return fmt.Errorf("LOG_OUTPUT=%s requires LOG_FILE to be set", c.LogOutput)
+		}
+	default:
+		return fmt.Errorf("LOG_OUTPUT must be stdout, file or both, got %q", c.LogOutput)
+	}
+	if c.LogMaxSizeMB < 0 || c.LogMaxBackups < 0 || c.LogMaxAgeDays < 0 {
+		return fmt.Errorf("LOG_MAX_SIZE_MB, LOG_MAX_BACKUPS and LOG_MAX_AGE_DAYS must not be negative")
+	}
+	if c.LogSampleRate < 0 {
+		return fmt.Errorf("LOG_SAMPLE_RATE must not be negative, got %d", c.LogSampleRate)
+	}
+	for _, path := range c.ExcludedPaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("EXCLUDED_PATHS entries must start with /, got %q", path)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("TRUSTED_PROXIES entries must be IP addresses or CIDRs, got %q", proxy)
+		}
+	}
+	if c.PowerOnFadeMs < 0 || c.PowerOnFadeMs > maxPowerOnFadeMs {
+		return fmt.Errorf("POWER_ON_FADE_MS must be between 0 and %d, got %d", maxPowerOnFadeMs, c.PowerOnFadeMs)
 	}
+	if c.TransitionSteps < 1 {
+		return fmt.Errorf("TRANSITION_STEPS must be at least 1")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if len(c.ACMEDomains) > 0 && c.TLSCertFile != "" {
+		return fmt.Errorf("ACME_DOMAINS cannot be combined with TLS_CERT_FILE/TLS_KEY_FILE")
+	}
+	return nil
+}
+
+// redacted replaces secret values in String
+const redacted = "***"
+
+// String summarizes the configuration with every secret replaced by "***", so it is
+// safe to log. Unset secrets are shown as empty.
+func (c *Config) String() string {
+	var b strings.Builder
+	for i, f := range c.fields(redact) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s=%v", f.key, f.value)
+	}
+	return b.String()
+}
+
+// LogValue logs the configuration as one structured field per setting, with secrets
+// redacted as in String, so it can be filtered and queried in log tooling
+func (c *Config) LogValue() slog.Value {
+	fields := c.fields(redact)
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.key, f.value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// field is one named setting in String, LogValue and Changes
+type field struct {
+	key   string
+	value any
+}
+
+// fields lists every setting, passing secrets through hide
+func (c *Config) fields(hide func(secret string) string) []field {
+	return []field{
+		{"host", c.Host},
+		{"port", c.Port},
+		{"metrics_host", c.MetricsHost},
+		{"metrics_port", c.MetricsPort},
+		{"bearer_token", hide(c.BearerToken)},
+		{"bearer_token_scopes", scopesString(c.BearerTokenScopes, hide)},
+		{"metrics_bearer_token", hide(c.MetricsBearerToken)},
+		{"metrics_namespace", c.MetricsNamespace},
+		{"instance_name", c.InstanceName},
+		{"enable_pprof", c.EnablePprof},
+		{"allow_basic_auth", c.AllowBasicAuth},
+		{"hmac_secret", hide(c.HMACSecret)},
+		{"stream_interval", c.StreamInterval},
+		{"read_timeout", c.ReadTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"status_cache_ttl", c.StatusCacheTTL},
+		{"idempotency_ttl", c.IdempotencyTTL},
+		{"device_op_delay", c.DeviceOpDelay},
+		{"coalesce_commands", c.CoalesceCommands},
+		{"device_op_retries", c.DeviceOpRetries},
+		{"circuit_breaker_threshold", c.BreakerThreshold},
+		{"circuit_breaker_cooldown", c.BreakerCooldown},
+		{"controller_start_attempts", c.ControllerStartAttempts},
+		{"controller_start_backoff", c.ControllerStartBackoff},
+		{"warmup_timeout", c.WarmupTimeout},
+		{"health_cache_ttl", c.HealthCacheTTL},
+		{"dry_run", c.DryRun},
+		{"allow_no_devices", c.AllowNoDevices},
+		{"disabled_devices", strings.Join(c.DisabledDevices, ",")},
+		{"turn_off_on_shutdown", c.TurnOffOnShutdown},
+		{"default_state", c.DefaultState},
+		{"latitude", optionalFloat(c.Latitude)},
+		{"longitude", optionalFloat(c.Longitude)},
+		{"sun_auto", c.SunAuto},
+		{"poll_interval", c.PollInterval},
+		{"base_path", c.BasePath},
+		{"base_path_exempt_health", c.BasePathExemptHealth},
+		{"not_found_redirect_url", c.NotFoundRedirectURL},
+		{"log_output", c.LogOutput},
+		{"log_file", c.LogFile},
+		{"log_max_size_mb", c.LogMaxSizeMB},
+		{"log_max_backups", c.LogMaxBackups},
+		{"log_max_age_days", c.LogMaxAgeDays},
+		{"log_level", c.LogLevel},
+		{"log_sample_rate", c.LogSampleRate},
+		{"excluded_paths", strings.Join(c.ExcludedPaths, ",")},
+		{"trusted_proxies", strings.Join(c.TrustedProxies, ",")},
+		{"tls_cert_file", c.TLSCertFile},
+		{"tls_key_file", c.TLSKeyFile},
+		{"acme_domains", strings.Join(c.ACMEDomains, ",")},
+		{"acme_cache_dir", c.ACMECacheDir},
+		{"acme_email", c.ACMEEmail},
+		{"mqtt_broker", c.MQTTBroker},
+		{"mqtt_client_id", c.MQTTClientID},
+		{"mqtt_username", c.MQTTUsername},
+		{"mqtt_password", hide(c.MQTTPassword)},
+		{"mqtt_topic_prefix", c.MQTTTopicPrefix},
+		{"ha_discovery", c.HADiscovery},
+		{"ha_discovery_prefix", c.HADiscoveryPrefix},
+		// Webhook URLs often embed a token in the path
+		{"webhook_url", hide(c.WebhookURL)},
+		{"transition_steps", c.TransitionSteps},
+		{"power_on_fade_ms", c.PowerOnFadeMs},
+		{"color_overrides", c.ColorOverrides},
+		{"colors_file", c.ColorsFile},
+		{"groups", c.Groups},
+		{"device_aliases", c.DeviceAliases},
+		{"schedules_file", c.SchedulesFile},
+	}
+}
+
+// redact hides a secret while still showing whether it is set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// scopesString formats token scopes as "token:scope,...", passing each token through hide
+func scopesString(scopes map[string]string, hide func(secret string) string) string {
+	entries := make([]string, 0, len(scopes))
+	for _, token := range slices.Sorted(maps.Keys(scopes)) {
+		entries = append(entries, hide(token)+":"+scopes[token])
+	}
+	return strings.Join(entries, ",")
+}
+
+// optionalFloat formats an unset coordinate as empty
+func optionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// listEnv reads a comma-separated list from the environment, dropping empty entries
+func listEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// scopesEnv reads comma-separated "token:scope" entries from the environment. Errors name
+// the entry by position rather than quoting it, since it holds a secret.
+func scopesEnv(key string) (map[string]string, error) {
+	entries := listEnv(key)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	scopes := make(map[string]string, len(entries))
+	for i, entry := range entries {
+		// Split on the last colon so a token may contain one
+		colon := strings.LastIndex(entry, ":")
+		if colon <= 0 || colon == len(entry)-1 {
+			return nil, fmt.Errorf("%s entry %d must be token:scope", key, i+1)
+		}
+		token, scope := strings.TrimSpace(entry[:colon]), strings.TrimSpace(entry[colon+1:])
+		if _, ok := scopes[token]; ok {
+			return nil, fmt.Errorf("%s entry %d repeats an earlier token", key, i+1)
+		}
+		scopes[token] = scope
+	}
+	return scopes, nil
+}
 
-	return &Config{
-		Host:        host,
-		Port:        port,
-		MetricsPort: metricsPort,
-		BearerToken: token,
-	}, nil
+// intEnv reads a non-negative integer from the environment, returning def when unset
+func intEnv(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, v)
+	}
+	return n, nil
+}
+
+// durationEnv reads a duration such as "5s" from the environment, returning def when unset
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration (e.g. 5s), got %q", key, v)
+	}
+	return d, nil
+}
+
+// floatEnv reads a number from the environment, returning nil when unset
+func floatEnv(key string) (*float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number, got %q", key, v)
+	}
+	return &f, nil
+}
+
+// boolEnv reads a boolean from the environment, returning def when unset
+func boolEnv(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", key, v)
+	}
+	return b, nil
 }
@@ -1,8 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -20,6 +27,7 @@ func TestLoad(t *testing.T) {
 			wantErr: false,
 			expected: &Config{
 				Host:        "0.0.0.0",
+				MetricsHost: "0.0.0.0",
 				Port:        "8080",
 				BearerToken: "test-token",
 			},
@@ -34,10 +42,115 @@ func TestLoad(t *testing.T) {
 			wantErr: false,
 			expected: &Config{
 				Host:        "127.0.0.1",
+				MetricsHost: "127.0.0.1",
 				Port:        "3000",
 				BearerToken: "custom-token",
 			},
 		},
+		{
+			name: "separate metrics host",
+			env: map[string]string{
+				"HOSTNAME":     "0.0.0.0",
+				"METRICS_HOST": "127.0.0.1",
+				"BEARER_TOKEN": "test-token",
+			},
+			wantErr: false,
+			expected: &Config{
+				Host:        "0.0.0.0",
+				MetricsHost: "127.0.0.1",
+				Port:        "8080",
+				BearerToken: "test-token",
+			},
+		},
+		{
+			name: "TLS cert without key",
+			env: map[string]string{
+				"BEARER_TOKEN":  "test-token",
+				"TLS_CERT_FILE": "/certs/server.crt",
+			},
+			wantErr: true,
+		},
+		{
+			name: "TLS key without cert",
+			env: map[string]string{
+				"BEARER_TOKEN": "test-token",
+				"TLS_KEY_FILE": "/certs/server.key",
+			},
+			wantErr: true,
+		},
+		{
+			name: "ACME domains with TLS files",
+			env: map[string]string{
+				"BEARER_TOKEN":  "test-token",
+				"ACME_DOMAINS":  "lights.example.com",
+				"TLS_CERT_FILE": "/certs/server.crt",
+				"TLS_KEY_FILE":  "/certs/server.key",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative device op delay",
+			env: map[string]string{
+				"BEARER_TOKEN":    "test-token",
+				"DEVICE_OP_DELAY": "-1ms",
+			},
+			wantErr: true,
+		},
+		{
+			name: "device op delay over max",
+			env: map[string]string{
+				"BEARER_TOKEN":    "test-token",
+				"DEVICE_OP_DELAY": "3s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "base path without leading slash",
+			env: map[string]string{
+				"BEARER_TOKEN": "test-token",
+				"BASE_PATH":    "api/v1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown log level",
+			env: map[string]string{
+				"BEARER_TOKEN": "test-token",
+				"LOG_LEVEL":    "verbose",
+			},
+			wantErr: true,
+		},
+		{
+			name: "token scopes",
+			env: map[string]string{
+				"BEARER_TOKEN":        "test-token",
+				"BEARER_TOKEN_SCOPES": "dashboard-token:read, switch-token:write",
+			},
+			wantErr: false,
+			expected: &Config{
+				Host:              "0.0.0.0",
+				MetricsHost:       "0.0.0.0",
+				Port:              "8080",
+				BearerToken:       "test-token",
+				BearerTokenScopes: map[string]string{"dashboard-token": "read", "switch-token": "write"},
+			},
+		},
+		{
+			name: "token scope without a scope",
+			env: map[string]string{
+				"BEARER_TOKEN":        "test-token",
+				"BEARER_TOKEN_SCOPES": "dashboard-token:read,kiosk-token",
+			},
+			wantErr: true,
+		},
+		{
+			name: "repeated scoped token",
+			env: map[string]string{
+				"BEARER_TOKEN":        "test-token",
+				"BEARER_TOKEN_SCOPES": "dashboard-token:read,dashboard-token:write",
+			},
+			wantErr: true,
+		},
 		{
 			name:    "missing bearer token",
 			env:     map[string]string{},
@@ -49,9 +162,17 @@ func TestLoad(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Clear env
 			os.Unsetenv("HOSTNAME")
+			os.Unsetenv("METRICS_HOST")
 			os.Unsetenv("PORT")
 			os.Unsetenv("BEARER_TOKEN")
 			os.Unsetenv("GO_ENV")
+			os.Unsetenv("TLS_CERT_FILE")
+			os.Unsetenv("TLS_KEY_FILE")
+			os.Unsetenv("ACME_DOMAINS")
+			os.Unsetenv("DEVICE_OP_DELAY")
+			os.Unsetenv("BASE_PATH")
+			os.Unsetenv("LOG_LEVEL")
+			os.Unsetenv("BEARER_TOKEN_SCOPES")
 
 			// Set test env
 			for k, v := range tt.env {
@@ -64,10 +185,235 @@ func TestLoad(t *testing.T) {
 				return
 			}
 			if !tt.wantErr && cfg != nil {
-				if cfg.Host != tt.expected.Host || cfg.Port != tt.expected.Port || cfg.BearerToken != tt.expected.BearerToken {
+				if cfg.Host != tt.expected.Host || cfg.MetricsHost != tt.expected.MetricsHost || cfg.Port != tt.expected.Port || cfg.BearerToken != tt.expected.BearerToken {
 					t.Errorf("Load() = %v, want %v", cfg, tt.expected)
 				}
+				if !maps.Equal(cfg.BearerTokenScopes, tt.expected.BearerTokenScopes) {
+					t.Errorf("BearerTokenScopes = %v, want %v", cfg.BearerTokenScopes, tt.expected.BearerTokenScopes)
+				}
 			}
 		})
 	}
 }
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			BearerToken:             "test-token",
+			StreamInterval:          5 * time.Second,
+			ReadTimeout:             15 * time.Second,
+			WriteTimeout:            60 * time.Second,
+			IdleTimeout:             120 * time.Second,
+			IdempotencyTTL:          60 * time.Second,
+			DeviceOpDelay:           100 * time.Millisecond,
+			DeviceOpRetries:         2,
+			BreakerThreshold:        5,
+			BreakerCooldown:         30 * time.Second,
+			ControllerStartAttempts: 5,
+			ControllerStartBackoff:  time.Second,
+			NotFoundRedirectURL:     "https://xkcd.com/random/",
+			LogOutput:               "stdout",
+			TransitionSteps:         20,
+			MetricsNamespace:        "lights",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{"valid", func(c *Config) {}, false},
+		{"missing token", func(c *Config) { c.BearerToken = "" }, true},
+		{"token scopes", func(c *Config) { c.BearerTokenScopes = map[string]string{"dashboard": "read", "switch": "write"} }, false},
+		{"unknown token scope", func(c *Config) { c.BearerTokenScopes = map[string]string{"dashboard": "admin"} }, true},
+		{"scoped bearer token", func(c *Config) { c.BearerTokenScopes = map[string]string{"test-token": "read"} }, true},
+		{"metrics namespace", func(c *Config) { c.MetricsNamespace = "home_lights" }, false},
+		{"instance name", func(c *Config) { c.InstanceName = "living-room" }, false},
+		{"instance name not UTF-8", func(c *Config) { c.InstanceName = "living\xffroom" }, true},
+		{"empty metrics namespace", func(c *Config) { c.MetricsNamespace = "" }, true},
+		{"metrics namespace with a dash", func(c *Config) { c.MetricsNamespace = "home-lights" }, true},
+		{"zero stream interval", func(c *Config) { c.StreamInterval = 0 }, true},
+		{"zero idempotency TTL", func(c *Config) { c.IdempotencyTTL = 0 }, true},
+		{"zero device op delay", func(c *Config) { c.DeviceOpDelay = 0 }, false},
+		{"device op delay over max", func(c *Config) { c.DeviceOpDelay = 3 * time.Second }, true},
+		{"too many retries", func(c *Config) { c.DeviceOpRetries = maxDeviceOpRetries + 1 }, true},
+		{"circuit breaker disabled", func(c *Config) { c.BreakerThreshold = 0; c.BreakerCooldown = 0 }, false},
+		{"negative circuit breaker threshold", func(c *Config) { c.BreakerThreshold = -1 }, true},
+		{"circuit breaker without cooldown", func(c *Config) { c.BreakerCooldown = 0 }, true},
+		{"negative poll interval", func(c *Config) { c.PollInterval = -time.Second }, true},
+		{"relative redirect URL", func(c *Config) { c.NotFoundRedirectURL = "/lost" }, true},
+		{"file logging without a file", func(c *Config) { c.LogOutput = "file" }, true},
+		{"unknown log output", func(c *Config) { c.LogOutput = "syslog" }, true},
+		{"negative log sample rate", func(c *Config) { c.LogSampleRate = -1 }, true},
+		{"excluded paths", func(c *Config) { c.ExcludedPaths = []string{"/health", "/metrics"} }, false},
+		{"relative excluded path", func(c *Config) { c.ExcludedPaths = []string{"health"} }, true},
+		{"trusted proxies", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.2", "fd00::/8"} }, false},
+		{"invalid trusted proxy", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/33"} }, true},
+		{"zero transition steps", func(c *Config) { c.TransitionSteps = 0 }, true},
+		{"zero read timeout", func(c *Config) { c.ReadTimeout = 0 }, true},
+		{"zero write timeout", func(c *Config) { c.WriteTimeout = 0 }, true},
+		{"zero idle timeout", func(c *Config) { c.IdleTimeout = 0 }, true},
+		{"status cache disabled", func(c *Config) { c.StatusCacheTTL = 0 }, false},
+		{"negative status cache ttl", func(c *Config) { c.StatusCacheTTL = -time.Second }, true},
+		{"zero controller start attempts", func(c *Config) { c.ControllerStartAttempts = 0 }, true},
+		{"zero controller start backoff", func(c *Config) { c.ControllerStartBackoff = 0 }, true},
+		{"warmup timeout", func(c *Config) { c.WarmupTimeout = 30 * time.Second }, false},
+		{"negative warmup timeout", func(c *Config) { c.WarmupTimeout = -time.Second }, true},
+		{"health cache disabled", func(c *Config) { c.HealthCacheTTL = 0 }, false},
+		{"negative health cache ttl", func(c *Config) { c.HealthCacheTTL = -time.Second }, true},
+		{"power on fade", func(c *Config) { c.PowerOnFadeMs = 1500 }, false},
+		{"negative power on fade", func(c *Config) { c.PowerOnFadeMs = -1 }, true},
+		{"power on fade too long", func(c *Config) { c.PowerOnFadeMs = 10001 }, true},
+		{"latitude without longitude", func(c *Config) { c.Latitude = ptr(40.7) }, true},
+		{"location in range", func(c *Config) { c.Latitude, c.Longitude = ptr(40.7), ptr(-74.0) }, false},
+		{"latitude out of range", func(c *Config) { c.Latitude, c.Longitude = ptr(91.0), ptr(0.0) }, true},
+		{"sun auto without location", func(c *Config) { c.SunAuto = true }, true},
+		{"TLS cert without key", func(c *Config) { c.TLSCertFile = "/certs/server.crt" }, true},
+		{"ACME with TLS files", func(c *Config) {
+			c.ACMEDomains = []string{"lights.example.com"}
+			c.TLSCertFile = "/certs/server.crt"
+			c.TLSKeyFile = "/certs/server.key"
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := &Config{
+		Host:               "0.0.0.0",
+		Port:               "8080",
+		BearerToken:        "bearer-secret",
+		MetricsBearerToken: "metrics-secret",
+		HMACSecret:         "hmac-secret",
+		MQTTPassword:       "mqtt-secret",
+		WebhookURL:         "https://hooks.example.com/webhook-secret",
+		BearerTokenScopes:  map[string]string{"scoped-secret": "read"},
+	}
+
+	out := cfg.String()
+	for _, secret := range []string{"bearer-secret", "metrics-secret", "hmac-secret", "mqtt-secret", "webhook-secret", "scoped-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("String() leaked %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "bearer_token=***") {
+		t.Errorf("String() = %q, want bearer_token=***", out)
+	}
+	if !strings.Contains(out, "bearer_token_scopes=***:read") {
+		t.Errorf("String() = %q, want bearer_token_scopes=***:read", out)
+	}
+	if !strings.Contains(out, "port=8080") {
+		t.Errorf("String() = %q, want port=8080", out)
+	}
+	if got := fmt.Sprint(cfg); got != out {
+		t.Errorf("fmt.Sprint(cfg) = %q, want String() output", got)
+	}
+}
+
+func TestLogValueRedactsSecrets(t *testing.T) {
+	cfg := &Config{
+		Port:        "8080",
+		BearerToken: "bearer-secret",
+		HMACSecret:  "hmac-secret",
+		DryRun:      true,
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("Effective configuration", "config", cfg)
+
+	var entry struct {
+		Config map[string]any `json:"config"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line %q: %v", buf.String(), err)
+	}
+	if got := entry.Config["port"]; got != "8080" {
+		t.Errorf("config.port = %v, want 8080", got)
+	}
+	if got := entry.Config["dry_run"]; got != true {
+		t.Errorf("config.dry_run = %v, want true", got)
+	}
+	if got := entry.Config["bearer_token"]; got != "***" {
+		t.Errorf("config.bearer_token = %v, want ***", got)
+	}
+	for _, secret := range []string{"bearer-secret", "hmac-secret"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("log line leaked %q: %s", secret, buf.String())
+		}
+	}
+}
+
+func TestChanges(t *testing.T) {
+	current := &Config{Port: "8080", BearerToken: "old-token", DeviceOpDelay: 100 * time.Millisecond, ExcludedPaths: []string{"/health"}}
+	next := *current
+	next.BearerToken = "new-token"
+	next.DeviceOpDelay = 50 * time.Millisecond
+	next.ColorsFile = "colors.json"
+
+	changes := current.Changes(&next)
+	if len(changes) != 3 || changes[0].Key != "bearer_token" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	// A rotated secret counts as a change even though String redacts both values
+	if changes[0].Old != "***" || changes[0].New != "***" || changes[0].Reloadable {
+		t.Errorf("expected a redacted change needing a restart, got %+v", changes[0])
+	}
+	if want := (Change{Key: "device_op_delay", Old: "100ms", New: "50ms", Reloadable: true}); changes[1] != want {
+		t.Errorf("changes[1] = %+v, want %+v", changes[1], want)
+	}
+	if changes[2].Key != "colors_file" || !changes[2].Reloadable {
+		t.Errorf("expected colors_file to be reloadable, got %+v", changes[2])
+	}
+
+	if changes := current.Changes(current); changes != nil {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestLoadDotenvReload(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// Register cleanups for both variables, then start with FROM_DOTENV unset
+	t.Setenv("LIGHTS_TEST_FROM_DOTENV", "")
+	os.Unsetenv("LIGHTS_TEST_FROM_DOTENV")
+	t.Setenv("LIGHTS_TEST_FROM_ENV", "env")
+	t.Cleanup(func() { delete(dotenvKeys, "LIGHTS_TEST_FROM_DOTENV") })
+
+	writeDotenv := func(contents string) {
+		if err := os.WriteFile(".env", []byte(contents), 0o600); err != nil {
+			t.Fatalf("failed to write .env: %v", err)
+		}
+		loadDotenv()
+	}
+
+	writeDotenv("LIGHTS_TEST_FROM_DOTENV=one\nLIGHTS_TEST_FROM_ENV=dotenv\n")
+	if got := os.Getenv("LIGHTS_TEST_FROM_DOTENV"); got != "one" {
+		t.Errorf("expected the .env value, got %q", got)
+	}
+	if got := os.Getenv("LIGHTS_TEST_FROM_ENV"); got != "env" {
+		t.Errorf("expected the real environment to win, got %q", got)
+	}
+
+	writeDotenv("LIGHTS_TEST_FROM_DOTENV=two\n")
+	if got := os.Getenv("LIGHTS_TEST_FROM_DOTENV"); got != "two" {
+		t.Errorf("expected the edited .env value, got %q", got)
+	}
+
+	writeDotenv("")
+	if _, set := os.LookupEnv("LIGHTS_TEST_FROM_DOTENV"); set {
+		t.Error("expected a variable removed from .env to be unset")
+	}
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
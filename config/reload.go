@@ -0,0 +1,93 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// reloadable are the settings POST /admin/reload and SIGHUP apply without a restart; a change to
+// any other setting only takes effect once the server restarts
+var reloadable = []string{"log_level", "device_op_delay", "color_overrides", "colors_file"}
+
+var (
+	dotenvMu sync.Mutex
+	// dotenvKeys are the variables set from .env rather than the real environment, so a
+	// later load can replace or clear them
+	dotenvKeys = map[string]bool{}
+)
+
+// loadDotenv copies .env into the environment. Variables from the real environment win,
+// as they always have, but values an earlier call took from .env are replaced, and
+// removed if .env no longer sets them, so Load can be called again to pick up edits.
+func loadDotenv() {
+	values, err := godotenv.Read()
+	if err != nil {
+		// A missing .env is fine; the environment alone configures the server
+		values = nil
+	}
+
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+	for key := range dotenvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotenvKeys, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !dotenvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotenvKeys[key] = true
+	}
+}
+
+// Change is a setting whose value differs between two configurations. Old and New are
+// redacted the way String redacts them, so a Change is safe to log.
+type Change struct {
+	Key        string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+// Changes compares c with a freshly loaded next and returns every setting that changed
+func (c *Config) Changes(next *Config) []Change {
+	// Compare secrets as they are; redacting them would hide a rotated token
+	asIs := func(secret string) string { return secret }
+	current, shown := c.fields(asIs), c.fields(redact)
+	nextShown := next.fields(redact)
+
+	var changes []Change
+	for i, f := range next.fields(asIs) {
+		if fmt.Sprint(current[i].value) == fmt.Sprint(f.value) {
+			continue
+		}
+		changes = append(changes, Change{
+			Key:        f.key,
+			Old:        fmt.Sprint(shown[i].value),
+			New:        fmt.Sprint(nextShown[i].value),
+			Reloadable: slices.Contains(reloadable, f.key),
+		})
+	}
+	return changes
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Aliases maps device IDs to friendly names that can stand in for them, e.g. "desk-lamp"
+// for "A1:B2:C3:D4:E5:F6:00:11". Both are matched ignoring case. Aliases are fixed once
+// parsed, so they are safe for concurrent use; a nil Aliases has none.
+type Aliases struct {
+	// ids maps a lowercased alias to its device ID; names maps a lowercased device ID to
+	// its alias as configured
+	ids   map[string]string
+	names map[string]string
+}
+
+// ParseAliases parses a definition such as "A1:B2:...=desk-lamp,C3:D4:...=shelf"
+func ParseAliases(definition string) (*Aliases, error) {
+	a := &Aliases{ids: make(map[string]string), names: make(map[string]string)}
+	for _, entry := range strings.Split(definition, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		deviceID, alias, ok := strings.Cut(entry, "=")
+		deviceID, alias = strings.TrimSpace(deviceID), strings.TrimSpace(alias)
+		if !ok || deviceID == "" || alias == "" {
+			return nil, fmt.Errorf("invalid device alias %q: expected deviceID=alias", entry)
+		}
+		if strings.ContainsAny(alias, " \t") {
+			return nil, fmt.Errorf("invalid device alias %q: aliases cannot contain spaces", alias)
+		}
+		if _, ok := a.ids[strings.ToLower(alias)]; ok {
+			return nil, fmt.Errorf("device alias %q is used twice", alias)
+		}
+		if _, ok := a.names[strings.ToLower(deviceID)]; ok {
+			return nil, fmt.Errorf("device %q has more than one alias", deviceID)
+		}
+		a.ids[strings.ToLower(alias)] = deviceID
+		a.names[strings.ToLower(deviceID)] = alias
+	}
+	return a, nil
+}
+
+// Resolve returns the device ID for an alias; anything else, such as a device ID, is
+// returned unchanged
+func (a *Aliases) Resolve(name string) string {
+	if a == nil {
+		return name
+	}
+	if deviceID, ok := a.ids[strings.ToLower(name)]; ok {
+		return deviceID
+	}
+	return name
+}
+
+// Alias returns the alias of a device, or "" if it has none
+func (a *Aliases) Alias(deviceID string) string {
+	if a == nil {
+		return ""
+	}
+	return a.names[strings.ToLower(deviceID)]
+}
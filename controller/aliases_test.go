@@ -0,0 +1,46 @@
+package controller
+
+import "testing"
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases("A1:B2:C3:D4=desk-lamp, E5:F6:00:11=Shelf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := aliases.Resolve("desk-lamp"); got != "A1:B2:C3:D4" {
+		t.Errorf("Resolve(desk-lamp) = %q", got)
+	}
+	if got := aliases.Resolve("shelf"); got != "E5:F6:00:11" {
+		t.Errorf("expected aliases to match ignoring case, got %q", got)
+	}
+	if got := aliases.Resolve("A1:B2:C3:D4"); got != "A1:B2:C3:D4" {
+		t.Errorf("expected a device ID to pass through, got %q", got)
+	}
+	if got := aliases.Alias("a1:b2:c3:d4"); got != "desk-lamp" {
+		t.Errorf("Alias() = %q, want desk-lamp", got)
+	}
+	if got := aliases.Alias("99:99"); got != "" {
+		t.Errorf("expected no alias, got %q", got)
+	}
+
+	var none *Aliases
+	if none.Resolve("desk-lamp") != "desk-lamp" || none.Alias("A1:B2:C3:D4") != "" {
+		t.Error("expected a nil Aliases to have no aliases")
+	}
+}
+
+func TestParseAliasesInvalid(t *testing.T) {
+	for _, definition := range []string{
+		"A1:B2",
+		"=desk-lamp",
+		"A1:B2=",
+		"A1:B2=desk lamp",
+		"A1:B2=desk-lamp,C3:D4=Desk-Lamp",
+		"A1:B2=desk-lamp,a1:b2=shelf",
+	} {
+		if _, err := ParseAliases(definition); err == nil {
+			t.Errorf("expected an error for %q", definition)
+		}
+	}
+}
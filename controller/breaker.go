@@ -0,0 +1,170 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/metrics"
+)
+
+// Circuit breaker states, as reported in status and health output
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// ErrBreakerOpen is returned instead of sending a command to a device whose breaker is open
+var ErrBreakerOpen = errors.New("circuit breaker open")
+
+// Breakers keeps a circuit breaker per device. After threshold consecutive failed commands
+// a device's breaker opens and its commands fail straight away with ErrBreakerOpen, sparing
+// the retries and the logs. Once cooldown has passed the breaker is half-open: the next
+// command is let through as a probe, closing the breaker if it succeeds and reopening it
+// for another cooldown if it fails. A nil Breakers lets every command through. It is safe
+// for concurrent use.
+type Breakers struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	devices map[string]*breaker
+}
+
+// breaker is one device's breaker state
+type breaker struct {
+	failures int
+	openedAt time.Time
+	// probing is set while the half-open probe command is running
+	probing bool
+}
+
+// NewBreakers returns breakers that open after threshold consecutive failures and stay
+// open for cooldown. A threshold of 0 disables them and returns nil.
+func NewBreakers(threshold int, cooldown time.Duration) *Breakers {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Breakers{threshold: threshold, cooldown: cooldown, now: time.Now, devices: make(map[string]*breaker)}
+}
+
+// Allow reports whether a command may be sent to the device, returning an error wrapping
+// ErrBreakerOpen if not. When it lets a half-open device's probe through, the caller must
+// pass the command's outcome to Record.
+func (b *Breakers) Allow(deviceID string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	device, ok := b.devices[deviceID]
+	if !ok || device.failures < b.threshold {
+		return nil
+	}
+	if remaining := b.cooldown - b.now().Sub(device.openedAt); remaining > 0 {
+		return fmt.Errorf("%w, retrying after %s", ErrBreakerOpen, remaining.Round(time.Second))
+	}
+	if device.probing {
+		return fmt.Errorf("%w, probing the device", ErrBreakerOpen)
+	}
+	device.probing = true
+	b.report(deviceID, BreakerHalfOpen)
+	return nil
+}
+
+// Record counts a command's outcome for the device: a success closes its breaker, and a
+// failure opens it once there have been threshold in a row, or reopens it after a probe
+func (b *Breakers) Record(deviceID string, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	device, ok := b.devices[deviceID]
+	if !ok {
+		device = &breaker{}
+		b.devices[deviceID] = device
+	}
+	device.probing = false
+	if err == nil {
+		if device.failures >= b.threshold {
+			b.report(deviceID, BreakerClosed)
+		}
+		device.failures = 0
+		return
+	}
+	device.failures++
+	if device.failures >= b.threshold {
+		device.openedAt = b.now()
+		b.report(deviceID, BreakerOpen)
+	}
+}
+
+// State returns the device's breaker state
+func (b *Breakers) State(deviceID string) string {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state(b.devices[deviceID])
+}
+
+// Tripped returns the IDs of the devices whose breaker isn't closed, sorted
+func (b *Breakers) Tripped() []string {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var tripped []string
+	for deviceID, device := range b.devices {
+		if b.state(device) != BreakerClosed {
+			tripped = append(tripped, deviceID)
+		}
+	}
+	slices.Sort(tripped)
+	return tripped
+}
+
+// state works out a breaker's state; callers hold b.mu
+func (b *Breakers) state(device *breaker) string {
+	switch {
+	case device == nil || device.failures < b.threshold:
+		return BreakerClosed
+	case device.probing || b.now().Sub(device.openedAt) >= b.cooldown:
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// report records a state change in the breaker state gauge
+func (b *Breakers) report(deviceID string, state string) {
+	value := 0.0
+	switch state {
+	case BreakerHalfOpen:
+		value = 1
+	case BreakerOpen:
+		value = 2
+	}
+	metrics.DeviceBreakerState.WithLabelValues(deviceID).Set(value)
+}
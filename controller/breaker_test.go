@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBreakers(t *testing.T) {
+	now := time.Now()
+	breakers := NewBreakers(3, 30*time.Second)
+	breakers.now = func() time.Time { return now }
+	failed := errors.New("no route to host")
+
+	// Failures short of the threshold, or broken up by a success, leave it closed
+	breakers.Record("AA:BB", failed)
+	breakers.Record("AA:BB", failed)
+	breakers.Record("AA:BB", nil)
+	breakers.Record("AA:BB", failed)
+	breakers.Record("AA:BB", failed)
+	if err := breakers.Allow("AA:BB"); err != nil || breakers.State("AA:BB") != BreakerClosed {
+		t.Fatalf("expected a closed breaker, got %s: %v", breakers.State("AA:BB"), err)
+	}
+
+	breakers.Record("AA:BB", failed)
+	if err := breakers.Allow("AA:BB"); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("expected ErrBreakerOpen after 3 failures in a row, got %v", err)
+	}
+	if state := breakers.State("AA:BB"); state != BreakerOpen {
+		t.Errorf("expected open, got %s", state)
+	}
+	if err := breakers.Allow("CC:DD"); err != nil {
+		t.Errorf("expected other devices to be unaffected, got %v", err)
+	}
+	if tripped := breakers.Tripped(); !slices.Equal(tripped, []string{"AA:BB"}) {
+		t.Errorf("expected AA:BB tripped, got %v", tripped)
+	}
+
+	// After the cooldown one probe is let through; a failed probe reopens the breaker
+	now = now.Add(30 * time.Second)
+	if state := breakers.State("AA:BB"); state != BreakerHalfOpen {
+		t.Errorf("expected half-open after the cooldown, got %s", state)
+	}
+	if err := breakers.Allow("AA:BB"); err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	if err := breakers.Allow("AA:BB"); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("expected only one probe at a time, got %v", err)
+	}
+	breakers.Record("AA:BB", failed)
+	if err := breakers.Allow("AA:BB"); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("expected a failed probe to reopen the breaker, got %v", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(30 * time.Second)
+	if err := breakers.Allow("AA:BB"); err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	breakers.Record("AA:BB", nil)
+	if state := breakers.State("AA:BB"); state != BreakerClosed {
+		t.Errorf("expected a successful probe to close the breaker, got %s", state)
+	}
+	if tripped := breakers.Tripped(); len(tripped) != 0 {
+		t.Errorf("expected nothing tripped, got %v", tripped)
+	}
+}
+
+func TestBreakersDisabled(t *testing.T) {
+	breakers := NewBreakers(0, time.Minute)
+	if breakers != nil {
+		t.Fatalf("expected a threshold of 0 to disable the breakers")
+	}
+	for range 10 {
+		breakers.Record("AA:BB", errors.New("no route to host"))
+	}
+	if err := breakers.Allow("AA:BB"); err != nil {
+		t.Errorf("expected nil breakers to allow everything, got %v", err)
+	}
+	if state := breakers.State("AA:BB"); state != BreakerClosed {
+		t.Errorf("expected closed, got %s", state)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/colors"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// WarmKelvin is the color temperature of the "warm" default state
+const WarmKelvin = 2700
+
+// DefaultStateTimeout bounds how long applying the startup default state may take
+const DefaultStateTimeout = 10 * time.Second
+
+// ParseDefaultState returns the operation for a DEFAULT_STATE value: "off", "on", "warm"
+// (warm white), a "#rrggbb" color or the name of a preset in table. Every state other than
+// "off" also turns the device on.
+func ParseDefaultState(state string, table *colors.Table) (Operation, error) {
+	switch state = strings.ToLower(strings.TrimSpace(state)); {
+	case state == "off":
+		return TurnOff(), nil
+	case state == "on":
+		return TurnOn(), nil
+	case state == "warm":
+		return Sequence(TurnOn(), SetColorKelvin(govee.NewColorKelvin(WarmKelvin))), nil
+	case strings.HasPrefix(state, "#"):
+		color, err := colors.ParseHex(state)
+		if err != nil {
+			return nil, err
+		}
+		return Sequence(TurnOn(), SetColor(color)), nil
+	}
+
+	color, ok := table.Lookup(state)
+	if !ok {
+		return nil, fmt.Errorf("unknown default state %q: expected off, on, warm, #rrggbb or a color preset", state)
+	}
+	return Sequence(TurnOn(), SetColor(color)), nil
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/jwhitcraft/lights-http/colors"
+)
+
+func TestParseDefaultState(t *testing.T) {
+	for _, state := range []string{"off", "on", "Warm", "#ff8000", "orange"} {
+		if op, err := ParseDefaultState(state, colors.Default()); err != nil || op == nil {
+			t.Errorf("ParseDefaultState(%q) = %v, %v; want an operation", state, op, err)
+		}
+	}
+
+	for _, state := range []string{"teal", "#ff80"} {
+		if _, err := ParseDefaultState(state, colors.Default()); err == nil {
+			t.Errorf("ParseDefaultState(%q) expected an error", state)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// DisabledDevices is the set of device IDs operators have taken out of service. Disabled
+// devices are left out of operations and status reads without being unplugged. IDs match
+// case-insensitively. A nil set disables nothing; it is safe for concurrent use.
+type DisabledDevices struct {
+	mu  sync.RWMutex
+	ids map[string]string // lowercased ID -> ID as given
+}
+
+// NewDisabledDevices returns a set holding ids
+func NewDisabledDevices(ids []string) *DisabledDevices {
+	d := &DisabledDevices{ids: make(map[string]string)}
+	for _, id := range ids {
+		d.Disable(id)
+	}
+	return d
+}
+
+// Disable adds the device to the set, reporting whether it was enabled before
+func (d *DisabledDevices) Disable(deviceID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := strings.ToLower(deviceID)
+	if _, ok := d.ids[key]; ok {
+		return false
+	}
+	d.ids[key] = deviceID
+	return true
+}
+
+// Enable removes the device from the set, reporting whether it was disabled before
+func (d *DisabledDevices) Enable(deviceID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := strings.ToLower(deviceID)
+	if _, ok := d.ids[key]; !ok {
+		return false
+	}
+	delete(d.ids, key)
+	return true
+}
+
+// Disabled reports whether the device is in the set
+func (d *DisabledDevices) Disabled(deviceID string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.ids[strings.ToLower(deviceID)]
+	return ok
+}
+
+// List returns the disabled device IDs, sorted
+func (d *DisabledDevices) List() []string {
+	ids := []string{}
+	if d == nil {
+		return ids
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, id := range d.ids {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Filter returns the devices that are not disabled
+func (d *DisabledDevices) Filter(devices []*govee.Device) []*govee.Device {
+	if d == nil {
+		return devices
+	}
+	var enabled []*govee.Device
+	for _, device := range devices {
+		if !d.Disabled(device.DeviceID()) {
+			enabled = append(enabled, device)
+		}
+	}
+	return enabled
+}
+
+// Enabled wraps devices so its Devices method leaves out the disabled ones
+func (d *DisabledDevices) Enabled(devices DeviceLister) DeviceLister {
+	return enabledDevices{devices: devices, disabled: d}
+}
+
+type enabledDevices struct {
+	devices  DeviceLister
+	disabled *DisabledDevices
+}
+
+func (e enabledDevices) Devices() []*govee.Device {
+	return e.disabled.Filter(e.devices.Devices())
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestDisabledDevices(t *testing.T) {
+	disabled := NewDisabledDevices([]string{"AA:BB", "cc:dd"})
+
+	if !disabled.Disabled("aa:bb") || !disabled.Disabled("CC:DD") {
+		t.Error("expected configured devices to be disabled regardless of case")
+	}
+	if disabled.Disabled("ee:ff") {
+		t.Error("expected other devices to stay enabled")
+	}
+
+	if !disabled.Disable("ee:ff") {
+		t.Error("expected disabling a new device to report a change")
+	}
+	if disabled.Disable("EE:FF") {
+		t.Error("expected disabling a disabled device to report no change")
+	}
+	if got, want := disabled.List(), []string{"AA:BB", "cc:dd", "ee:ff"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if !disabled.Enable("Aa:Bb") {
+		t.Error("expected enabling a disabled device to report a change")
+	}
+	if disabled.Enable("aa:bb") {
+		t.Error("expected enabling an enabled device to report no change")
+	}
+	if disabled.Disabled("aa:bb") {
+		t.Error("expected the device to be enabled")
+	}
+}
+
+func TestDisabledDevicesFilter(t *testing.T) {
+	devices := []*govee.Device{{}, {}}
+
+	var none *DisabledDevices
+	if got := none.Filter(devices); len(got) != 2 {
+		t.Errorf("expected a nil set to keep every device, got %d", len(got))
+	}
+	if none.Disabled("") || len(none.List()) != 0 {
+		t.Error("expected a nil set to disable nothing")
+	}
+
+	// Devices built outside the library have an empty ID
+	if got := NewDisabledDevices([]string{""}).Filter(devices); len(got) != 0 {
+		t.Errorf("expected disabled devices to be filtered out, got %d", len(got))
+	}
+}
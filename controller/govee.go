@@ -15,15 +15,111 @@
 package controller
 
 import (
+	"context"
 	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/jwhitcraft/lights-http/metrics"
 	govee "github.com/swrm-io/go-vee"
 )
 
+// DefaultDiscoveryWait is how long devices are given to answer the scan sent when the
+// controller starts
+const DefaultDiscoveryWait = 5 * time.Second
+
+// Controller states reported by State
+const (
+	// StateStarting means discovery is running but no device has answered yet
+	StateStarting = "starting"
+	// StateReady means at least one device has been discovered
+	StateReady = "ready"
+	// StateError means the controller gave up starting
+	StateError = "error"
+)
+
+// maxStartBackoff caps the pause between controller start attempts
+const maxStartBackoff = time.Minute
+
 type GoveeController struct {
 	*govee.Controller
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	startErr error
 }
 
 func NewGoveeController(logger *slog.Logger) *GoveeController {
-	return &GoveeController{govee.NewController(logger)}
+	return &GoveeController{Controller: govee.NewController(logger), logger: logger}
+}
+
+// StartWithRetry starts the controller, retrying a failed start up to attempts times in
+// total with a backoff that doubles after every failure. Start only returns early when it
+// can't open the discovery socket, so a nil error means the controller ran and was shut
+// down. Once every attempt has failed State reports StateError.
+func (g *GoveeController) StartWithRetry(ctx context.Context, attempts int, backoff time.Duration) error {
+	err := retryStart(ctx, g.Controller.Start, attempts, backoff, g.logger)
+	if err != nil {
+		g.mu.Lock()
+		g.startErr = err
+		g.mu.Unlock()
+	}
+	return err
+}
+
+// State reports whether the controller is still discovering devices, has found some, or
+// gave up starting
+func (g *GoveeController) State() string {
+	g.mu.RLock()
+	failed := g.startErr != nil
+	g.mu.RUnlock()
+
+	switch {
+	case failed:
+		return StateError
+	case len(g.Devices()) > 0:
+		return StateReady
+	default:
+		return StateStarting
+	}
+}
+
+// retryStart calls start until it succeeds, attempts run out or ctx is cancelled
+func retryStart(ctx context.Context, start func() error, attempts int, backoff time.Duration, logger *slog.Logger) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = start(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		logger.Warn("Failed to start controller, retrying", "attempt", attempt, "attempts", attempts, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxStartBackoff)
+	}
+	return err
+}
+
+// AfterDiscovery calls fn with the discovered devices once wait has passed, unless ctx is
+// cancelled first. Call it right after starting the controller; the govee library doesn't
+// report when discovery is done, so the wait stands in for it. The device count gauge is
+// updated before fn runs; fn may be nil.
+func (g *GoveeController) AfterDiscovery(ctx context.Context, wait time.Duration, fn func(devices []*govee.Device)) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(wait):
+		devices := g.Devices()
+		metrics.SetDeviceCount(len(devices))
+		if fn != nil {
+			fn(devices)
+		}
+	}
 }
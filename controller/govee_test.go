@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRetryStart(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	errListen := errors.New("address already in use")
+
+	calls := 0
+	flaky := func() error {
+		calls++
+		if calls < 3 {
+			return errListen
+		}
+		return nil
+	}
+	if err := retryStart(context.Background(), flaky, 5, time.Millisecond, logger); err != nil {
+		t.Errorf("expected the third attempt to succeed, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+
+	calls = 0
+	broken := func() error {
+		calls++
+		return errListen
+	}
+	if err := retryStart(context.Background(), broken, 3, time.Millisecond, logger); !errors.Is(err, errListen) {
+		t.Errorf("expected the last start error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	if err := retryStart(ctx, broken, 3, time.Hour, logger); err == nil {
+		t.Error("expected an error when cancelled during the backoff")
+	}
+	if calls != 1 {
+		t.Errorf("expected retries to stop once cancelled, got %d attempts", calls)
+	}
+}
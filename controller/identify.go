@@ -0,0 +1,57 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Identify defaults: three flashes, each half a second off then half a second on
+const (
+	DefaultIdentifyFlashes  = 3
+	DefaultIdentifyInterval = 500 * time.Millisecond
+)
+
+// Identify returns an operation that flashes a device off and on so it can be picked out,
+// then turns it back on or off as wasOn says, even if a flash fails part way. Color and
+// brightness are left untouched. Read wasOn before running the operation, not inside it:
+// a retry after a failed flash would find the light off.
+func Identify(flashes int, interval time.Duration, wasOn bool) Operation {
+	return func(device *govee.Device) (err error) {
+		defer func() {
+			restore := device.TurnOff
+			if wasOn {
+				restore = device.TurnOn
+			}
+			if restoreErr := restore(); err == nil {
+				err = restoreErr
+			}
+		}()
+
+		for i := 0; i < flashes; i++ {
+			if err := device.TurnOff(); err != nil {
+				return err
+			}
+			time.Sleep(interval)
+			if err := device.TurnOn(); err != nil {
+				return err
+			}
+			time.Sleep(interval)
+		}
+		return nil
+	}
+}
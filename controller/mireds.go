@@ -0,0 +1,24 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+// MiredsToKelvin converts a color temperature in mireds (micro reciprocal degrees, as used
+// by Home Assistant) to Kelvin, rounded to the nearest degree. It returns 0 for non-positive input.
+func MiredsToKelvin(mireds int) int {
+	if mireds <= 0 {
+		return 0
+	}
+	return (1_000_000 + mireds/2) / mireds
+}
@@ -0,0 +1,24 @@
+package controller
+
+import "testing"
+
+func TestMiredsToKelvin(t *testing.T) {
+	tests := []struct {
+		mireds int
+		kelvin int
+	}{
+		{111, 9009},
+		{153, 6536},
+		{250, 4000},
+		{370, 2703},
+		{500, 2000},
+		{0, 0},
+		{-5, 0},
+	}
+
+	for _, tt := range tests {
+		if got := MiredsToKelvin(tt.mireds); got != tt.kelvin {
+			t.Errorf("MiredsToKelvin(%d) = %d, want %d", tt.mireds, got, tt.kelvin)
+		}
+	}
+}
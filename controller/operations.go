@@ -0,0 +1,151 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jwhitcraft/lights-http/metrics"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Operation is a single command applied to one device
+type Operation func(device *govee.Device) error
+
+// TurnOn returns an operation that powers a device on
+func TurnOn() Operation {
+	return func(device *govee.Device) error {
+		return device.TurnOn()
+	}
+}
+
+// TurnOff returns an operation that powers a device off
+func TurnOff() Operation {
+	return func(device *govee.Device) error {
+		return device.TurnOff()
+	}
+}
+
+// SetColor returns an operation that sets a device to the given RGB color
+func SetColor(color govee.Color) Operation {
+	return func(device *govee.Device) error {
+		return device.SetColor(color)
+	}
+}
+
+// SetColorKelvin returns an operation that sets a device to the given color temperature
+func SetColorKelvin(colorTemp govee.ColorKelvin) Operation {
+	return func(device *govee.Device) error {
+		return device.SetColorKelvin(colorTemp)
+	}
+}
+
+// SetBrightness returns an operation that sets a device's brightness (0-100)
+func SetBrightness(brightness govee.Brightness) Operation {
+	return func(device *govee.Device) error {
+		return device.SetBrightness(brightness)
+	}
+}
+
+// SetState returns an operation that turns a device off, or turns it on with the given
+// color and brightness
+func SetState(on bool, color govee.Color, brightness govee.Brightness) Operation {
+	return func(device *govee.Device) error {
+		if !on {
+			return device.TurnOff()
+		}
+		if err := device.TurnOn(); err != nil {
+			return err
+		}
+		if err := device.SetColor(color); err != nil {
+			return err
+		}
+		return device.SetBrightness(brightness)
+	}
+}
+
+// Sequence returns an operation that applies each of ops in order, stopping at the first error
+func Sequence(ops ...Operation) Operation {
+	return func(device *govee.Device) error {
+		for _, op := range ops {
+			if err := op(device); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// DeviceLabel identifies a device in logs and error messages as "model (id)", or just the
+// ID when the model isn't known. The govee LAN API doesn't report a friendly name.
+func DeviceLabel(device *govee.Device) string {
+	if sku := device.SKU(); sku != "" {
+		return fmt.Sprintf("%s (%s)", sku, device.DeviceID())
+	}
+	return device.DeviceID()
+}
+
+// FilterByModel returns the devices whose SKU matches model, ignoring case
+func FilterByModel(devices []*govee.Device, model string) []*govee.Device {
+	var matched []*govee.Device
+	for _, device := range devices {
+		if strings.EqualFold(device.SKU(), model) {
+			matched = append(matched, device)
+		}
+	}
+	return matched
+}
+
+// PoweredOn reports whether a device was last reported on. It is the one power check every
+// caller should use; govee's Active only says the device was seen on the network recently.
+func PoweredOn(device *govee.Device) bool {
+	return device.State() == 1
+}
+
+// UpdateDeviceMetrics sets the brightness and power gauges from each device's last reported state.
+// Callers should refresh the devices with RequestStatus first.
+func UpdateDeviceMetrics(devices []*govee.Device) {
+	for _, device := range devices {
+		if device.DeviceID() == "" {
+			continue
+		}
+		metrics.SetDeviceState(device.DeviceID(), device.Brightness(), PoweredOn(device))
+	}
+}
+
+// DeviceStatus builds the status payload for a device from its last reported state
+func DeviceStatus(device *govee.Device) map[string]interface{} {
+	color := device.Color()
+	status := map[string]interface{}{
+		"deviceID":   device.DeviceID(),
+		"onOff":      PoweredOn(device),
+		"brightness": int(device.Brightness()),
+		"color": map[string]int{
+			"r": int(color.R),
+			"g": int(color.G),
+			"b": int(color.B),
+		},
+		"colortemp": device.ColorKelvin().String(),
+	}
+	// The govee LAN API only reports the SKU; there is no friendly name to expose
+	if sku := device.SKU(); sku != "" {
+		status["sku"] = sku
+	}
+	if ip := device.IP(); ip != "" {
+		status["ip"] = ip
+	}
+	return status
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestFilterByModel(t *testing.T) {
+	devices := []*govee.Device{{}, {}}
+
+	if got := FilterByModel(devices, ""); len(got) != 2 {
+		t.Errorf("expected devices without a SKU to match an empty model, got %d", len(got))
+	}
+	if got := FilterByModel(devices, "H6159"); len(got) != 0 {
+		t.Errorf("expected no devices to match H6159, got %d", len(got))
+	}
+}
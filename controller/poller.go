@@ -0,0 +1,85 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// DeviceLister is anything that can list the currently known devices
+type DeviceLister interface {
+	Devices() []*govee.Device
+}
+
+// Poller periodically refreshes every device's status and updates the device gauges
+type Poller struct {
+	devices  DeviceLister
+	interval time.Duration
+	logger   *slog.Logger
+	polling  atomic.Bool
+}
+
+// NewPoller creates a poller that refreshes devices every interval
+func NewPoller(devices DeviceLister, interval time.Duration, logger *slog.Logger) *Poller {
+	return &Poller{
+		devices:  devices,
+		interval: interval,
+		logger:   logger.With("component", "poller"),
+	}
+}
+
+// Run polls until ctx is cancelled
+func (p *Poller) Run(ctx context.Context) {
+	p.logger.Info("Starting status poller", "interval", p.interval)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Status poller stopped")
+			return
+		case <-ticker.C:
+			// Poll in the background so a slow device doesn't delay the schedule;
+			// poll itself refuses to start while a previous pass is still running.
+			go p.poll()
+		}
+	}
+}
+
+// poll refreshes all devices once. It returns false if a previous poll was still running.
+func (p *Poller) poll() bool {
+	if !p.polling.CompareAndSwap(false, true) {
+		p.logger.Warn("Skipping status poll, previous poll still running")
+		return false
+	}
+	defer p.polling.Store(false)
+
+	var refreshed []*govee.Device
+	for _, device := range p.devices.Devices() {
+		if err := device.RequestStatus(); err != nil {
+			p.logger.Error("Failed to request status", "device", DeviceLabel(device), "error", err)
+			continue
+		}
+		refreshed = append(refreshed, device)
+	}
+	UpdateDeviceMetrics(refreshed)
+	return true
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// blockingLister blocks in Devices until released, simulating a slow poll
+type blockingLister struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingLister) Devices() []*govee.Device {
+	b.started <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func TestPollerSkipsOverlappingPolls(t *testing.T) {
+	lister := &blockingLister{started: make(chan struct{}, 1), release: make(chan struct{})}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	poller := NewPoller(lister, time.Minute, logger)
+
+	done := make(chan bool)
+	go func() { done <- poller.poll() }()
+	<-lister.started
+
+	if poller.poll() {
+		t.Errorf("expected overlapping poll to be skipped")
+	}
+
+	close(lister.release)
+	if !<-done {
+		t.Errorf("expected first poll to run")
+	}
+	if !poller.poll() {
+		t.Errorf("expected poll to run once the previous one finished")
+	}
+}
+
+type emptyLister struct{}
+
+func (emptyLister) Devices() []*govee.Device { return nil }
+
+func TestPollerStopsOnCancel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	poller := NewPoller(emptyLister{}, time.Millisecond, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		poller.Run(ctx)
+		close(stopped)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("poller did not stop after cancellation")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// ErrSuperseded is returned for a command that was dropped from a device's queue because a
+// newer command of the same kind arrived before it ran
+var ErrSuperseded = errors.New("superseded by a newer command")
+
+// DeviceQueue sends the devices their commands one at a time. The govee library hands
+// every device's commands to a single sender over one unbuffered channel and gives up
+// with "channel blocked" if that sender is busy, so two commands in flight at once, even
+// for different devices, can fail. Every device gets its own worker goroutine, keeping its
+// commands in the order they were submitted, and the workers take turns at one
+// process-wide gate before sending anything. A nil DeviceQueue runs operations straight
+// away. It is safe for concurrent use.
+type DeviceQueue struct {
+	spacing  func() time.Duration
+	coalesce bool
+	breakers *Breakers
+
+	mu      sync.Mutex
+	workers map[string]*deviceWorker
+
+	// send is the gate every operation runs behind; lastSend is when the previous one
+	// finished, for spacing
+	send     sync.Mutex
+	lastSend time.Time
+}
+
+// deviceWorker holds one device's commands that haven't started yet
+type deviceWorker struct {
+	deviceID string
+	pending  []*queuedOperation
+	// wake is signalled when pending gains a command; its buffer of one means a signal
+	// sent while the worker is busy isn't lost
+	wake chan struct{}
+}
+
+// queuedOperation is one submitted operation and where to send its result
+type queuedOperation struct {
+	kind string
+	run  func() error
+	done chan error
+}
+
+// NewDeviceQueue returns a queue that leaves at least spacing between one command
+// finishing and the next starting, whichever devices they are for. spacing is read before
+// every command, so it can change while the queue runs; nil means no gap. With coalesce
+// set, DoCoalesced drops a waiting command when a newer one of the same kind arrives. Each
+// command is checked against breakers when its turn comes and its outcome recorded there;
+// a command short-circuited by an open breaker never takes the gate.
+func NewDeviceQueue(spacing func() time.Duration, coalesce bool, breakers *Breakers) *DeviceQueue {
+	return &DeviceQueue{spacing: spacing, coalesce: coalesce, breakers: breakers, workers: make(map[string]*deviceWorker)}
+}
+
+// Do queues op for device and waits for it to run, returning its error
+func (q *DeviceQueue) Do(device *govee.Device, op Operation) error {
+	return q.DoCoalesced(device, "", op)
+}
+
+// DoCoalesced is Do for commands where only the latest of a kind matters, such as setting
+// a color while a slider is dragged. If the queue coalesces, a command of the same kind
+// still waiting for the device is dropped, its caller getting ErrSuperseded, and op takes
+// its turn at the back of the queue. An empty kind never coalesces.
+func (q *DeviceQueue) DoCoalesced(device *govee.Device, kind string, op Operation) error {
+	if q == nil {
+		return op(device)
+	}
+	return q.submit(device.DeviceID(), kind, func() error { return op(device) })
+}
+
+// submit queues run on the worker for deviceID, starting it if needed, and waits for it
+func (q *DeviceQueue) submit(deviceID string, kind string, run func() error) error {
+	op := &queuedOperation{kind: kind, run: run, done: make(chan error, 1)}
+
+	q.mu.Lock()
+	worker, ok := q.workers[deviceID]
+	if !ok {
+		// Workers live as long as the process; there is one per device ever commanded
+		worker = &deviceWorker{deviceID: deviceID, wake: make(chan struct{}, 1)}
+		q.workers[deviceID] = worker
+		go q.work(worker)
+	}
+	if q.coalesce && kind != "" {
+		// Every arrival coalesces, so at most one command of a kind is ever waiting
+		if i := slices.IndexFunc(worker.pending, func(p *queuedOperation) bool { return p.kind == kind }); i >= 0 {
+			worker.pending[i].done <- ErrSuperseded
+			worker.pending = slices.Delete(worker.pending, i, i+1)
+		}
+	}
+	worker.pending = append(worker.pending, op)
+	q.mu.Unlock()
+
+	select {
+	case worker.wake <- struct{}{}:
+	default:
+	}
+	return <-op.done
+}
+
+// work runs a device's operations in turn
+func (q *DeviceQueue) work(worker *deviceWorker) {
+	for range worker.wake {
+		for {
+			q.mu.Lock()
+			if len(worker.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			op := worker.pending[0]
+			worker.pending = worker.pending[1:]
+			q.mu.Unlock()
+
+			if err := q.breakers.Allow(worker.deviceID); err != nil {
+				op.done <- err
+				continue
+			}
+			err := q.runGated(op.run)
+			q.breakers.Record(worker.deviceID, err)
+			op.done <- err
+		}
+	}
+}
+
+// runGated runs run once no other operation is running and spacing has passed since the
+// last one finished
+func (q *DeviceQueue) runGated(run func() error) error {
+	q.send.Lock()
+	defer q.send.Unlock()
+	if q.spacing != nil {
+		if wait := q.spacing() - time.Since(q.lastSend); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	defer func() { q.lastSend = time.Now() }()
+	return runRecovered(run)
+}
+
+// runRecovered runs run, turning a panic into an error so it can't take down the worker
+// and with it every later command for the device
+func runRecovered(run func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("device operation panicked: %v", p)
+		}
+	}()
+	return run()
+}
@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestDeviceQueueSerializesDevice(t *testing.T) {
+	queue := NewDeviceQueue(nil, false, nil)
+
+	var active, maxActive atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			queue.submit("AA:BB", "", func() error {
+				n := active.Add(1)
+				defer active.Add(-1)
+				if n > maxActive.Load() {
+					maxActive.Store(n)
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+		})
+	}
+	wg.Wait()
+	if got := maxActive.Load(); got != 1 {
+		t.Errorf("expected one command at a time, saw %d at once", got)
+	}
+}
+
+func TestDeviceQueueSerializesSends(t *testing.T) {
+	queue := NewDeviceQueue(nil, false, nil)
+
+	// Like the govee library's single sender, a send fails if another is in flight
+	var sending atomic.Bool
+	send := func() error {
+		if !sending.CompareAndSwap(false, true) {
+			return errors.New("channel blocked or closed")
+		}
+		defer sending.Store(false)
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+
+	var failed atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Go(func() {
+			if err := queue.submit(fmt.Sprintf("device-%d", i), "", send); err != nil {
+				failed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	if n := failed.Load(); n != 0 {
+		t.Errorf("expected commands for different devices never to overlap, %d failed", n)
+	}
+}
+
+func TestDeviceQueueSpacing(t *testing.T) {
+	const spacing = 20 * time.Millisecond
+	queue := NewDeviceQueue(func() time.Duration { return spacing }, false, nil)
+
+	// The gap applies across devices, since they share the library's sender
+	var first, second time.Time
+	queue.submit("AA:BB", "", func() error { first = time.Now(); return nil })
+	queue.submit("CC:DD", "", func() error { second = time.Now(); return nil })
+	if gap := second.Sub(first); gap < spacing {
+		t.Errorf("expected commands at least %s apart, got %s", spacing, gap)
+	}
+}
+
+func TestDeviceQueueRecoversPanics(t *testing.T) {
+	queue := NewDeviceQueue(nil, false, nil)
+
+	if err := queue.submit("AA:BB", "", func() error { panic("boom") }); err == nil {
+		t.Error("expected a panicking operation to return an error")
+	}
+	// The device's worker survives to run the next command
+	if err := queue.submit("AA:BB", "", func() error { return nil }); err != nil {
+		t.Errorf("expected the next command to run, got %v", err)
+	}
+}
+
+func TestNilDeviceQueue(t *testing.T) {
+	var queue *DeviceQueue
+	ran := false
+	err := queue.Do(&govee.Device{}, func(*govee.Device) error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Errorf("expected a nil queue to run the operation directly, got ran=%v err=%v", ran, err)
+	}
+}
+
+// pendingCount returns how many commands are waiting for deviceID
+func (q *DeviceQueue) pendingCount(deviceID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if worker, ok := q.workers[deviceID]; ok {
+		return len(worker.pending)
+	}
+	return 0
+}
+
+// waitForPending blocks until n commands are waiting for deviceID
+func waitForPending(t *testing.T, q *DeviceQueue, deviceID string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.pendingCount(deviceID) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d pending commands, got %d", n, q.pendingCount(deviceID))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDeviceQueueCoalesce(t *testing.T) {
+	for _, coalesce := range []bool{true, false} {
+		t.Run(fmt.Sprintf("coalesce=%v", coalesce), func(t *testing.T) {
+			queue := NewDeviceQueue(nil, coalesce, nil)
+
+			// Hold the device busy so the next commands have to wait
+			started, release := make(chan struct{}), make(chan struct{})
+			busy := make(chan error, 1)
+			go func() {
+				busy <- queue.submit("AA:BB", "", func() error { close(started); <-release; return nil })
+			}()
+			<-started
+
+			var mu sync.Mutex
+			var ran []string
+			record := func(name string) func() error {
+				return func() error {
+					mu.Lock()
+					defer mu.Unlock()
+					ran = append(ran, name)
+					return nil
+				}
+			}
+			commands := []struct{ kind, name string }{
+				{"set_color", "red"},
+				{"set_brightness", "dim"},
+				{"set_color", "green"},
+				{"set_color", "blue"},
+			}
+			errs := make([]error, len(commands))
+			results := make([]chan error, len(commands))
+			lastColor := -1
+			for i, cmd := range commands {
+				results[i] = make(chan error, 1)
+				go func() { results[i] <- queue.submit("AA:BB", cmd.kind, record(cmd.name)) }()
+				// Wait for each command to be queued, so they arrive in a known order
+				if coalesce && cmd.kind == "set_color" && lastColor >= 0 {
+					errs[lastColor] = <-results[lastColor]
+					results[lastColor] = nil
+					waitForPending(t, queue, "AA:BB", 2)
+				} else {
+					waitForPending(t, queue, "AA:BB", i+1)
+				}
+				if cmd.kind == "set_color" {
+					lastColor = i
+				}
+			}
+
+			close(release)
+			<-busy
+			for i, result := range results {
+				if result != nil {
+					errs[i] = <-result
+				}
+			}
+
+			want := []string{"red", "dim", "green", "blue"}
+			if coalesce {
+				// Only the latest color ran; the brightness in between is kept
+				want = []string{"dim", "blue"}
+				if !errors.Is(errs[0], ErrSuperseded) || !errors.Is(errs[2], ErrSuperseded) {
+					t.Errorf("expected the stale colors to be superseded, got %v", errs)
+				}
+				if errs[1] != nil || errs[3] != nil {
+					t.Errorf("expected the commands that ran to succeed, got %v", errs)
+				}
+			} else {
+				for i, err := range errs {
+					if err != nil {
+						t.Errorf("expected command %d to succeed, got %v", i, err)
+					}
+				}
+			}
+			if !slices.Equal(ran, want) {
+				t.Errorf("expected %v to run, got %v", want, ran)
+			}
+		})
+	}
+}
+
+func TestDeviceQueueBreaker(t *testing.T) {
+	queue := NewDeviceQueue(nil, false, NewBreakers(2, time.Hour))
+
+	runs := 0
+	failing := func() error {
+		runs++
+		return errors.New("no route to host")
+	}
+	queue.submit("AA:BB", "", failing)
+	queue.submit("AA:BB", "", failing)
+
+	if err := queue.submit("AA:BB", "", failing); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("expected ErrBreakerOpen once the breaker opened, got %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("expected the short-circuited command not to run, got %d runs", runs)
+	}
+	if err := queue.submit("CC:DD", "", func() error { return nil }); err != nil {
+		t.Errorf("expected other devices to carry on, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// DefaultShutdownTimeout bounds how long shutdown waits for devices to be turned off
+const DefaultShutdownTimeout = 5 * time.Second
+
+// ApplyAll queues op for every device at once, so it waits its turn behind any other
+// command, and returns the combined errors. It gives up once
+// timeout has passed so a device that never answers can't hold up the caller; the devices
+// not yet reached are left as they are.
+func ApplyAll(queue *DeviceQueue, devices []*govee.Device, op Operation, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		var mu sync.Mutex
+		var errs []error
+		var wg sync.WaitGroup
+		for _, device := range devices {
+			wg.Go(func() {
+				if err := queue.Do(device, op); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", DeviceLabel(device), err))
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
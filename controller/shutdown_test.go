@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestApplyAll(t *testing.T) {
+	devices := []*govee.Device{{}, {}}
+
+	var calls atomic.Int32
+	op := func(*govee.Device) error {
+		if calls.Add(1) == 1 {
+			return errors.New("channel blocked or closed")
+		}
+		return nil
+	}
+	if err := ApplyAll(nil, devices, op, time.Second); err == nil {
+		t.Error("expected the failed device's error to be returned")
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected every device to be tried, got %d calls", got)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	hang := func(*govee.Device) error {
+		<-release
+		return nil
+	}
+	start := time.Now()
+	if err := ApplyAll(nil, devices, hang, 50*time.Millisecond); err == nil {
+		t.Error("expected a timeout error for a hung device")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected ApplyAll to give up after the timeout, took %s", elapsed)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// StatusCache remembers when each device last reported its status, so rapid polling can
+// reuse the state the device already holds instead of asking it again. A nil cache never
+// holds anything. It is safe for concurrent use.
+type StatusCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	refreshed map[string]time.Time
+}
+
+// NewStatusCache returns a cache whose entries last ttl. A ttl of 0 disables caching.
+func NewStatusCache(ttl time.Duration) *StatusCache {
+	return &StatusCache{ttl: ttl, refreshed: make(map[string]time.Time)}
+}
+
+// Fresh reports whether the device's status was refreshed within the TTL
+func (c *StatusCache) Fresh(deviceID string) bool {
+	if c == nil || c.ttl <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	refreshed, ok := c.refreshed[deviceID]
+	return ok && time.Since(refreshed) < c.ttl
+}
+
+// Mark records that the device's status was just refreshed
+func (c *StatusCache) Mark(deviceID string) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshed[deviceID] = time.Now()
+}
+
+// Invalidate forgets the given devices, so their next status read asks the device. Call
+// it after sending a device a command.
+func (c *StatusCache) Invalidate(deviceIDs ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, deviceID := range deviceIDs {
+		delete(c.refreshed, deviceID)
+	}
+}
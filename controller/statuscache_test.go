@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusCache(t *testing.T) {
+	cache := NewStatusCache(time.Hour)
+	if cache.Fresh("AA:BB") {
+		t.Error("expected an unknown device not to be fresh")
+	}
+
+	cache.Mark("AA:BB")
+	cache.Mark("CC:DD")
+	if !cache.Fresh("AA:BB") {
+		t.Error("expected a just-refreshed device to be fresh")
+	}
+
+	cache.Invalidate("AA:BB")
+	if cache.Fresh("AA:BB") {
+		t.Error("expected an invalidated device not to be fresh")
+	}
+	if !cache.Fresh("CC:DD") {
+		t.Error("expected other devices to stay fresh")
+	}
+
+	expired := NewStatusCache(time.Millisecond)
+	expired.Mark("AA:BB")
+	time.Sleep(5 * time.Millisecond)
+	if expired.Fresh("AA:BB") {
+		t.Error("expected the entry to expire after the TTL")
+	}
+
+	for _, cache := range []*StatusCache{nil, NewStatusCache(0)} {
+		cache.Mark("AA:BB")
+		if cache.Fresh("AA:BB") {
+			t.Error("expected a nil or disabled cache never to be fresh")
+		}
+		cache.Invalidate("AA:BB")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"math"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Summary is a rollup of the state of every device
+type Summary struct {
+	Total         int  `json:"total"`
+	On            int  `json:"on"`
+	Off           int  `json:"off"`
+	AvgBrightness int  `json:"avgBrightness"`
+	AllSameColor  bool `json:"allSameColor"`
+}
+
+// Summarize rolls up the last reported state of devices.
+// Callers should refresh the devices with RequestStatus first.
+func Summarize(devices []*govee.Device) Summary {
+	summary := Summary{Total: len(devices)}
+	if len(devices) == 0 {
+		return summary
+	}
+
+	totalBrightness := 0
+	summary.AllSameColor = true
+	first := devices[0].Color()
+	for _, device := range devices {
+		if PoweredOn(device) {
+			summary.On++
+		} else {
+			summary.Off++
+		}
+		totalBrightness += int(device.Brightness())
+		if device.Color() != first {
+			summary.AllSameColor = false
+		}
+	}
+	summary.AvgBrightness = int(math.Round(float64(totalBrightness) / float64(len(devices))))
+	return summary
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name     string
+		devices  []*govee.Device
+		expected Summary
+	}{
+		{
+			name:     "no devices",
+			devices:  nil,
+			expected: Summary{},
+		},
+		{
+			name:     "devices with no reported state",
+			devices:  []*govee.Device{{}, {}},
+			expected: Summary{Total: 2, Off: 2, AllSameColor: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.devices); got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// DefaultTransitionSteps is how many intermediate colors a fade sends when no step count is configured
+const DefaultTransitionSteps = 20
+
+// InterpolateColors returns steps colors moving linearly from from to to. The last color is always to.
+func InterpolateColors(from, to govee.Color, steps int) []govee.Color {
+	if steps < 1 {
+		steps = 1
+	}
+	colors := make([]govee.Color, steps)
+	for i := 1; i <= steps; i++ {
+		colors[i-1] = govee.Color{
+			R: lerp(from.R, to.R, i, steps),
+			G: lerp(from.G, to.G, i, steps),
+			B: lerp(from.B, to.B, i, steps),
+		}
+	}
+	return colors
+}
+
+// InterpolateBrightness returns steps brightness levels moving linearly from from to to.
+// The last level is always to.
+func InterpolateBrightness(from, to govee.Brightness, steps int) []govee.Brightness {
+	if steps < 1 {
+		steps = 1
+	}
+	levels := make([]govee.Brightness, steps)
+	for i := 1; i <= steps; i++ {
+		levels[i-1] = govee.Brightness(lerp(uint(from), uint(to), i, steps))
+	}
+	return levels
+}
+
+// lerp returns the value step/steps of the way from a to b, rounded to the nearest integer
+func lerp(a, b uint, step, steps int) uint {
+	delta := (int(b) - int(a)) * step
+	// Round the change half away from zero so fades up and down move by the same amounts
+	if delta >= 0 {
+		return uint(int(a) + (delta+steps/2)/steps)
+	}
+	return uint(int(a) - (-delta+steps/2)/steps)
+}
+
+// FadeColor returns an operation that fades a device from its current color to target
+// over duration, in the given number of steps. If the current color can't be read the
+// device is set to target immediately.
+func FadeColor(target govee.Color, duration time.Duration, steps int) Operation {
+	return func(device *govee.Device) error {
+		if duration <= 0 {
+			return device.SetColor(target)
+		}
+		if err := device.RequestStatus(); err != nil {
+			return device.SetColor(target)
+		}
+
+		colors := InterpolateColors(device.Color(), target, steps)
+		interval := duration / time.Duration(len(colors))
+		for i, color := range colors {
+			if err := device.SetColor(color); err != nil {
+				return err
+			}
+			if i < len(colors)-1 {
+				time.Sleep(interval)
+			}
+		}
+		return nil
+	}
+}
+
+// FadedBrightness remembers the brightness each device had before FadeOff dimmed it, so
+// the next turn on restores that level rather than the near-zero one the fade ended on.
+// A nil FadedBrightness remembers nothing. It is safe for concurrent use.
+type FadedBrightness struct {
+	mu     sync.Mutex
+	levels map[string]govee.Brightness
+}
+
+// NewFadedBrightness returns an empty FadedBrightness
+func NewFadedBrightness() *FadedBrightness {
+	return &FadedBrightness{levels: make(map[string]govee.Brightness)}
+}
+
+// Remember records the brightness a device had before fading off
+func (f *FadedBrightness) Remember(deviceID string, level govee.Brightness) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.levels[deviceID] = level
+}
+
+// Take returns and forgets the brightness remembered for a device
+func (f *FadedBrightness) Take(deviceID string) (govee.Brightness, bool) {
+	if f == nil {
+		return 0, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	level, ok := f.levels[deviceID]
+	delete(f.levels, deviceID)
+	return level, ok
+}
+
+// TurnOn returns an operation that turns a device on, restoring the brightness it had
+// before FadeOff dimmed it. Every turn on goes through it, or FadeOn, so a light faded off
+// never comes back at the lowest brightness.
+func (f *FadedBrightness) TurnOn() Operation {
+	return FadeOn(0, 0, f)
+}
+
+// TurnOff returns an operation that turns a device off straight away, forgetting any
+// brightness remembered for it so a later turn on doesn't restore a stale level
+func (f *FadedBrightness) TurnOff() Operation {
+	return f.Forget(TurnOff())
+}
+
+// SetBrightness returns an operation that sets a device's brightness, forgetting any
+// remembered level so the next turn on keeps the one set here
+func (f *FadedBrightness) SetBrightness(brightness govee.Brightness) Operation {
+	return f.Forget(SetBrightness(brightness))
+}
+
+// Forget returns an operation that forgets the brightness remembered for a device and then
+// runs op, for operations that leave the device at a brightness of their own
+func (f *FadedBrightness) Forget(op Operation) Operation {
+	return func(device *govee.Device) error {
+		f.Take(device.DeviceID())
+		return op(device)
+	}
+}
+
+// FadeOn returns an operation that turns a device on at the lowest brightness and ramps it
+// up to the brightness it had before it was turned off, over duration in the given number
+// of steps. A level remembered in faded by FadeOff wins over the reported one, and is also
+// restored when duration is 0. Devices that report no brightness ramp up to full.
+func FadeOn(duration time.Duration, steps int, faded *FadedBrightness) Operation {
+	return func(device *govee.Device) error {
+		remembered, restore := faded.Take(device.DeviceID())
+		if duration <= 0 {
+			if err := device.TurnOn(); err != nil {
+				return err
+			}
+			if restore {
+				return device.SetBrightness(remembered)
+			}
+			return nil
+		}
+		// Fall back to the last reported brightness if the device doesn't answer
+		_ = device.RequestStatus()
+		target := device.Brightness()
+		if restore {
+			target = remembered
+		}
+		if target == 0 {
+			target = 100
+		}
+
+		if err := device.SetBrightness(1); err != nil {
+			return err
+		}
+		if err := device.TurnOn(); err != nil {
+			return err
+		}
+		levels := InterpolateBrightness(1, target, steps)
+		interval := duration / time.Duration(len(levels))
+		for _, level := range levels {
+			time.Sleep(interval)
+			if err := device.SetBrightness(level); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// FadeOff returns an operation that ramps a device's brightness down to the lowest level
+// over duration, in the given number of steps, and then turns it off. The brightness it
+// started from is remembered in faded for the next FadeOn. A device that is already off or
+// at the lowest level is turned off straight away, and with no duration it is turned off
+// like faded.TurnOff.
+func FadeOff(duration time.Duration, steps int, faded *FadedBrightness) Operation {
+	return func(device *govee.Device) error {
+		if duration <= 0 {
+			return faded.TurnOff()(device)
+		}
+		// Fall back to the last reported state if the device doesn't answer
+		_ = device.RequestStatus()
+		start := device.Brightness()
+		if !PoweredOn(device) || start <= 1 {
+			return device.TurnOff()
+		}
+
+		faded.Remember(device.DeviceID(), start)
+		levels := InterpolateBrightness(start, 1, steps)
+		interval := duration / time.Duration(len(levels))
+		for _, level := range levels {
+			if err := device.SetBrightness(level); err != nil {
+				return err
+			}
+			time.Sleep(interval)
+		}
+		return device.TurnOff()
+	}
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestInterpolateColors(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     govee.Color
+		to       govee.Color
+		steps    int
+		expected []govee.Color
+	}{
+		{
+			name:     "single step jumps to target",
+			from:     govee.Color{R: 0, G: 0, B: 0},
+			to:       govee.Color{R: 255, G: 128, B: 0},
+			steps:    1,
+			expected: []govee.Color{{R: 255, G: 128, B: 0}},
+		},
+		{
+			name:  "fade up",
+			from:  govee.Color{R: 0, G: 0, B: 0},
+			to:    govee.Color{R: 100, G: 200, B: 40},
+			steps: 4,
+			expected: []govee.Color{
+				{R: 25, G: 50, B: 10},
+				{R: 50, G: 100, B: 20},
+				{R: 75, G: 150, B: 30},
+				{R: 100, G: 200, B: 40},
+			},
+		},
+		{
+			name:  "fade down",
+			from:  govee.Color{R: 255, G: 0, B: 100},
+			to:    govee.Color{R: 0, G: 0, B: 0},
+			steps: 2,
+			expected: []govee.Color{
+				{R: 127, G: 0, B: 50},
+				{R: 0, G: 0, B: 0},
+			},
+		},
+		{
+			name:     "non-positive steps treated as one",
+			from:     govee.Color{R: 10, G: 10, B: 10},
+			to:       govee.Color{R: 20, G: 20, B: 20},
+			steps:    0,
+			expected: []govee.Color{{R: 20, G: 20, B: 20}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InterpolateColors(tt.from, tt.to, tt.steps)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInterpolateBrightness(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     govee.Brightness
+		to       govee.Brightness
+		steps    int
+		expected []govee.Brightness
+	}{
+		{"ramp up", 1, 81, 4, []govee.Brightness{21, 41, 61, 81}},
+		{"single step jumps to target", 1, 50, 1, []govee.Brightness{50}},
+		{"non-positive steps treated as one", 1, 100, 0, []govee.Brightness{100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InterpolateBrightness(tt.from, tt.to, tt.steps)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFadedBrightness(t *testing.T) {
+	faded := NewFadedBrightness()
+	if _, ok := faded.Take("AA:BB"); ok {
+		t.Error("expected nothing remembered for an unknown device")
+	}
+
+	faded.Remember("AA:BB", 80)
+	faded.Remember("CC:DD", 40)
+	if level, ok := faded.Take("AA:BB"); !ok || level != 80 {
+		t.Errorf("expected 80, got %v (remembered %v)", level, ok)
+	}
+	// Taking a level forgets it, so only the first turn on after a fade restores it
+	if _, ok := faded.Take("AA:BB"); ok {
+		t.Error("expected the level to be forgotten once taken")
+	}
+	if level, ok := faded.Take("CC:DD"); !ok || level != 40 {
+		t.Errorf("expected 40, got %v (remembered %v)", level, ok)
+	}
+
+	// An operation that sets its own brightness forgets the remembered level first; the
+	// zero-value device's ID is ""
+	faded.Remember("", 60)
+	ran := false
+	if err := faded.Forget(func(*govee.Device) error { ran = true; return nil })(&govee.Device{}); err != nil || !ran {
+		t.Fatalf("expected the wrapped operation to run, got %v", err)
+	}
+	if _, ok := faded.Take(""); ok {
+		t.Error("expected Forget to clear the remembered level")
+	}
+
+	var none *FadedBrightness
+	none.Remember("AA:BB", 80)
+	if _, ok := none.Take("AA:BB"); ok {
+		t.Error("expected a nil FadedBrightness to remember nothing")
+	}
+}
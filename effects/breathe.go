@@ -0,0 +1,77 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package effects
+
+import (
+	"context"
+	"math"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// breatheStepsPerPeriod is how many brightness updates are sent per breathe cycle
+const breatheStepsPerPeriod = 20
+
+// Brightness limits for the breathe effect. The low end stays above 0 so the light never goes fully dark.
+const (
+	breatheMinBrightness = 1
+	breatheMaxBrightness = 100
+)
+
+// BreatheBrightness returns the brightness at elapsed time into a breathe cycle of the
+// given period. It follows a sine curve starting and ending at the minimum brightness.
+func BreatheBrightness(elapsed, period time.Duration) govee.Brightness {
+	phase := 2 * math.Pi * float64(elapsed%period) / float64(period)
+	level := (1 - math.Cos(phase)) / 2
+	return govee.Brightness(math.Round(breatheMinBrightness + level*(breatheMaxBrightness-breatheMinBrightness)))
+}
+
+// Breathe holds color on devices and ramps their brightness up and down for the given
+// number of cycles, then leaves them at full brightness. It stops early when ctx is cancelled.
+// Every device write is handed to apply, which sends it (e.g. through the device queue) and
+// deals with any error; the effect carries on regardless.
+func Breathe(ctx context.Context, devices []*govee.Device, color govee.Color, period time.Duration, cycles int, apply func(device *govee.Device, write func(*govee.Device) error)) error {
+	for _, device := range devices {
+		apply(device, func(d *govee.Device) error { return d.SetColor(color) })
+	}
+
+	ticker := time.NewTicker(period / breatheStepsPerPeriod)
+	defer ticker.Stop()
+
+	start := time.Now()
+	total := period * time.Duration(cycles)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		elapsed := time.Since(start)
+		if elapsed >= total {
+			break
+		}
+		brightness := BreatheBrightness(elapsed, period)
+		for _, device := range devices {
+			apply(device, func(d *govee.Device) error { return d.SetBrightness(brightness) })
+		}
+	}
+
+	for _, device := range devices {
+		apply(device, func(d *govee.Device) error { return d.SetBrightness(breatheMaxBrightness) })
+	}
+	return nil
+}
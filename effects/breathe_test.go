@@ -0,0 +1,33 @@
+package effects
+
+import (
+	"testing"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestBreatheBrightness(t *testing.T) {
+	period := 4 * time.Second
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		min     govee.Brightness
+		max     govee.Brightness
+	}{
+		{"start of cycle", 0, 1, 1},
+		{"quarter cycle", time.Second, 50, 51},
+		{"peak", 2 * time.Second, 100, 100},
+		{"three quarters", 3 * time.Second, 50, 51},
+		{"next cycle", 4 * time.Second, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BreatheBrightness(tt.elapsed, period); got < tt.min || got > tt.max {
+				t.Errorf("expected %d-%d, got %d", tt.min, tt.max, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package effects runs long-lived light effects and tracks them so that conflicting
+// effects can be cancelled.
+package effects
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Effect is a single running effect
+type Effect struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	DeviceIDs []string  `json:"devices"`
+	StartedAt time.Time `json:"startedAt"`
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Done is closed once the effect has stopped
+func (e *Effect) Done() <-chan struct{} {
+	return e.done
+}
+
+// Wait blocks until the effect stops and returns its error
+func (e *Effect) Wait() error {
+	<-e.done
+	return e.err
+}
+
+// Manager tracks running effects. It is safe for concurrent use.
+type Manager struct {
+	mu      sync.Mutex
+	running map[string]*Effect
+}
+
+// NewManager returns a manager with no running effects
+func NewManager() *Manager {
+	return &Manager{running: make(map[string]*Effect)}
+}
+
+// Start runs an effect on the given devices in the background. Any running effect that
+// shares a device is cancelled first, and run is not called until those effects have
+// stopped, so two effects never drive the same device at once.
+func (m *Manager) Start(name string, deviceIDs []string, run func(ctx context.Context) error) *Effect {
+	ctx, cancel := context.WithCancel(context.Background())
+	effect := &Effect{
+		ID:        newID(),
+		Name:      name,
+		DeviceIDs: deviceIDs,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	var superseded []*Effect
+	for id, running := range m.running {
+		if overlaps(running.DeviceIDs, deviceIDs) {
+			running.cancel()
+			superseded = append(superseded, running)
+			delete(m.running, id)
+		}
+	}
+	m.running[effect.ID] = effect
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		for _, previous := range superseded {
+			<-previous.done
+		}
+		effect.err = run(ctx)
+		close(effect.done)
+
+		m.mu.Lock()
+		if m.running[effect.ID] == effect {
+			delete(m.running, effect.ID)
+		}
+		m.mu.Unlock()
+	}()
+
+	return effect
+}
+
+// Stop cancels a running effect, reporting whether it was found
+func (m *Manager) Stop(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	effect, ok := m.running[id]
+	if !ok {
+		return false
+	}
+	effect.cancel()
+	delete(m.running, id)
+	return true
+}
+
+// Count returns how many effects are running
+func (m *Manager) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.running)
+}
+
+// List returns the running effects, oldest first
+func (m *Manager) List() []Effect {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Effect, 0, len(m.running))
+	for _, effect := range m.running {
+		list = append(list, Effect{
+			ID:        effect.ID,
+			Name:      effect.Name,
+			DeviceIDs: effect.DeviceIDs,
+			StartedAt: effect.StartedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+	return list
+}
+
+// overlaps reports whether two device ID lists share a device
+func overlaps(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newID returns a random effect ID
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package effects
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestManagerStartRunsEffect(t *testing.T) {
+	m := NewManager()
+	effect := m.Start("test", []string{"AA:BB"}, func(ctx context.Context) error {
+		return nil
+	})
+
+	if effect.ID == "" {
+		t.Errorf("expected an effect ID")
+	}
+	if err := effect.Wait(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestManagerCancelsOverlappingEffects(t *testing.T) {
+	m := NewManager()
+
+	first := m.Start("first", []string{"AA:BB", "CC:DD"}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	unrelated := m.Start("unrelated", []string{"EE:FF"}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	started := make(chan struct{})
+	second := m.Start("second", []string{"cc:dd"}, func(ctx context.Context) error {
+		close(started)
+		return nil
+	})
+
+	if err := first.Wait(); err != context.Canceled {
+		t.Errorf("expected first effect to be cancelled, got %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("second effect did not start")
+	}
+	if err := second.Wait(); err != nil {
+		t.Errorf("expected second effect to finish cleanly, got %v", err)
+	}
+
+	select {
+	case <-unrelated.Done():
+		t.Errorf("expected effect on other devices to keep running")
+	default:
+	}
+}
+
+func TestManagerStopAndList(t *testing.T) {
+	m := NewManager()
+	effect := m.Start("breathe", []string{"AA:BB"}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	list := m.List()
+	if len(list) != 1 || list[0].ID != effect.ID || list[0].Name != "breathe" {
+		t.Fatalf("expected running effect in list, got %+v", list)
+	}
+	if m.Count() != 1 {
+		t.Errorf("expected 1 running effect, got %d", m.Count())
+	}
+
+	if !m.Stop(effect.ID) {
+		t.Errorf("expected stop to find the effect")
+	}
+	if err := effect.Wait(); err != context.Canceled {
+		t.Errorf("expected effect to be cancelled, got %v", err)
+	}
+	if m.Stop(effect.ID) {
+		t.Errorf("expected second stop to report missing effect")
+	}
+	if len(m.List()) != 0 || m.Count() != 0 {
+		t.Errorf("expected no running effects, got %+v", m.List())
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package effects
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Wave calls apply on each device in turn, waiting stagger between consecutive devices,
+// so a row of lights changes one after another. It stops early when ctx is cancelled.
+func Wave(ctx context.Context, devices []*govee.Device, stagger time.Duration, apply func(device *govee.Device)) error {
+	for i, device := range devices {
+		if i > 0 {
+			timer := time.NewTimer(stagger)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		apply(device)
+	}
+	return nil
+}
+
+// WaveOrder returns devices in the order a wave should visit them: those named in order
+// first, in that order, then the rest sorted by device ID, so the same devices always
+// give the same wave
+func WaveOrder(devices []*govee.Device, order []string) []*govee.Device {
+	return waveOrder(devices, (*govee.Device).DeviceID, order)
+}
+
+// waveOrder sorts a copy of items as WaveOrder describes, identifying each item by id
+func waveOrder[T any](items []T, id func(T) string, order []string) []T {
+	position := func(item T) int {
+		for i, named := range order {
+			if strings.EqualFold(named, id(item)) {
+				return i
+			}
+		}
+		return len(order)
+	}
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b T) int {
+		if pa, pb := position(a), position(b); pa != pb {
+			return pa - pb
+		}
+		return strings.Compare(id(a), id(b))
+	})
+	return sorted
+}
@@ -0,0 +1,69 @@
+package effects
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestWave(t *testing.T) {
+	devices := []*govee.Device{{}, {}, {}}
+	stagger := 20 * time.Millisecond
+
+	var visited []*govee.Device
+	var times []time.Time
+	err := Wave(context.Background(), devices, stagger, func(device *govee.Device) {
+		visited = append(visited, device)
+		times = append(times, time.Now())
+	})
+	if err != nil {
+		t.Fatalf("expected the wave to finish, got %v", err)
+	}
+	if len(visited) != len(devices) {
+		t.Fatalf("expected every device to be visited, got %d", len(visited))
+	}
+	for i := range devices {
+		if visited[i] != devices[i] {
+			t.Errorf("expected device %d to be visited in order", i)
+		}
+		if i > 0 && times[i].Sub(times[i-1]) < stagger {
+			t.Errorf("expected at least %s between devices %d and %d, got %s", stagger, i-1, i, times[i].Sub(times[i-1]))
+		}
+	}
+}
+
+func TestWaveCancelled(t *testing.T) {
+	devices := []*govee.Device{{}, {}, {}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	visited := 0
+	err := Wave(ctx, devices, time.Hour, func(*govee.Device) {
+		visited++
+		cancel()
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if visited != 1 {
+		t.Errorf("expected the wave to stop after the first device, got %d", visited)
+	}
+}
+
+func TestWaveOrder(t *testing.T) {
+	id := func(s string) string { return s }
+	ids := []string{"dd", "bb", "AA", "cc"}
+
+	got := waveOrder(ids, id, []string{"cc", "aa"})
+	if want := []string{"cc", "AA", "bb", "dd"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := waveOrder(ids, id, nil); !slices.Equal(got, []string{"AA", "bb", "cc", "dd"}) {
+		t.Errorf("expected devices sorted by ID, got %v", got)
+	}
+	if ids[0] != "dd" {
+		t.Error("expected the input to be left unsorted")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package groups holds named subsets of devices that light operations can target.
+package groups
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry maps group names to device IDs. It is safe for concurrent use, and a nil
+// Registry behaves as an empty, read-only one.
+type Registry struct {
+	mu     sync.RWMutex
+	groups map[string][]string
+}
+
+// New returns an empty registry
+func New() *Registry {
+	return &Registry{groups: make(map[string][]string)}
+}
+
+// Load builds a registry from a definition such as "desk=id1,id2;shelf=id3"
+func Load(definition string) (*Registry, error) {
+	r := New()
+	for _, entry := range strings.Split(definition, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid group %q: expected name=deviceID,deviceID", entry)
+		}
+		if err := r.Set(name, strings.Split(value, ",")); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Get returns the device IDs in the named group
+func (r *Registry) Get(name string) ([]string, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	deviceIDs, ok := r.groups[normalize(name)]
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), deviceIDs...), true
+}
+
+// Set creates or replaces a group
+func (r *Registry) Set(name string, deviceIDs []string) error {
+	name = normalize(name)
+	if name == "" {
+		return fmt.Errorf("group name must not be empty")
+	}
+	var ids []string
+	for _, id := range deviceIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("group %q must contain at least one device", name)
+	}
+	if r == nil {
+		return fmt.Errorf("group registry is not configured")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups[name] = ids
+	return nil
+}
+
+// Delete removes a group, reporting whether it existed
+func (r *Registry) Delete(name string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = normalize(name)
+	if _, ok := r.groups[name]; !ok {
+		return false
+	}
+	delete(r.groups, name)
+	return true
+}
+
+// All returns a copy of every group
+func (r *Registry) All() map[string][]string {
+	all := make(map[string][]string)
+	if r == nil {
+		return all
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, deviceIDs := range r.groups {
+		all[name] = append([]string(nil), deviceIDs...)
+	}
+	return all
+}
+
+// Names returns the group names in sorted order
+func (r *Registry) Names() []string {
+	all := r.All()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Contains reports whether deviceID is in the named group. Device IDs are compared
+// case-insensitively.
+func (r *Registry) Contains(name, deviceID string) bool {
+	deviceIDs, _ := r.Get(name)
+	for _, id := range deviceIDs {
+		if strings.EqualFold(id, deviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
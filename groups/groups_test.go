@@ -0,0 +1,98 @@
+package groups
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	r, err := Load("Desk=AA:BB, CC:DD; shelf=EE:FF")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if ids, ok := r.Get("desk"); !ok || !reflect.DeepEqual(ids, []string{"AA:BB", "CC:DD"}) {
+		t.Errorf("expected desk group, got %v", ids)
+	}
+	if names := r.Names(); !reflect.DeepEqual(names, []string{"desk", "shelf"}) {
+		t.Errorf("expected sorted names, got %v", names)
+	}
+	if !r.Contains("shelf", "ee:ff") {
+		t.Errorf("expected case-insensitive device match")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+	}{
+		{"missing equals", "desk"},
+		{"empty name", "=AA:BB"},
+		{"no devices", "desk= , "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.definition); err == nil {
+				t.Errorf("expected error for %q", tt.definition)
+			}
+		})
+	}
+}
+
+func TestRegistryCRUD(t *testing.T) {
+	r := New()
+
+	if err := r.Set("desk", []string{"AA:BB"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := r.Set("desk", []string{"CC:DD"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if ids, _ := r.Get("desk"); !reflect.DeepEqual(ids, []string{"CC:DD"}) {
+		t.Errorf("expected desk to be replaced, got %v", ids)
+	}
+
+	if !r.Delete("DESK") {
+		t.Errorf("expected delete to succeed")
+	}
+	if r.Delete("desk") {
+		t.Errorf("expected second delete to report missing group")
+	}
+	if _, ok := r.Get("desk"); ok {
+		t.Errorf("expected desk to be gone")
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	if _, ok := r.Get("desk"); ok {
+		t.Errorf("expected nil registry to have no groups")
+	}
+	if r.Delete("desk") {
+		t.Errorf("expected nil registry delete to report missing group")
+	}
+	if len(r.All()) != 0 {
+		t.Errorf("expected nil registry to be empty")
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	r := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			r.Set("desk", []string{"AA:BB"})
+		}()
+		go func() {
+			defer wg.Done()
+			r.Get("desk")
+			r.All()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/jwhitcraft/lights-http/controller"
+)
+
+// MaintenanceSwitch turns maintenance mode on and off
+type MaintenanceSwitch interface {
+	Enabled() bool
+	Set(enabled bool) bool
+}
+
+// Reloader re-reads the configuration and applies the settings that don't need a restart.
+// It returns the names of the reloaded settings that changed and of the changed settings
+// that only take effect after a restart.
+type Reloader interface {
+	Reload() (reloaded, restartRequired []string, err error)
+}
+
+// AdminHandler serves operator endpoints under /admin
+type AdminHandler struct {
+	Maintenance MaintenanceSwitch
+	Disabled    *controller.DisabledDevices
+	Aliases     *controller.Aliases
+	Reloader    Reloader
+	Logger      *slog.Logger
+}
+
+// ReloadResponse lists what a config reload changed
+type ReloadResponse struct {
+	Status          string   `json:"status"`
+	Reloaded        []string `json:"reloaded"`
+	RestartRequired []string `json:"restart_required"`
+	RequestID       string   `json:"requestID"`
+}
+
+// SetMaintenance turns maintenance mode on or off from ?enabled=true|false. While it is
+// on, /lights endpoints return 503 without touching the devices.
+func (h *AdminHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidMaintenance, "enabled must be true or false")
+		return
+	}
+
+	if h.Maintenance.Set(enabled) {
+		if enabled {
+			h.Logger.Warn("Maintenance mode enabled, HTTP light operations are rejected; MQTT, schedules and sun mode still run", "requestID", requestID)
+		} else {
+			h.Logger.Info("Maintenance mode disabled", "requestID", requestID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"maintenance": h.Maintenance.Enabled(),
+		"requestID":   requestID,
+	})
+}
+
+// ListDisabled returns the IDs of the disabled devices
+func (h *AdminHandler) ListDisabled(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"disabled":  h.Disabled.List(),
+		"requestID": getRequestID(r.Context()),
+	})
+}
+
+// DisableDevice takes the device named in the path, by ID or alias, out of service until
+// it is enabled again or the server restarts. The device doesn't need to have been
+// discovered yet.
+func (h *AdminHandler) DisableDevice(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// EnableDevice puts the device named in the path back into service
+func (h *AdminHandler) EnableDevice(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *AdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	requestID := getRequestID(r.Context())
+	deviceID := h.Aliases.Resolve(r.PathValue("id"))
+
+	if disabled && h.Disabled.Disable(deviceID) {
+		h.Logger.Warn("Device disabled, operations will skip it", "device", deviceID, "requestID", requestID)
+	}
+	if !disabled && h.Disabled.Enable(deviceID) {
+		h.Logger.Info("Device enabled", "device", deviceID, "requestID", requestID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"device":    deviceID,
+		"disabled":  disabled,
+		"requestID": requestID,
+	})
+}
+
+// Reload re-reads the configuration from the environment and .env. Log level, color
+// presets and the command gap take effect at once; other changed settings are
+// listed as needing a restart. An invalid configuration leaves the running settings alone.
+func (h *AdminHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	reloaded, restartRequired, err := h.Reloader.Reload()
+	if err != nil {
+		h.Logger.Error("Failed to reload config", "error", err, "requestID", requestID)
+		writeJSONError(w, r, http.StatusInternalServerError, errCodeReloadFailed, err.Error())
+		return
+	}
+	h.Logger.Info("Reloaded config", "reloaded", reloaded, "restartRequired", restartRequired, "requestID", requestID)
+	if len(restartRequired) > 0 {
+		h.Logger.Warn("Changed settings need a restart to take effect", "settings", restartRequired, "requestID", requestID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReloadResponse{
+		Status:          "reloaded",
+		Reloaded:        nonNil(reloaded),
+		RestartRequired: nonNil(restartRequired),
+		RequestID:       requestID,
+	})
+}
+
+// nonNil turns a nil slice into an empty one so it encodes as [] rather than null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/history"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// maxBatchSteps caps how many operations a single batch may contain
+const maxBatchSteps = 20
+
+// Batch step results
+const (
+	batchStepSuccess = "success"
+	batchStepError   = "error"
+	batchStepSkipped = "skipped"
+)
+
+// BatchStep is a single operation in a batch request
+type BatchStep struct {
+	Op          string `json:"op"`
+	R           int    `json:"r"`
+	G           int    `json:"g"`
+	B           int    `json:"b"`
+	Brightness  int    `json:"brightness"`
+	Temperature int    `json:"temperature"`
+	Color       string `json:"color"`
+}
+
+// BatchRequest is either a bare array of steps or an object with options
+type BatchRequest struct {
+	Operations      []BatchStep `json:"operations"`
+	ContinueOnError bool        `json:"continue_on_error"`
+}
+
+// UnmarshalJSON accepts both [{"op": "on"}, ...] and {"operations": [...], "continue_on_error": true}.
+// Unknown fields are rejected in either form, as parseAndValidateJSON does for other bodies;
+// its decoder setting doesn't reach a custom unmarshaler.
+func (b *BatchRequest) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return decodeStrict(trimmed, &b.Operations)
+	}
+	type batchRequest BatchRequest
+	return decodeStrict(data, (*batchRequest)(b))
+}
+
+// decodeStrict unmarshals data into v, rejecting fields v doesn't define
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// BatchStepResult reports the outcome of one batch step
+type BatchStepResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse reports the outcome of every step in a batch
+type BatchResponse struct {
+	Success   bool              `json:"success"`
+	Results   []BatchStepResult `json:"results"`
+	RequestID string            `json:"requestID"`
+}
+
+// Batch applies a sequence of operations in order. Every step is validated first, so an
+// invalid one rejects the whole batch before any device is changed. Processing stops at the
+// first step that fails on a device, unless continue_on_error is set; later steps are
+// reported as skipped.
+func (h *LightsHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	var req BatchRequest
+	if !h.parseAndValidateJSON(w, r, &req, "batch") {
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchSteps {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidBatch, fmt.Sprintf("batch must contain between 1 and %d operations", maxBatchSteps))
+		return
+	}
+
+	type batchStep struct {
+		name      string
+		operation controller.Operation
+		settings  *history.Settings
+	}
+	steps := make([]batchStep, len(req.Operations))
+	for i, step := range req.Operations {
+		name, operation, settings, err := h.batchOperation(step)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidBatch, fmt.Sprintf("operation %d: %v", i, err))
+			return
+		}
+		steps[i] = batchStep{name, operation, settings}
+	}
+
+	devices, ok := h.targetDevices(w, r)
+	if !ok {
+		return
+	}
+	h.Logger.Info("Executing batch", "requestID", requestID, "steps", len(req.Operations), "continue_on_error", req.ContinueOnError)
+
+	response := BatchResponse{Success: true, RequestID: requestID}
+	dryRun := h.dryRun(r)
+	stopped := false
+	for i, step := range req.Operations {
+		result := BatchStepResult{Index: i, Op: step.Op}
+		if stopped {
+			result.Status = batchStepSkipped
+			response.Results = append(response.Results, result)
+			continue
+		}
+
+		if failed := failedDevices(h.runOperation(requestID, steps[i].name, devices, steps[i].operation, steps[i].settings, dryRun)); len(failed) > 0 {
+			result.Status = batchStepError
+			result.Error = operationFailedMessage(steps[i].name, failed)
+		} else {
+			result.Status = batchStepSuccess
+		}
+		response.Results = append(response.Results, result)
+
+		if result.Status != batchStepSuccess {
+			response.Success = false
+			stopped = !req.ContinueOnError
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// batchOperation validates a batch step and returns its operation name and operation
+func (h *LightsHandler) batchOperation(step BatchStep) (string, controller.Operation, *history.Settings, error) {
+	switch step.Op {
+	case "on":
+		return "turn_on", h.Faded.TurnOn(), history.Power(true), nil
+	case "off":
+		return "turn_off", h.Faded.TurnOff(), history.Power(false), nil
+	case "rgb":
+		if step.R < 0 || step.R > 255 || step.G < 0 || step.G > 255 || step.B < 0 || step.B > 255 {
+			return "", nil, nil, fmt.Errorf("RGB values must be between 0 and 255")
+		}
+		color := govee.Color{R: uint(step.R), G: uint(step.G), B: uint(step.B)}
+		return "set_color", controller.SetColor(color), history.Color(color), nil
+	case "color":
+		color, ok := h.colorTable().Lookup(step.Color)
+		if !ok {
+			return "", nil, nil, fmt.Errorf("unknown color %q", step.Color)
+		}
+		return "set_color", controller.SetColor(color), history.NamedColor(step.Color, color), nil
+	case "warm":
+		warm := controller.Sequence(h.Faded.TurnOn(), controller.SetColorKelvin(govee.NewColorKelvin(controller.WarmKelvin)))
+		return "set_warm", warm, history.Temperature(controller.WarmKelvin), nil
+	case "brightness":
+		if step.Brightness < 0 || step.Brightness > 100 {
+			return "", nil, nil, fmt.Errorf("brightness must be between 0 and 100")
+		}
+		return "set_brightness", h.Faded.SetBrightness(govee.Brightness(step.Brightness)), history.Brightness(step.Brightness), nil
+	case "colortemp":
+		if !validColorTemp(step.Temperature) {
+			return "", nil, nil, errors.New(colorTempRangeMessage)
+		}
+		return "set_color_temp", controller.SetColorKelvin(govee.NewColorKelvin(uint(step.Temperature))), history.Temperature(step.Temperature), nil
+	default:
+		return "", nil, nil, fmt.Errorf("unknown op %q", step.Op)
+	}
+}
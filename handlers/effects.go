@@ -0,0 +1,259 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/effects"
+	"github.com/jwhitcraft/lights-http/history"
+	"github.com/jwhitcraft/lights-http/metrics"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Limits for breathe effect requests
+const (
+	minBreathePeriodMs = 500
+	maxBreathePeriodMs = 60000
+	maxBreatheCycles   = 100
+)
+
+// Limits for wave effect requests
+const (
+	defaultWaveStaggerMs = 200
+	maxWaveStaggerMs     = 10000
+)
+
+// EffectResponse is returned when an effect is started or finishes
+type EffectResponse struct {
+	Status    string `json:"status"`
+	EffectID  string `json:"effectID"`
+	RequestID string `json:"requestID"`
+}
+
+// Breathe holds a color while ramping brightness up and down. By default it runs in the
+// background and returns 202 with the effect ID; with "async": false it returns once finished.
+func (h *LightsHandler) Breathe(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	var req struct {
+		R        int   `json:"r"`
+		G        int   `json:"g"`
+		B        int   `json:"b"`
+		PeriodMs int   `json:"period_ms"`
+		Cycles   int   `json:"cycles"`
+		Async    *bool `json:"async"`
+	}
+	if !h.parseAndValidateJSON(w, r, &req, "breathe") {
+		return
+	}
+
+	if req.R < 0 || req.R > 255 || req.G < 0 || req.G > 255 || req.B < 0 || req.B > 255 {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidRGB, "RGB values must be between 0 and 255")
+		return
+	}
+	if req.PeriodMs < minBreathePeriodMs || req.PeriodMs > maxBreathePeriodMs {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidEffect, fmt.Sprintf("period_ms must be between %d and %d", minBreathePeriodMs, maxBreathePeriodMs))
+		return
+	}
+	if req.Cycles < 1 || req.Cycles > maxBreatheCycles {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidEffect, fmt.Sprintf("cycles must be between 1 and %d", maxBreatheCycles))
+		return
+	}
+
+	devices, ok := h.targetDevices(w, r)
+	if !ok {
+		return
+	}
+	deviceIDs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.DeviceID())
+	}
+
+	color := govee.Color{R: uint(req.R), G: uint(req.G), B: uint(req.B)}
+	period := time.Duration(req.PeriodMs) * time.Millisecond
+	dryRun := h.dryRun(r)
+	effect := h.Effects.Start("breathe", deviceIDs, func(ctx context.Context) error {
+		err := effects.Breathe(ctx, devices, color, period, req.Cycles, func(device *govee.Device, write func(*govee.Device) error) {
+			if dryRun {
+				h.Logger.Debug("Dry run: would update device during breathe effect", "device", controller.DeviceLabel(device), "requestID", requestID)
+				return
+			}
+			if err := h.Queue.Do(device, write); err != nil {
+				h.Logger.Error("Failed to update device during breathe effect", "device", controller.DeviceLabel(device), "requestID", requestID, "error", err)
+				metrics.LightDeviceOperationsTotal.WithLabelValues("breathe", "error", device.DeviceID()).Inc()
+			}
+		})
+		// The effect leaves the devices wherever its last step put them
+		h.StatusCache.Invalidate(deviceIDs...)
+		result := "success"
+		switch {
+		case err != nil:
+			result = "cancelled"
+		case dryRun:
+			result = "dry_run"
+		}
+		metrics.LightOperationsTotal.WithLabelValues("breathe", result, "").Inc()
+		return err
+	})
+	h.Logger.Info("Started breathe effect",
+		"requestID", requestID,
+		"effectID", effect.ID,
+		"color", fmt.Sprintf("rgb(%d,%d,%d)", req.R, req.G, req.B),
+		"period_ms", req.PeriodMs,
+		"cycles", req.Cycles)
+
+	w.Header().Set("Content-Type", "application/json")
+	if req.Async == nil || *req.Async {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(EffectResponse{Status: "breathe effect started", EffectID: effect.ID, RequestID: requestID})
+		return
+	}
+
+	status := "breathe effect finished"
+	if err := effect.Wait(); err != nil {
+		status = "breathe effect cancelled"
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(EffectResponse{Status: status, EffectID: effect.ID, RequestID: requestID})
+}
+
+// Wave sets a color on one device at a time, stagger_ms apart, so it ripples down a row of
+// lights. Devices are visited in the order of the group named by ?group=, otherwise by
+// device ID. Like Breathe it runs in the background unless "async" is false.
+func (h *LightsHandler) Wave(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	var req struct {
+		R         int   `json:"r"`
+		G         int   `json:"g"`
+		B         int   `json:"b"`
+		StaggerMs *int  `json:"stagger_ms"`
+		Async     *bool `json:"async"`
+	}
+	if !h.parseAndValidateJSON(w, r, &req, "wave") {
+		return
+	}
+
+	if req.R < 0 || req.R > 255 || req.G < 0 || req.G > 255 || req.B < 0 || req.B > 255 {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidRGB, "RGB values must be between 0 and 255")
+		return
+	}
+	staggerMs := defaultWaveStaggerMs
+	if req.StaggerMs != nil {
+		staggerMs = *req.StaggerMs
+	}
+	if staggerMs < 0 || staggerMs > maxWaveStaggerMs {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidEffect, fmt.Sprintf("stagger_ms must be between 0 and %d", maxWaveStaggerMs))
+		return
+	}
+
+	devices, ok := h.targetDevices(w, r)
+	if !ok {
+		return
+	}
+	members, _ := h.Groups.Get(r.URL.Query().Get("group"))
+	order := make([]string, 0, len(members))
+	for _, member := range members {
+		order = append(order, h.Aliases.Resolve(member))
+	}
+	devices = effects.WaveOrder(devices, order)
+	deviceIDs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.DeviceID())
+	}
+
+	color := govee.Color{R: uint(req.R), G: uint(req.G), B: uint(req.B)}
+	operation := controller.SetColor(color)
+	settings := history.Color(color)
+	dryRun := h.dryRun(r)
+	effect := h.Effects.Start("wave", deviceIDs, func(ctx context.Context) error {
+		failed := false
+		err := effects.Wave(ctx, devices, time.Duration(staggerMs)*time.Millisecond, func(device *govee.Device) {
+			if dryRun {
+				h.Logger.Info("Dry run: would set wave color on device", "device", controller.DeviceLabel(device), "requestID", requestID)
+				return
+			}
+			err := h.apply(requestID, "wave", device, operation)
+			h.StatusCache.Invalidate(device.DeviceID())
+			if err != nil {
+				failed = true
+				h.Logger.Error("Failed to update device during wave effect", "device", controller.DeviceLabel(device), "requestID", requestID, "error", err)
+				metrics.LightDeviceOperationsTotal.WithLabelValues("wave", "error", device.DeviceID()).Inc()
+				return
+			}
+			metrics.LightDeviceOperationsTotal.WithLabelValues("wave", "success", device.DeviceID()).Inc()
+			h.History.Record(device.DeviceID(), "wave", *settings)
+		})
+		result := "success"
+		switch {
+		case err != nil:
+			result = "cancelled"
+		case failed:
+			result = "error"
+		case dryRun:
+			result = "dry_run"
+		}
+		metrics.LightOperationsTotal.WithLabelValues("wave", result, colorLabel(settings)).Inc()
+		return err
+	})
+	h.Logger.Info("Started wave effect",
+		"requestID", requestID,
+		"effectID", effect.ID,
+		"color", fmt.Sprintf("rgb(%d,%d,%d)", req.R, req.G, req.B),
+		"stagger_ms", staggerMs,
+		"devices", len(devices))
+
+	w.Header().Set("Content-Type", "application/json")
+	if req.Async == nil || *req.Async {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(EffectResponse{Status: "wave effect started", EffectID: effect.ID, RequestID: requestID})
+		return
+	}
+
+	status := "wave effect finished"
+	if err := effect.Wait(); err != nil {
+		status = "wave effect cancelled"
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(EffectResponse{Status: status, EffectID: effect.ID, RequestID: requestID})
+}
+
+// ListEffects returns the running effects
+func (h *LightsHandler) ListEffects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Effects.List())
+}
+
+// StopEffect cancels the running effect named in the path
+func (h *LightsHandler) StopEffect(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	id := r.PathValue("id")
+	if !h.Effects.Stop(id) {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownEffect, fmt.Sprintf("no running effect %q", id))
+		return
+	}
+	h.Logger.Info("Stopped effect", "effectID", id, "requestID", requestID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(EffectResponse{Status: "effect stopped", EffectID: id, RequestID: requestID})
+}
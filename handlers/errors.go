@@ -0,0 +1,82 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Stable, machine-readable error codes returned in the "code" field of error responses.
+// Clients may branch on these, so existing values must not change.
+const (
+	errCodeInvalidJSON          = "invalid_json"
+	errCodeInvalidRGB           = "invalid_rgb"
+	errCodeInvalidTransition    = "invalid_transition"
+	errCodeInvalidColorTemp     = "invalid_color_temperature"
+	errCodeInvalidColorName     = "invalid_color_name"
+	errCodeInvalidBrightness    = "invalid_brightness"
+	errCodeInvalidEffect        = "invalid_effect"
+	errCodeInvalidBatch         = "invalid_batch"
+	errCodeInvalidPatch         = "invalid_patch"
+	errCodeInvalidPower         = "invalid_power"
+	errCodeInvalidDevice        = "invalid_device"
+	errCodeInvalidSnapshot      = "invalid_snapshot"
+	errCodeUnknownEffect        = "unknown_effect"
+	errCodeUnknownColor         = "unknown_color"
+	errCodeUnknownGroup         = "unknown_group"
+	errCodeUnknownDevice        = "unknown_device"
+	errCodeUnknownModel         = "unknown_model"
+	errCodeUnknownSnapshot      = "unknown_snapshot"
+	errCodeDeviceDisabled       = "device_disabled"
+	errCodeInvalidGroup         = "invalid_group"
+	errCodeInvalidSchedule      = "invalid_schedule"
+	errCodeUnknownSchedule      = "unknown_schedule"
+	errCodeInvalidSunMode       = "invalid_sun_mode"
+	errCodeInvalidMaintenance   = "invalid_maintenance"
+	errCodeReloadFailed         = "reload_failed"
+	errCodeUnsupportedMedia     = "unsupported_media_type"
+	errCodeMethodNotAllowed     = "method_not_allowed"
+	errCodeOperationFailed      = "operation_failed"
+	errCodeNoDevices            = "no_devices"
+	errCodeStreamingUnsupported = "streaming_unsupported"
+	errCodeInternal             = "internal_error"
+)
+
+// ErrorDetail describes what went wrong in an error response
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the JSON body returned for every handler error
+type ErrorResponse struct {
+	Error     ErrorDetail `json:"error"`
+	RequestID string      `json:"requestID"`
+}
+
+// writeJSONError writes a structured error response with the given status, code and message
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error: ErrorDetail{
+			Code:    code,
+			Message: message,
+		},
+		RequestID: getRequestID(r.Context()),
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GroupRequest is the body accepted when creating or replacing a group
+type GroupRequest struct {
+	Devices []string `json:"devices"`
+}
+
+// GroupResponse describes a single group
+type GroupResponse struct {
+	Name    string   `json:"name"`
+	Devices []string `json:"devices"`
+}
+
+// ListGroups returns every group keyed by name
+func (h *LightsHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Groups.All())
+}
+
+// GetGroup returns the devices in the group named in the path
+func (h *LightsHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	deviceIDs, ok := h.Groups.Get(name)
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownGroup, fmt.Sprintf("unknown group %q", name))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(GroupResponse{Name: name, Devices: deviceIDs})
+}
+
+// PutGroup creates or replaces the group named in the path
+func (h *LightsHandler) PutGroup(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	name := r.PathValue("name")
+
+	var req GroupRequest
+	if !h.parseAndValidateJSON(w, r, &req, "group") {
+		return
+	}
+	if err := h.Groups.Set(name, req.Devices); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidGroup, err.Error())
+		return
+	}
+	h.Logger.Info("Group saved", "group", name, "devices", req.Devices, "requestID", requestID)
+
+	deviceIDs, _ := h.Groups.Get(name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(GroupResponse{Name: name, Devices: deviceIDs})
+}
+
+// DeleteGroup removes the group named in the path
+func (h *LightsHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	name := r.PathValue("name")
+	if !h.Groups.Delete(name) {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownGroup, fmt.Sprintf("unknown group %q", name))
+		return
+	}
+	h.Logger.Info("Group deleted", "group", name, "requestID", requestID)
+	w.WriteHeader(http.StatusNoContent)
+}
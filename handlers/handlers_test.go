@@ -1,15 +1,33 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"log/slog"
 
+	"github.com/gorilla/websocket"
+	"github.com/jwhitcraft/lights-http/colors"
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/effects"
+	"github.com/jwhitcraft/lights-http/groups"
+	"github.com/jwhitcraft/lights-http/history"
+	"github.com/jwhitcraft/lights-http/metrics"
+	"github.com/jwhitcraft/lights-http/middleware"
+	"github.com/jwhitcraft/lights-http/schedules"
+	"github.com/jwhitcraft/lights-http/snapshots"
+	"github.com/jwhitcraft/lights-http/solar"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	govee "github.com/swrm-io/go-vee"
 )
 
@@ -21,6 +39,10 @@ func (m *MockController) Devices() []*govee.Device {
 	return []*govee.Device{}
 }
 
+func (m *MockController) State() string {
+	return controller.StateReady
+}
+
 func TestTurnOn(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
@@ -46,6 +68,10 @@ func TestTurnOn(t *testing.T) {
 	if response["status"] != "lights turned on" {
 		t.Errorf("expected status 'lights turned on', got %s", response["status"])
 	}
+
+	if response["requestID"] == "" {
+		t.Errorf("expected requestID to be set")
+	}
 }
 
 func TestTurnOff(t *testing.T) {
@@ -75,6 +101,57 @@ func TestTurnOff(t *testing.T) {
 	}
 }
 
+func TestPower(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedResult string
+		expectedCode   string
+	}{
+		{"on", `{"on": true}`, http.StatusOK, "lights turned on", ""},
+		{"off", `{"on": false}`, http.StatusOK, "lights turned off", ""},
+		{"on with a fade", `{"on": true, "fade_ms": 500}`, http.StatusOK, "lights turned on", ""},
+		{"fade out of range", `{"on": true, "fade_ms": -1}`, http.StatusBadRequest, "", "invalid_transition"},
+		{"off with a fade", `{"on": false, "fade_ms": 2000}`, http.StatusOK, "lights turned off", ""},
+		{"off fade out of range", `{"on": false, "fade_ms": 10001}`, http.StatusBadRequest, "", "invalid_transition"},
+		{"missing on", `{}`, http.StatusBadRequest, "", "invalid_power"},
+		{"null on", `{"on": null}`, http.StatusBadRequest, "", "invalid_power"},
+		{"string on", `{"on": "true"}`, http.StatusBadRequest, "", "invalid_json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/power", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Power(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				assertErrorCode(t, w, tt.expectedCode)
+				return
+			}
+			var response map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response["status"] != tt.expectedResult {
+				t.Errorf("expected status %q, got %q", tt.expectedResult, response["status"])
+			}
+		})
+	}
+}
+
 func TestRed(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
@@ -129,20 +206,23 @@ func TestDarkRed(t *testing.T) {
 	}
 }
 
-func TestBrightness(t *testing.T) {
+func TestOrangeWithCustomColors(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	table, err := colors.Load("orange=255,140,0", "")
+	if err != nil {
+		t.Fatalf("failed to load colors: %v", err)
+	}
 	handler := &LightsHandler{
 		Controller: mockController,
 		Logger:     logger,
+		Colors:     table,
 	}
 
-	body := map[string]int{"brightness": 50}
-	bodyBytes, _ := json.Marshal(body)
-	req := httptest.NewRequest("POST", "/lights/brightness", bytes.NewReader(bodyBytes))
+	req := httptest.NewRequest("POST", "/lights/orange", nil)
 	w := httptest.NewRecorder()
 
-	handler.Brightness(w, req)
+	handler.Orange(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
@@ -153,184 +233,260 @@ func TestBrightness(t *testing.T) {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if response["status"] != "brightness set" {
-		t.Errorf("expected status 'brightness set', got %s", response["status"])
+	if response["status"] != "lights set to orange" {
+		t.Errorf("expected status 'lights set to orange', got %s", response["status"])
 	}
 }
 
-func TestStatus(t *testing.T) {
+func TestNamedColor(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	table, err := colors.Load("teal=0,128,128", "")
+	if err != nil {
+		t.Fatalf("failed to load colors: %v", err)
+	}
 	handler := &LightsHandler{
 		Controller: mockController,
 		Logger:     logger,
+		Colors:     table,
 	}
 
-	req := httptest.NewRequest("GET", "/lights/status", nil)
-	w := httptest.NewRecorder()
-
-	handler.Status(w, req)
-
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	tests := []struct {
+		name           string
+		color          string
+		expectedStatus int
+	}{
+		{"default preset", "red", http.StatusOK},
+		{"configured preset", "teal", http.StatusOK},
+		{"unknown preset", "chartreuse", http.StatusNotFound},
 	}
 
-	var response []map[string]interface{}
-	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/color/"+tt.color, nil)
+			req.SetPathValue("name", tt.color)
+			w := httptest.NewRecorder()
 
-	// Since mock has no devices, expect empty array
-	if len(response) != 0 {
-		t.Errorf("expected empty array, got %v", response)
+			handler.NamedColor(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusNotFound {
+				assertErrorCode(t, w, "unknown_color")
+			}
+		})
 	}
 }
 
-func TestRGB(t *testing.T) {
-	mockController := &MockController{}
+func TestCSSColor(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
 	handler := &LightsHandler{
-		Controller: mockController,
+		Controller: &MockController{},
 		Logger:     logger,
 	}
 
-	body := map[string]int{"r": 255, "g": 128, "b": 0}
-	bodyBytes, _ := json.Marshal(body)
-	req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader(bodyBytes))
-	w := httptest.NewRecorder()
-
-	handler.RGB(w, req)
-
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedHint   string
+	}{
+		{"known name", `{"name": "tomato"}`, http.StatusOK, ""},
+		{"spaced and capitalized", `{"name": "Cornflower Blue"}`, http.StatusOK, ""},
+		{"typo", `{"name": "tomatoe"}`, http.StatusBadRequest, "did you mean"},
+		{"unknown", `{"name": "xyzzyplugh"}`, http.StatusBadRequest, "CSS color name"},
+		{"missing", `{}`, http.StatusBadRequest, "CSS color name"},
 	}
 
-	var response map[string]string
-	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/named", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
 
-	if response["status"] != "lights set to rgb" {
-		t.Errorf("expected status 'lights set to rgb', got %s", response["status"])
+			handler.CSSColor(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				body := w.Body.String()
+				assertErrorCode(t, w, "invalid_color_name")
+				if !strings.Contains(body, tt.expectedHint) {
+					t.Errorf("expected hint %q in %s", tt.expectedHint, body)
+				}
+			}
+		})
 	}
 }
 
-func TestRGBInvalidValues(t *testing.T) {
+func TestGroupTargeting(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	registry, err := groups.Load("desk=AA:BB")
+	if err != nil {
+		t.Fatalf("failed to load groups: %v", err)
+	}
 	handler := &LightsHandler{
 		Controller: mockController,
 		Logger:     logger,
+		Groups:     registry,
 	}
 
 	tests := []struct {
-		name string
-		body map[string]int
+		name           string
+		url            string
+		expectedStatus int
 	}{
-		{"negative r", map[string]int{"r": -1, "g": 0, "b": 0}},
-		{"r too high", map[string]int{"r": 256, "g": 0, "b": 0}},
-		{"negative g", map[string]int{"r": 0, "g": -1, "b": 0}},
-		{"g too high", map[string]int{"r": 0, "g": 256, "b": 0}},
-		{"negative b", map[string]int{"r": 0, "g": 0, "b": -1}},
-		{"b too high", map[string]int{"r": 0, "g": 0, "b": 256}},
+		{"no group", "/lights/on", http.StatusOK},
+		{"known group", "/lights/on?group=desk", http.StatusOK},
+		{"unknown group", "/lights/on?group=shelf", http.StatusNotFound},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			bodyBytes, _ := json.Marshal(tt.body)
-			req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader(bodyBytes))
+			req := httptest.NewRequest("POST", tt.url, nil)
 			w := httptest.NewRecorder()
 
-			handler.RGB(w, req)
+			handler.TurnOn(w, req)
 
-			if w.Code != http.StatusBadRequest {
-				t.Errorf("expected status 400, got %d", w.Code)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusNotFound {
+				assertErrorCode(t, w, "unknown_group")
 			}
 		})
 	}
 }
 
-func TestRGBInvalidJSON(t *testing.T) {
-	mockController := &MockController{}
+func TestRequireDevices(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
 	handler := &LightsHandler{
-		Controller: mockController,
-		Logger:     logger,
+		Controller:     &MockController{},
+		Logger:         logger,
+		RequireDevices: true,
 	}
 
-	req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader([]byte("invalid json")))
+	req := httptest.NewRequest("POST", "/lights/on", nil)
 	w := httptest.NewRecorder()
+	handler.TurnOn(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 with no devices, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "no_devices")
 
-	handler.RGB(w, req)
-
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", w.Code)
+	// Without the guard the operation reaches no devices and still succeeds
+	handler.RequireDevices = false
+	w = httptest.NewRecorder()
+	handler.TurnOn(w, httptest.NewRequest("POST", "/lights/on", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 when no devices are allowed, got %d", w.Code)
 	}
 }
 
-func TestColorTemp(t *testing.T) {
-	mockController := &MockController{}
+func TestModelTargeting(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
 	handler := &LightsHandler{
-		Controller: mockController,
+		Controller: staticController{&govee.Device{}},
 		Logger:     logger,
 	}
 
-	body := map[string]int{"temperature": 3000}
-	bodyBytes, _ := json.Marshal(body)
-	req := httptest.NewRequest("POST", "/lights/colortemp", bytes.NewReader(bodyBytes))
+	// Undiscovered devices report no SKU, so no model can match
+	req := httptest.NewRequest("POST", "/lights/on?model=H6159", nil)
 	w := httptest.NewRecorder()
+	handler.TurnOn(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "unknown_model")
+}
 
-	handler.ColorTemp(w, req)
+func TestGroupsCRUD(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Groups:     groups.New(),
+	}
 
+	// Create
+	req := httptest.NewRequest("PUT", "/lights/groups/desk", strings.NewReader(`{"devices": ["AA:BB", "CC:DD"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("name", "desk")
+	w := httptest.NewRecorder()
+	handler.PutGroup(w, req)
 	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+		t.Fatalf("expected status 200, got %d", w.Code)
 	}
 
-	var response map[string]string
-	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+	// Read
+	req = httptest.NewRequest("GET", "/lights/groups/desk", nil)
+	req.SetPathValue("name", "desk")
+	w = httptest.NewRecorder()
+	handler.GetGroup(w, req)
+	var group GroupResponse
+	if err := json.NewDecoder(w.Body).Decode(&group); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
+	if group.Name != "desk" || len(group.Devices) != 2 {
+		t.Errorf("unexpected group %+v", group)
+	}
 
-	if response["status"] != "color temperature set" {
-		t.Errorf("expected status 'color temperature set', got %s", response["status"])
+	// List
+	req = httptest.NewRequest("GET", "/lights/groups", nil)
+	w = httptest.NewRecorder()
+	handler.ListGroups(w, req)
+	var all map[string][]string
+	if err := json.NewDecoder(w.Body).Decode(&all); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(all["desk"]) != 2 {
+		t.Errorf("expected desk in group list, got %v", all)
+	}
+
+	// Delete
+	req = httptest.NewRequest("DELETE", "/lights/groups/desk", nil)
+	req.SetPathValue("name", "desk")
+	w = httptest.NewRecorder()
+	handler.DeleteGroup(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
 	}
+
+	// Deleted group is gone
+	req = httptest.NewRequest("GET", "/lights/groups/desk", nil)
+	req.SetPathValue("name", "desk")
+	w = httptest.NewRecorder()
+	handler.GetGroup(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "unknown_group")
 }
 
-func TestColorTempInvalidValues(t *testing.T) {
-	mockController := &MockController{}
+func TestPutGroupInvalid(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
 	handler := &LightsHandler{
-		Controller: mockController,
+		Controller: &MockController{},
 		Logger:     logger,
+		Groups:     groups.New(),
 	}
 
-	tests := []struct {
-		name string
-		temp int
-	}{
-		{"too low", 1500},
-		{"too high", 10000},
-		{"negative", -1000},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			body := map[string]int{"temperature": tt.temp}
-			bodyBytes, _ := json.Marshal(body)
-			req := httptest.NewRequest("POST", "/lights/colortemp", bytes.NewReader(bodyBytes))
-			w := httptest.NewRecorder()
-
-			handler.ColorTemp(w, req)
+	req := httptest.NewRequest("PUT", "/lights/groups/desk", strings.NewReader(`{"devices": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("name", "desk")
+	w := httptest.NewRecorder()
+	handler.PutGroup(w, req)
 
-			if w.Code != http.StatusBadRequest {
-				t.Errorf("expected status 400, got %d", w.Code)
-			}
-		})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
 	}
+	assertErrorCode(t, w, "invalid_group")
 }
 
-func TestColorTempInvalidJSON(t *testing.T) {
+func TestBrightness(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
 	handler := &LightsHandler{
@@ -338,79 +494,1213 @@ func TestColorTempInvalidJSON(t *testing.T) {
 		Logger:     logger,
 	}
 
-	req := httptest.NewRequest("POST", "/lights/colortemp", bytes.NewReader([]byte("invalid json")))
+	body := map[string]int{"brightness": 50}
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/lights/brightness", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	handler.ColorTemp(w, req)
+	handler.Brightness(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", w.Code)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response["status"] != "brightness set" {
+		t.Errorf("expected status 'brightness set', got %s", response["status"])
 	}
 }
 
-func TestHealth(t *testing.T) {
+func TestStatus(t *testing.T) {
 	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
-	handler := &HealthHandler{
+	handler := &LightsHandler{
 		Controller: mockController,
 		Logger:     logger,
-		StartTime:  time.Now().Add(-time.Hour), // 1 hour ago
 	}
 
-	req := httptest.NewRequest("GET", "/health", nil)
+	req := httptest.NewRequest("GET", "/lights/status", nil)
 	w := httptest.NewRecorder()
 
-	handler.Health(w, req)
+	handler.Status(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
 
-	var response HealthStatus
+	var response []map[string]interface{}
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if response.Status != "ok" { // Controller is initialized
-		t.Errorf("expected status 'ok', got %s", response.Status)
+	// Since mock has no devices, expect empty array
+	if len(response) != 0 {
+		t.Errorf("expected empty array, got %v", response)
 	}
+}
 
-	if response.Uptime == "" {
-		t.Errorf("expected uptime to be set")
+func TestStatusSetsDeviceCount(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	cache := controller.NewStatusCache(time.Minute)
+	cache.Mark("")
+	devices := &staticController{&govee.Device{}, &govee.Device{}}
+	// Disabled devices are hidden from the payload but still count as discovered
+	disabled := controller.NewDisabledDevices([]string{"kitchen"})
+	handler := &LightsHandler{Controller: devices, Logger: logger, StatusCache: cache, Disabled: disabled}
+
+	handler.Status(httptest.NewRecorder(), httptest.NewRequest("GET", "/lights/status", nil))
+	if got := testutil.ToFloat64(metrics.DevicesTotal); got != 2 {
+		t.Errorf("expected lights_devices_total 2, got %v", got)
 	}
 
-	if len(response.Checks) == 0 {
-		t.Errorf("expected checks to be present")
+	// A device dropping off the network shows up on the next status call
+	*devices = (*devices)[:1]
+	handler.Status(httptest.NewRecorder(), httptest.NewRequest("GET", "/lights/status", nil))
+	if got := testutil.ToFloat64(metrics.DevicesTotal); got != 1 {
+		t.Errorf("expected lights_devices_total 1, got %v", got)
 	}
+}
 
-	// Check controller status
-	if controllerCheck, ok := response.Checks["controller"]; !ok {
-		t.Errorf("expected controller check to be present")
-	} else if controllerCheck.Status != "ok" {
-		t.Errorf("expected controller status 'ok', got %s", controllerCheck.Status)
+func TestStatusETag(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	device := &govee.Device{}
+	// A fresh cache entry keeps Status from asking the fake device for its state
+	cache := controller.NewStatusCache(time.Minute)
+	cache.Mark(device.DeviceID())
+	handler := &LightsHandler{Controller: staticController{device}, Logger: logger, StatusCache: cache}
+
+	req := httptest.NewRequest("GET", "/lights/status", nil)
+	w := httptest.NewRecorder()
+	handler.Status(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+	var response []map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response) != 1 {
+		t.Fatalf("expected one device status, got %v", response)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantCode    int
+	}{
+		{name: "matching tag", ifNoneMatch: etag, wantCode: http.StatusNotModified},
+		{name: "weak tag", ifNoneMatch: "W/" + etag, wantCode: http.StatusNotModified},
+		{name: "tag in a list", ifNoneMatch: `"stale", ` + etag, wantCode: http.StatusNotModified},
+		{name: "wildcard", ifNoneMatch: "*", wantCode: http.StatusNotModified},
+		{name: "stale tag", ifNoneMatch: `"stale"`, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/lights/status", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			w := httptest.NewRecorder()
+			handler.Status(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("expected ETag %s, got %s", etag, got)
+			}
+			if tt.wantCode == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected an empty body with 304, got %q", w.Body.String())
+			}
+		})
+	}
+
+	// Different statuses get a different tag
+	empty := &LightsHandler{Controller: &MockController{}, Logger: logger}
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/lights/status", nil)
+	req.Header.Set("If-None-Match", etag)
+	empty.Status(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 once the statuses change, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("expected a different ETag once the statuses change")
 	}
 }
 
-func TestHealthWithDevices(t *testing.T) {
-	// Create a mock controller with devices
-	mockController := &MockControllerWithDevices{}
+func TestSummary(t *testing.T) {
+	mockController := &MockController{}
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
-	handler := &HealthHandler{
+	handler := &LightsHandler{
 		Controller: mockController,
 		Logger:     logger,
-		StartTime:  time.Now().Add(-time.Minute * 30), // 30 minutes ago
 	}
 
-	req := httptest.NewRequest("GET", "/health", nil)
+	req := httptest.NewRequest("GET", "/lights/summary", nil)
 	w := httptest.NewRecorder()
 
-	handler.Health(w, req)
+	handler.Summary(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
 
-	var response HealthStatus
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	for _, key := range []string{"total", "on", "off", "avgBrightness", "allSameColor"} {
+		if _, ok := response[key]; !ok {
+			t.Errorf("expected %s in response, got %v", key, response)
+		}
+	}
+	if response["total"] != float64(0) {
+		t.Errorf("expected total 0, got %v", response["total"])
+	}
+}
+
+func TestRGB(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	body := map[string]int{"r": 255, "g": 128, "b": 0}
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.RGB(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response["status"] != "lights set to rgb" {
+		t.Errorf("expected status 'lights set to rgb', got %s", response["status"])
+	}
+}
+
+func TestRGBInvalidValues(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name string
+		body map[string]int
+	}{
+		{"negative r", map[string]int{"r": -1, "g": 0, "b": 0}},
+		{"r too high", map[string]int{"r": 256, "g": 0, "b": 0}},
+		{"negative g", map[string]int{"r": 0, "g": -1, "b": 0}},
+		{"g too high", map[string]int{"r": 0, "g": 256, "b": 0}},
+		{"negative b", map[string]int{"r": 0, "g": 0, "b": -1}},
+		{"b too high", map[string]int{"r": 0, "g": 0, "b": 256}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.RGB(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+			assertErrorCode(t, w, "invalid_rgb")
+		})
+	}
+}
+
+func TestUnknownJSONFields(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		handler http.HandlerFunc
+		field   string
+	}{
+		{"rgb typo", "/lights/rgb", `{"r": 255, "g": 0, "bleu": 0}`, handler.RGB, "bleu"},
+		{"brightness typo", "/lights/brightness", `{"birghtness": 50}`, handler.Brightness, "birghtness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.field) {
+				t.Errorf("expected error to name field %s, got %s", tt.field, w.Body.String())
+			}
+			assertErrorCode(t, w, "invalid_json")
+		})
+	}
+}
+
+func TestContentTypeRequired(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		wantStatus  int
+	}{
+		{"missing", "", http.StatusUnsupportedMediaType},
+		{"form", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"text", "text/plain", http.StatusUnsupportedMediaType},
+		{"json with charset", "application/json; charset=utf-8", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/brightness", strings.NewReader(`{"brightness": 50}`))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Brightness(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusUnsupportedMediaType {
+				assertErrorCode(t, w, "unsupported_media_type")
+			}
+		})
+	}
+
+	// Operations without a body don't need a content type
+	req := httptest.NewRequest("POST", "/lights/on", nil)
+	w := httptest.NewRecorder()
+	handler.TurnOn(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for an empty body, got %d", w.Code)
+	}
+}
+
+func TestRGBTransition(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name           string
+		transitionMs   int
+		expectedStatus int
+	}{
+		{"instant", 0, http.StatusOK},
+		{"fade", 2000, http.StatusOK},
+		{"maximum", 10000, http.StatusOK},
+		{"negative", -1, http.StatusBadRequest},
+		{"too long", 10001, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodyBytes, _ := json.Marshal(map[string]int{"r": 0, "g": 0, "b": 255, "transition_ms": tt.transitionMs})
+			req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.RGB(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				assertErrorCode(t, w, "invalid_transition")
+			}
+		})
+	}
+}
+
+func TestTurnOnFade(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"no body", "", http.StatusOK, ""},
+		{"instant", `{"fade_ms": 0}`, http.StatusOK, ""},
+		{"fade", `{"fade_ms": 1500}`, http.StatusOK, ""},
+		{"maximum", `{"fade_ms": 10000}`, http.StatusOK, ""},
+		{"negative", `{"fade_ms": -1}`, http.StatusBadRequest, "invalid_transition"},
+		{"too long", `{"fade_ms": 10001}`, http.StatusBadRequest, "invalid_transition"},
+		{"invalid json", `{"fade_ms": "slow"}`, http.StatusBadRequest, "invalid_json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/on", strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+
+			handler.TurnOn(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				assertErrorCode(t, w, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestCoalescedOperations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: staticController{&govee.Device{}},
+		Logger:     logger,
+		History:    history.NewTracker(),
+		Queue:      controller.NewDeviceQueue(nil, true, nil),
+	}
+	run := func(operationName string, op controller.Operation, settings *history.Settings) int {
+		w := httptest.NewRecorder()
+		handler.executeLightOperation(w, httptest.NewRequest("POST", "/lights/rgb", nil), operationName, "done", op, settings)
+		return w.Code
+	}
+
+	// Keep the device busy so both colors have to wait in its queue
+	started, release := make(chan struct{}), make(chan struct{})
+	busy := make(chan int, 1)
+	go func() {
+		busy <- run("identify", func(*govee.Device) error { close(started); <-release; return nil }, nil)
+	}()
+	<-started
+
+	var mu sync.Mutex
+	var ran []uint
+	codes := make(chan int, 2)
+	for _, red := range []uint{10, 20} {
+		color := govee.Color{R: red}
+		go func() {
+			codes <- run("set_color", func(*govee.Device) error {
+				mu.Lock()
+				defer mu.Unlock()
+				ran = append(ran, red)
+				return nil
+			}, history.Color(color))
+		}()
+	}
+	// Whichever arrived first is dropped as soon as the second is queued, and still succeeds
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("expected the superseded request to succeed, got %d", code)
+	}
+	close(release)
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("expected the latest request to succeed, got %d", code)
+	}
+	<-busy
+
+	if len(ran) != 1 {
+		t.Fatalf("expected only the latest color to be sent, got %v", ran)
+	}
+	entry, ok := handler.History.Get("")
+	if !ok || entry.Color == nil || entry.Color.R != ran[0] {
+		t.Errorf("expected the history to hold the color that was sent (%d), got %+v", ran[0], entry)
+	}
+}
+
+func TestTurnOffFade(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Faded:      controller.NewFadedBrightness(),
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"no body", "", http.StatusOK, ""},
+		{"instant", `{"fade_ms": 0}`, http.StatusOK, ""},
+		{"fade", `{"fade_ms": 2000}`, http.StatusOK, ""},
+		{"maximum", `{"fade_ms": 10000}`, http.StatusOK, ""},
+		{"negative", `{"fade_ms": -1}`, http.StatusBadRequest, "invalid_transition"},
+		{"too long", `{"fade_ms": 10001}`, http.StatusBadRequest, "invalid_transition"},
+		{"invalid json", `{"fade_ms": "slow"}`, http.StatusBadRequest, "invalid_json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/off", strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+
+			handler.TurnOff(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				assertErrorCode(t, w, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestBreathe(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Effects:    effects.NewManager(),
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"async by default", `{"r": 255, "g": 0, "b": 0, "period_ms": 3000, "cycles": 5}`, http.StatusAccepted, ""},
+		{"synchronous", `{"r": 255, "g": 0, "b": 0, "period_ms": 500, "cycles": 1, "async": false}`, http.StatusOK, ""},
+		{"invalid color", `{"r": 256, "g": 0, "b": 0, "period_ms": 3000, "cycles": 5}`, http.StatusBadRequest, "invalid_rgb"},
+		{"period too short", `{"r": 255, "g": 0, "b": 0, "period_ms": 100, "cycles": 5}`, http.StatusBadRequest, "invalid_effect"},
+		{"no cycles", `{"r": 255, "g": 0, "b": 0, "period_ms": 3000, "cycles": 0}`, http.StatusBadRequest, "invalid_effect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/effect/breathe", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Breathe(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				assertErrorCode(t, w, tt.expectedCode)
+				return
+			}
+
+			var response EffectResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.EffectID == "" {
+				t.Errorf("expected an effect ID")
+			}
+		})
+	}
+}
+
+func TestBreatheDryRun(t *testing.T) {
+	// The fake devices would block on a real command, so finishing shows none was sent
+	handler := &LightsHandler{
+		Controller: staticController{&govee.Device{}},
+		Logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{})),
+		Effects:    effects.NewManager(),
+	}
+	dryRuns := metrics.LightOperationsTotal.WithLabelValues("breathe", "dry_run", "")
+	before := testutil.ToFloat64(dryRuns)
+
+	req := httptest.NewRequest("POST", "/lights/effect/breathe?dry_run=true", strings.NewReader(`{"r": 255, "g": 0, "b": 0, "period_ms": 500, "cycles": 1, "async": false}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.Breathe(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := testutil.ToFloat64(dryRuns) - before; got != 1 {
+		t.Errorf("expected one dry_run breathe in the metrics, got %v", got)
+	}
+}
+
+func TestWave(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	// A dry run walks the fake devices without sending them anything
+	handler := &LightsHandler{
+		Controller: staticController{&govee.Device{}, &govee.Device{}},
+		Logger:     logger,
+		Effects:    effects.NewManager(),
+		DryRun:     true,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedText   string
+	}{
+		{"async by default", `{"r": 0, "g": 0, "b": 255}`, http.StatusAccepted, "", "wave effect started"},
+		{"synchronous", `{"r": 0, "g": 0, "b": 255, "stagger_ms": 1, "async": false}`, http.StatusOK, "", "wave effect finished"},
+		{"invalid color", `{"r": -1, "g": 0, "b": 255}`, http.StatusBadRequest, "invalid_rgb", ""},
+		{"negative stagger", `{"r": 0, "g": 0, "b": 255, "stagger_ms": -1}`, http.StatusBadRequest, "invalid_effect", ""},
+		{"stagger too long", `{"r": 0, "g": 0, "b": 255, "stagger_ms": 10001}`, http.StatusBadRequest, "invalid_effect", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/effect/wave", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Wave(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCode != "" {
+				assertErrorCode(t, w, tt.expectedCode)
+				return
+			}
+
+			var response EffectResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.EffectID == "" {
+				t.Errorf("expected an effect ID")
+			}
+			if response.Status != tt.expectedText {
+				t.Errorf("expected status %q, got %q", tt.expectedText, response.Status)
+			}
+		})
+	}
+}
+
+func TestStopEffect(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Effects:    effects.NewManager(),
+	}
+
+	req := httptest.NewRequest("POST", "/lights/effect/breathe", strings.NewReader(`{"r": 255, "g": 0, "b": 0, "period_ms": 3000, "cycles": 5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.Breathe(w, req)
+	var started EffectResponse
+	if err := json.NewDecoder(w.Body).Decode(&started); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	req = httptest.NewRequest("GET", "/lights/effects", nil)
+	w = httptest.NewRecorder()
+	handler.ListEffects(w, req)
+	var running []effects.Effect
+	if err := json.NewDecoder(w.Body).Decode(&running); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(running) != 1 || running[0].ID != started.EffectID {
+		t.Fatalf("expected started effect to be listed, got %+v", running)
+	}
+
+	req = httptest.NewRequest("DELETE", "/lights/effect/"+started.EffectID, nil)
+	req.SetPathValue("id", started.EffectID)
+	w = httptest.NewRecorder()
+	handler.StopEffect(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/lights/effect/"+started.EffectID, nil)
+	req.SetPathValue("id", started.EffectID)
+	w = httptest.NewRecorder()
+	handler.StopEffect(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "unknown_effect")
+}
+
+func TestBatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedSuccess  bool
+		expectedStatuses []string
+	}{
+		{
+			name:             "array of valid steps",
+			body:             `[{"op": "on"}, {"op": "brightness", "brightness": 40}, {"op": "rgb", "r": 255, "g": 0, "b": 0}, {"op": "color", "color": "orange"}]`,
+			expectedSuccess:  true,
+			expectedStatuses: []string{"success", "success", "success", "success"},
+		},
+		{
+			name:             "object with options",
+			body:             `{"operations": [{"op": "warm"}, {"op": "colortemp", "temperature": 3000}], "continue_on_error": true}`,
+			expectedSuccess:  true,
+			expectedStatuses: []string{"success", "success"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Batch(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			var response BatchResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Success != tt.expectedSuccess {
+				t.Errorf("expected success %v, got %v", tt.expectedSuccess, response.Success)
+			}
+			if len(response.Results) != len(tt.expectedStatuses) {
+				t.Fatalf("expected %d results, got %+v", len(tt.expectedStatuses), response.Results)
+			}
+			for i, expected := range tt.expectedStatuses {
+				if response.Results[i].Status != expected {
+					t.Errorf("step %d: expected status %s, got %s", i, expected, response.Results[i].Status)
+				}
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedCode    string
+		expectedApplied []string
+	}{
+		{"power only", `{"power": "on"}`, http.StatusOK, "", []string{"power"}},
+		{"power, color and brightness", `{"brightness": 40, "rgb": {"r": 255, "g": 0, "b": 0}, "power": "on"}`, http.StatusOK, "", []string{"power", "rgb", "brightness"}},
+		{"kelvin and brightness", `{"kelvin": 3000, "brightness": 10}`, http.StatusOK, "", []string{"kelvin", "brightness"}},
+		{"off goes last", `{"power": "off", "brightness": 40}`, http.StatusOK, "", []string{"brightness", "power"}},
+		{"empty body", `{}`, http.StatusBadRequest, "invalid_patch", nil},
+		{"rgb and kelvin", `{"rgb": {"r": 1, "g": 2, "b": 3}, "kelvin": 3000}`, http.StatusBadRequest, "invalid_patch", nil},
+		{"bad power", `{"power": "dim"}`, http.StatusBadRequest, "invalid_power", nil},
+		{"bad rgb", `{"rgb": {"r": 256, "g": 0, "b": 0}}`, http.StatusBadRequest, "invalid_rgb", nil},
+		{"bad kelvin", `{"kelvin": 100}`, http.StatusBadRequest, "invalid_color_temperature", nil},
+		{"bad brightness", `{"power": "on", "brightness": 101}`, http.StatusBadRequest, "invalid_brightness", nil},
+		{"unknown field", `{"hue": 120}`, http.StatusBadRequest, "invalid_json", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/lights", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Patch(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				assertErrorCode(t, w, tt.expectedCode)
+				return
+			}
+			var response PatchResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !slices.Equal(response.Applied, tt.expectedApplied) {
+				t.Errorf("expected applied %v, got %v", tt.expectedApplied, response.Applied)
+			}
+		})
+	}
+}
+
+func TestPatchOperationSettings(t *testing.T) {
+	power, brightness := "off", 40
+	_, _, settings := patchOperation(PatchRequest{Power: &power, Brightness: &brightness}, nil)
+	if settings.On == nil || *settings.On {
+		t.Errorf("expected the settings to record power off, got %v", settings.On)
+	}
+	if settings.Brightness == nil || *settings.Brightness != 40 {
+		t.Errorf("expected the settings to record brightness 40, got %v", settings.Brightness)
+	}
+	if settings.Color != nil || settings.Temperature != nil {
+		t.Errorf("expected absent fields to stay unset, got %+v", settings)
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"empty batch", `[]`, "invalid_batch"},
+		{"too many steps", "[" + strings.Repeat(`{"op": "on"},`, maxBatchSteps) + `{"op": "on"}]`, "invalid_batch"},
+		{"not JSON", `on, off`, "invalid_json"},
+		{"misspelled step field", `[{"op": "brightness", "birghtness": 50}]`, "invalid_json"},
+		{"misspelled option", `{"operations": [{"op": "on"}], "continue_on_eror": true}`, "invalid_json"},
+		// An invalid step rejects the batch before the valid steps ahead of it are applied
+		{"invalid step", `[{"op": "on"}, {"op": "brightness", "brightness": 400}, {"op": "off"}]`, "invalid_batch"},
+		{"unknown op", `{"operations": [{"op": "blink"}], "continue_on_error": true}`, "invalid_batch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Batch(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+			assertErrorCode(t, w, tt.code)
+		})
+	}
+}
+
+func TestRGBInvalidJSON(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	req := httptest.NewRequest("POST", "/lights/rgb", bytes.NewReader([]byte("invalid json")))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.RGB(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "invalid_json")
+}
+
+func TestColorTemp(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	body := map[string]int{"temperature": 3000}
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/lights/colortemp", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ColorTemp(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response["status"] != "color temperature set" {
+		t.Errorf("expected status 'color temperature set', got %s", response["status"])
+	}
+}
+
+func TestColorTempInvalidValues(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name string
+		temp int
+	}{
+		{"too low", 1500},
+		{"too high", 10000},
+		{"negative", -1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]int{"temperature": tt.temp}
+			bodyBytes, _ := json.Marshal(body)
+			req := httptest.NewRequest("POST", "/lights/colortemp", bytes.NewReader(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ColorTemp(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+			assertErrorCode(t, w, "invalid_color_temperature")
+		})
+	}
+}
+
+func TestColorTempQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{"valid kelvin", "/lights/colortemp?kelvin=4000", http.StatusOK},
+		{"minimum", "/lights/colortemp?kelvin=2000", http.StatusOK},
+		{"too low", "/lights/colortemp?kelvin=1999", http.StatusBadRequest},
+		{"too high", "/lights/colortemp?kelvin=9001", http.StatusBadRequest},
+		{"not a number", "/lights/colortemp?kelvin=warm", http.StatusBadRequest},
+		{"missing", "/lights/colortemp", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.ColorTemp(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				assertErrorCode(t, w, "invalid_color_temperature")
+			}
+		})
+	}
+}
+
+func TestMutatingGetRejectsHead(t *testing.T) {
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{})),
+	}
+
+	tests := []struct {
+		url   string
+		serve http.HandlerFunc
+	}{
+		{"/lights/brightness?level=10", handler.Brightness},
+		{"/lights/colortemp?kelvin=3000", handler.ColorTemp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.serve(w, httptest.NewRequest("HEAD", tt.url, nil))
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("expected status 405, got %d", w.Code)
+			}
+			if allow := w.Header().Get("Allow"); !strings.Contains(allow, "GET") || strings.Contains(allow, "HEAD") {
+				t.Errorf("expected an Allow header without HEAD, got %q", allow)
+			}
+		})
+	}
+}
+
+func TestColorTempMireds(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid mireds", `{"mireds": 250}`, http.StatusOK},
+		{"warmest supported", `{"mireds": 500}`, http.StatusOK},
+		{"too warm", `{"mireds": 501}`, http.StatusBadRequest},
+		{"too cool", `{"mireds": 100}`, http.StatusBadRequest},
+		{"zero", `{"mireds": 0}`, http.StatusBadRequest},
+		{"both units", `{"mireds": 250, "temperature": 4000}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/colortemp", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ColorTemp(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				assertErrorCode(t, w, "invalid_color_temperature")
+			}
+		})
+	}
+}
+
+func TestColorTempInvalidJSON(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	req := httptest.NewRequest("POST", "/lights/colortemp", bytes.NewReader([]byte("invalid json")))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ColorTemp(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "invalid_json")
+}
+
+func TestBrightnessQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{"valid level", "/lights/brightness?level=75", http.StatusOK},
+		{"minimum", "/lights/brightness?level=0", http.StatusOK},
+		{"maximum", "/lights/brightness?level=100", http.StatusOK},
+		{"too low", "/lights/brightness?level=-1", http.StatusBadRequest},
+		{"too high", "/lights/brightness?level=101", http.StatusBadRequest},
+		{"not a number", "/lights/brightness?level=bright", http.StatusBadRequest},
+		{"missing", "/lights/brightness", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.Brightness(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				assertErrorCode(t, w, "invalid_brightness")
+			}
+		})
+	}
+}
+
+func TestBrightnessInvalidValues(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: mockController,
+		Logger:     logger,
+	}
+
+	tests := []struct {
+		name       string
+		brightness int
+	}{
+		{"negative", -1},
+		{"too high", 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]int{"brightness": tt.brightness}
+			bodyBytes, _ := json.Marshal(body)
+			req := httptest.NewRequest("POST", "/lights/brightness", bytes.NewReader(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Brightness(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+			assertErrorCode(t, w, "invalid_brightness")
+		})
+	}
+}
+
+// assertErrorCode checks that the response is a structured error with the given code
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, code string) {
+	t.Helper()
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if response.Error.Code != code {
+		t.Errorf("expected error code %s, got %s", code, response.Error.Code)
+	}
+	if response.Error.Message == "" {
+		t.Errorf("expected error message to be set")
+	}
+	if response.RequestID == "" {
+		t.Errorf("expected requestID to be set")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &HealthHandler{
+		Controller: mockController,
+		Logger:     logger,
+		StartTime:  time.Now().Add(-time.Hour), // 1 hour ago
+	}
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.Health(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response HealthStatus
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Status != "ok" { // Controller is initialized
+		t.Errorf("expected status 'ok', got %s", response.Status)
+	}
+
+	if response.Uptime == "" {
+		t.Errorf("expected uptime to be set")
+	}
+
+	if response.RequestID == "" {
+		t.Errorf("expected requestID to be set")
+	}
+
+	if len(response.Checks) == 0 {
+		t.Errorf("expected checks to be present")
+	}
+
+	// Check controller status
+	if controllerCheck, ok := response.Checks["controller"]; !ok {
+		t.Errorf("expected controller check to be present")
+	} else if controllerCheck.Status != "ok" {
+		t.Errorf("expected controller status 'ok', got %s", controllerCheck.Status)
+	}
+}
+
+func TestHealthWithDevices(t *testing.T) {
+	// Create a mock controller with devices
+	mockController := &MockControllerWithDevices{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &HealthHandler{
+		Controller: mockController,
+		Logger:     logger,
+		StartTime:  time.Now().Add(-time.Minute * 30), // 30 minutes ago
+	}
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.Health(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response HealthStatus
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
@@ -419,20 +1709,1112 @@ func TestHealthWithDevices(t *testing.T) {
 		t.Errorf("expected status 'ok', got %s", response.Status)
 	}
 
-	// Check controller status
-	if controllerCheck, ok := response.Checks["controller"]; !ok {
-		t.Errorf("expected controller check to be present")
-	} else if controllerCheck.Status != "ok" {
-		t.Errorf("expected controller status 'ok', got %s", controllerCheck.Status)
+	// Check controller status
+	if controllerCheck, ok := response.Checks["controller"]; !ok {
+		t.Errorf("expected controller check to be present")
+	} else if controllerCheck.Status != "ok" {
+		t.Errorf("expected controller status 'ok', got %s", controllerCheck.Status)
+	}
+}
+
+func TestHealthCircuitBreakers(t *testing.T) {
+	breakers := controller.NewBreakers(1, time.Hour)
+	handler := &HealthHandler{
+		Controller: &MockController{},
+		Logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{})),
+		StartTime:  time.Now(),
+		Breakers:   breakers,
+	}
+	health := func() (int, HealthStatus) {
+		w := httptest.NewRecorder()
+		handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+		var response HealthStatus
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	if _, response := health(); response.Checks["circuit_breakers"].Status != "ok" {
+		t.Errorf("expected a passing circuit_breakers check, got %+v", response.Checks["circuit_breakers"])
+	}
+
+	breakers.Record("AA:BB", errors.New("no route to host"))
+	code, response := health()
+	check := response.Checks["circuit_breakers"]
+	if code != http.StatusOK || response.Status != "warn" || check.Status != "warn" {
+		t.Errorf("expected an open breaker to warn, got %d %s: %+v", code, response.Status, check)
+	}
+	if !strings.Contains(check.Detail, "AA:BB (open)") {
+		t.Errorf("expected the open device in the detail, got %q", check.Detail)
+	}
+}
+
+// stateController reports a fixed controller state and no devices
+type stateController string
+
+func (c stateController) Devices() []*govee.Device {
+	return nil
+}
+
+func (c stateController) State() string {
+	return string(c)
+}
+
+func TestHealthControllerState(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+
+	tests := []struct {
+		state          string
+		expectedStatus int
+		expectedCheck  string
+	}{
+		{controller.StateReady, http.StatusOK, "ok"},
+		{controller.StateStarting, http.StatusOK, "warn"},
+		{controller.StateError, http.StatusServiceUnavailable, "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			handler := &HealthHandler{
+				Controller: stateController(tt.state),
+				Logger:     logger,
+				StartTime:  time.Now(),
+			}
+
+			req := httptest.NewRequest("GET", "/ready", nil)
+			w := httptest.NewRecorder()
+
+			handler.Health(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			var response HealthStatus
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if check := response.Checks["controller_state"]; check.Status != tt.expectedCheck {
+				t.Errorf("expected controller_state status %q, got %q", tt.expectedCheck, check.Status)
+			}
+		})
+	}
+}
+
+func TestReadyWarmup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	ready := func(h *HealthHandler) (int, HealthStatus) {
+		w := httptest.NewRecorder()
+		h.Ready(w, httptest.NewRequest("GET", "/ready", nil))
+		var response HealthStatus
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	devices := &staticController{}
+	handler := &HealthHandler{
+		Controller:    devices,
+		Logger:        logger,
+		StartTime:     time.Now(),
+		WarmupTimeout: time.Hour,
+	}
+
+	// Before discovery, /ready holds traffic back while /health stays up
+	code, response := ready(handler)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 while warming up, got %d", code)
+	}
+	if check := response.Checks["warmup"]; check.Status != "error" {
+		t.Errorf("expected a failing warmup check, got %+v", response.Checks)
+	}
+	w := httptest.NewRecorder()
+	handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected /health to ignore the warmup gate, got %d", w.Code)
+	}
+
+	// The first discovered device opens the gate, and it stays open if the device goes away
+	*devices = staticController{&govee.Device{}}
+	if code, response := ready(handler); code != http.StatusOK {
+		t.Errorf("expected status 200 once a device is discovered, got %d: %+v", code, response.Checks)
+	}
+	*devices = staticController{}
+	if code, _ := ready(handler); code != http.StatusOK {
+		t.Errorf("expected the gate to stay open, got %d", code)
+	}
+
+	// Without a device, the timeout opens it
+	expired := &HealthHandler{
+		Controller:    &staticController{},
+		Logger:        logger,
+		StartTime:     time.Now().Add(-2 * time.Hour),
+		WarmupTimeout: time.Hour,
+	}
+	if code, response := ready(expired); code != http.StatusOK {
+		t.Errorf("expected status 200 after the warmup timeout, got %d: %+v", code, response.Checks)
+	}
+
+	// And no timeout means no gate
+	ungated := &HealthHandler{Controller: &staticController{}, Logger: logger, StartTime: time.Now()}
+	if code, response := ready(ungated); code != http.StatusOK {
+		t.Errorf("expected status 200 without a warmup timeout, got %d: %+v", code, response.Checks)
+	}
+}
+
+// MockControllerWithDevices is a mock that returns devices
+type MockControllerWithDevices struct{}
+
+func TestHealthDeviceCheckCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	health := func(h *HealthHandler, serve http.HandlerFunc) (int, HealthStatus) {
+		w := httptest.NewRecorder()
+		serve(w, httptest.NewRequest("GET", "/health", nil))
+		var response HealthStatus
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	probes := 0
+	var probeErr error
+	handler := &HealthHandler{
+		Controller: &staticController{&govee.Device{}},
+		Logger:     logger,
+		StartTime:  time.Now(),
+		ProbeDevice: func(*govee.Device) error {
+			probes++
+			return probeErr
+		},
+		CacheTTL: time.Hour,
+	}
+
+	code, response := health(handler, handler.Health)
+	if code != http.StatusOK || response.Checks["devices"].Status != "ok" {
+		t.Errorf("expected a passing devices check, got %d: %+v", code, response.Checks)
+	}
+
+	// Within the TTL the devices aren't asked again, even once they stop answering
+	probeErr = errors.New("no route to host")
+	health(handler, handler.Health)
+	health(handler, handler.Ready)
+	if probes != 1 {
+		t.Errorf("expected 1 probe within the TTL, got %d", probes)
+	}
+
+	// Liveness never probes the devices
+	code, response = health(handler, handler.Live)
+	if _, ok := response.Checks["devices"]; ok || code != http.StatusOK {
+		t.Errorf("expected /live to skip the devices check, got %d: %+v", code, response.Checks)
+	}
+	if probes != 1 {
+		t.Errorf("expected /live not to probe, got %d probes", probes)
+	}
+
+	// Once the TTL is up the devices are asked again
+	handler.CacheTTL = 0
+	code, response = health(handler, handler.Health)
+	if probes != 2 {
+		t.Errorf("expected a new probe after the TTL, got %d", probes)
+	}
+	if code != http.StatusServiceUnavailable || response.Checks["devices"].Status != "error" {
+		t.Errorf("expected a failing devices check, got %d: %+v", code, response.Checks)
+	}
+
+	// Without devices there is nothing to check
+	empty := &HealthHandler{Controller: &staticController{}, Logger: logger, StartTime: time.Now(), ProbeDevice: handler.ProbeDevice}
+	if _, response := health(empty, empty.Health); response.Checks["devices"].Status != "" {
+		t.Errorf("expected no devices check without devices, got %+v", response.Checks)
+	}
+}
+
+func TestHealthDeviceCheckTimeout(t *testing.T) {
+	hanging, answering := &govee.Device{}, &govee.Device{}
+	release := make(chan struct{})
+	defer close(release)
+	handler := &HealthHandler{
+		Controller: &staticController{hanging, answering},
+		Logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{})),
+		StartTime:  time.Now(),
+		ProbeDevice: func(device *govee.Device) error {
+			if device == hanging {
+				<-release
+			}
+			return nil
+		},
+		ProbeTimeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the check to give up after the probe timeout, took %s", elapsed)
+	}
+
+	var response HealthStatus
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// The device that answered in time is reachable; the one that didn't is not
+	if check := response.Checks["devices"]; check.Status != "warn" || !strings.HasPrefix(check.Detail, "1 of 2 unreachable") {
+		t.Errorf("expected one unreachable device, got %+v", check)
+	}
+}
+func (m *MockControllerWithDevices) Devices() []*govee.Device {
+	// Return a mock device (we can't easily create a real one in tests)
+	return []*govee.Device{} // Empty slice represents "some devices"
+}
+
+func (m *MockControllerWithDevices) State() string {
+	return controller.StateReady
+}
+
+// Similar tests for Yellow and Orange can be added
+
+func TestStream(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller:     mockController,
+		Logger:         logger,
+		StreamInterval: 10 * time.Millisecond,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler.Stream))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial stream: %v", err)
+	}
+	defer conn.Close()
+
+	// Expect at least two snapshots to confirm the periodic push
+	for i := 0; i < 2; i++ {
+		var response []map[string]interface{}
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("failed to read snapshot %d: %v", i, err)
+		}
+		if len(response) != 0 {
+			t.Errorf("expected empty snapshot, got %v", response)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	mockController := &MockController{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller:     mockController,
+		Logger:         logger,
+		StreamInterval: 10 * time.Millisecond,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler.Events))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %s", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	if line != "event: status\n" {
+		t.Errorf("expected status event, got %q", line)
+	}
+	line, err = reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read event data: %v", err)
+	}
+	if !strings.HasPrefix(line, "data: ") {
+		t.Errorf("expected data line, got %q", line)
+	}
+}
+
+func TestEventsOutlivesWriteTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller:     &MockController{},
+		Logger:         logger,
+		StreamInterval: 10 * time.Millisecond,
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler.Events))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	start := time.Now()
+	for time.Since(start) < 200*time.Millisecond {
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatalf("stream ended after %s, before the write timeout was lifted: %v", time.Since(start), err)
+		}
+	}
+}
+
+func TestWriteInvalidatesStatusCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	device := &govee.Device{}
+	cache := controller.NewStatusCache(time.Minute)
+	handler := &LightsHandler{Controller: staticController{device}, Logger: logger, StatusCache: cache}
+	noop := func(*govee.Device) error { return nil }
+
+	// A dry run sends nothing, so the cached status still holds
+	cache.Mark(device.DeviceID())
+	req := httptest.NewRequest("POST", "/lights/on?dry_run=true", nil)
+	w := httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "ok", noop, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !cache.Fresh(device.DeviceID()) {
+		t.Error("expected a dry run to leave the cached status alone")
+	}
+
+	// A real write makes the next cached read go back to the device
+	req = httptest.NewRequest("POST", "/lights/on", nil)
+	w = httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "ok", noop, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if cache.Fresh(device.DeviceID()) {
+		t.Error("expected the write to invalidate the cached status")
+	}
+
+	// So does a failed one, since the device may have applied part of it
+	cache.Mark(device.DeviceID())
+	fail := func(*govee.Device) error { return errors.New("channel blocked or closed") }
+	w = httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "ok", fail, nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+	if cache.Fresh(device.DeviceID()) {
+		t.Error("expected the failed write to invalidate the cached status")
+	}
+}
+
+func TestOffAllExcept(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Disabled:   controller.NewDisabledDevices([]string{"AA:BB"}),
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"missing device", "/lights/off-all-except", http.StatusBadRequest, "invalid_device"},
+		{"unknown device", "/lights/off-all-except?device=CC:DD", http.StatusNotFound, "unknown_device"},
+		{"disabled device", "/lights/off-all-except?device=AA:BB", http.StatusConflict, "device_disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.OffAllExcept(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			assertErrorCode(t, w, tt.expectedCode)
+		})
+	}
+}
+
+func TestExceptDevice(t *testing.T) {
+	kept, other := &govee.Device{}, &govee.Device{}
+	others := exceptDevice([]*govee.Device{other, kept}, kept)
+	if len(others) != 1 || others[0] != other {
+		t.Errorf("expected only the other device, got %v", others)
+	}
+	if others := exceptDevice([]*govee.Device{kept}, kept); len(others) != 0 {
+		t.Errorf("expected no devices, got %v", others)
+	}
+}
+
+func TestRunOperationRetries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{Controller: &MockController{}, Logger: logger, DeviceOpRetries: 2}
+	device := &govee.Device{}
+
+	calls := 0
+	failOnce := func(*govee.Device) error {
+		calls++
+		if calls == 1 {
+			return errors.New("channel blocked or closed")
+		}
+		return nil
+	}
+	if failed := failedDevices(handler.runOperation("test", "on", []*govee.Device{device}, failOnce, nil, false)); len(failed) != 0 {
+		t.Errorf("expected no failed devices after a retry, got %v", failed)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+
+	calls = 0
+	alwaysFail := func(*govee.Device) error {
+		calls++
+		return errors.New("channel blocked or closed")
+	}
+	if failed := failedDevices(handler.runOperation("test", "on", []*govee.Device{device}, alwaysFail, nil, false)); len(failed) != 1 {
+		t.Errorf("expected the device to fail once retries are exhausted, got %v", failed)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+// staticController returns a fixed set of devices
+type staticController []*govee.Device
+
+func (c staticController) Devices() []*govee.Device {
+	return c
+}
+
+func (c staticController) State() string {
+	return controller.StateReady
+}
+
+func TestExecuteLightOperationPartialFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	devices := staticController{&govee.Device{}, &govee.Device{}}
+	handler := &LightsHandler{
+		Controller: devices,
+		Logger:     logger,
+	}
+
+	// Devices are sent their commands concurrently, so fail by device rather than call order
+	failFirst := func(device *govee.Device) error {
+		if device == devices[0] {
+			return errors.New("channel blocked or closed")
+		}
+		return nil
+	}
+	req := httptest.NewRequest("POST", "/lights/on", nil)
+	w := httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "lights turned on", failFirst, nil)
+
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d", w.Code)
+	}
+	var response MultiStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Devices) != 2 {
+		t.Fatalf("expected 2 device results, got %d", len(response.Devices))
+	}
+	if response.Devices[0].Result != "error" || response.Devices[0].Error == "" {
+		t.Errorf("expected first device to report an error, got %+v", response.Devices[0])
+	}
+	if response.Devices[1].Result != "ok" {
+		t.Errorf("expected second device to succeed, got %+v", response.Devices[1])
+	}
+
+	alwaysFail := func(*govee.Device) error {
+		return errors.New("channel blocked or closed")
+	}
+	w = httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "lights turned on", alwaysFail, nil)
+	assertErrorCode(t, w, errCodeOperationFailed)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 when every device fails, got %d", w.Code)
 	}
 }
 
-// MockControllerWithDevices is a mock that returns devices
-type MockControllerWithDevices struct{}
+func TestExecuteLightOperationPerDevice(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	first, second := &govee.Device{}, &govee.Device{}
+	handler := &LightsHandler{
+		Controller: staticController{first, second},
+		Logger:     logger,
+	}
 
-func (m *MockControllerWithDevices) Devices() []*govee.Device {
-	// Return a mock device (we can't easily create a real one in tests)
-	return []*govee.Device{} // Empty slice represents "some devices"
+	applied := map[*govee.Device]string{}
+	perDevice := func(device *govee.Device) (controller.Operation, string) {
+		detail := "blue"
+		if device == first {
+			detail = "red"
+		}
+		return func(d *govee.Device) error {
+			if detail == "blue" {
+				return errors.New("channel blocked or closed")
+			}
+			applied[d] = detail
+			return nil
+		}, detail
+	}
+
+	req := httptest.NewRequest("POST", "/lights/random", nil)
+	w := httptest.NewRecorder()
+	handler.executeLightOperationPerDevice(w, req, "random", "lights randomized", perDevice, nil)
+
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d", w.Code)
+	}
+	if applied[first] != "red" || len(applied) != 1 {
+		t.Errorf("expected only the first device to get its own operation, got %v", applied)
+	}
+	var response MultiStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Devices[0].Detail != "red" || response.Devices[1].Detail != "blue" {
+		t.Errorf("expected per-device details, got %+v", response.Devices)
+	}
 }
 
-// Similar tests for Yellow and Orange can be added
+func TestDryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: staticController{&govee.Device{}},
+		Logger:     logger,
+	}
+
+	calls := 0
+	operation := func(*govee.Device) error {
+		calls++
+		return errors.New("should not be called")
+	}
+
+	req := httptest.NewRequest("POST", "/lights/on?dry_run=true", nil)
+	w := httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "lights turned on", operation, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for a dry run, got %d", w.Code)
+	}
+
+	handler.DryRun = true
+	req = httptest.NewRequest("POST", "/lights/on", nil)
+	w = httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "on", "lights turned on", operation, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 with DryRun set, got %d", w.Code)
+	}
+
+	if calls != 0 {
+		t.Errorf("expected no device calls in a dry run, got %d", calls)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+	}
+
+	req := httptest.NewRequest("POST", "/lights/identify", nil)
+	w := httptest.NewRecorder()
+	handler.Identify(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a device, got %d", w.Code)
+	}
+	assertErrorCode(t, w, errCodeInvalidDevice)
+
+	req = httptest.NewRequest("POST", "/lights/identify?device=AA:BB", nil)
+	w = httptest.NewRecorder()
+	handler.Identify(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown device, got %d", w.Code)
+	}
+	assertErrorCode(t, w, errCodeUnknownDevice)
+}
+
+func TestLast(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: staticController{&govee.Device{}},
+		Logger:     logger,
+		History:    history.NewTracker(),
+	}
+
+	succeed := func(*govee.Device) error { return nil }
+	req := httptest.NewRequest("POST", "/lights/brightness", nil)
+	w := httptest.NewRecorder()
+	handler.executeLightOperation(w, req, "set_brightness", "brightness set", succeed, history.Brightness(40))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/lights/last", nil)
+	w = httptest.NewRecorder()
+	handler.Last(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]history.Entry
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	entry, ok := response[""]
+	if !ok {
+		t.Fatalf("expected an entry for the device, got %v", response)
+	}
+	if entry.Operation != "set_brightness" || entry.Brightness == nil || *entry.Brightness != 40 {
+		t.Errorf("expected set_brightness to 40, got %+v", entry)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Snapshots:  snapshots.New(),
+	}
+
+	// Name is required
+	req := httptest.NewRequest("POST", "/lights/snapshot", nil)
+	w := httptest.NewRecorder()
+	handler.CreateSnapshot(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "invalid_snapshot")
+
+	// Create
+	req = httptest.NewRequest("POST", "/lights/snapshot?name=evening", nil)
+	w = httptest.NewRecorder()
+	handler.CreateSnapshot(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+
+	// List
+	req = httptest.NewRequest("GET", "/lights/snapshots", nil)
+	w = httptest.NewRecorder()
+	handler.ListSnapshots(w, req)
+	var list []snapshots.Snapshot
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "evening" {
+		t.Errorf("expected the evening snapshot, got %+v", list)
+	}
+
+	// Restore
+	req = httptest.NewRequest("POST", "/lights/restore?name=evening", nil)
+	w = httptest.NewRecorder()
+	handler.RestoreSnapshot(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	// Delete
+	req = httptest.NewRequest("DELETE", "/lights/snapshots/evening", nil)
+	req.SetPathValue("name", "evening")
+	w = httptest.NewRecorder()
+	handler.DeleteSnapshot(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+
+	// Deleted snapshot can't be restored
+	req = httptest.NewRequest("POST", "/lights/restore?name=evening", nil)
+	w = httptest.NewRecorder()
+	handler.RestoreSnapshot(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "unknown_snapshot")
+}
+
+func TestColorLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings *history.Settings
+		want     string
+	}{
+		{"no settings", nil, ""},
+		{"power", history.Power(true), ""},
+		{"named color", history.NamedColor("orange", govee.Color{R: 255, G: 165}), "orange"},
+		{"rgb", history.Color(govee.Color{R: 255}), "rgb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := colorLabel(tt.settings); got != tt.want {
+				t.Errorf("colorLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{Controller: &MockController{}, Logger: logger}
+	scheduler, err := schedules.New("", handler.RunSchedule, logger)
+	if err != nil {
+		t.Fatalf("schedules.New() error = %v", err)
+	}
+	handler.Schedules = scheduler
+
+	invalid := []string{
+		`{"cron": "every morning", "action": {"op": "on"}}`,
+		`{"cron": "0 8 * * *", "action": {"op": "disco"}}`,
+		`{"cron": "0 8 * * *", "action": {"op": "warm", "brightness": 150}}`,
+	}
+	for _, body := range invalid {
+		req := httptest.NewRequest("POST", "/schedules", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.CreateSchedule(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+		assertErrorCode(t, w, "invalid_schedule")
+	}
+
+	req := httptest.NewRequest("POST", "/schedules", strings.NewReader(`{"cron": "0 8 * * *", "action": {"op": "warm", "brightness": 60}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.CreateSchedule(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var created schedules.Schedule
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	steps, err := handler.scheduledOperations(created.Action)
+	if err != nil || len(steps) != 2 || steps[1].name != "set_brightness" {
+		t.Errorf("expected warm then brightness, got %+v, %v", steps, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ListSchedules(w, httptest.NewRequest("GET", "/schedules", nil))
+	var list []schedules.Schedule
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Errorf("expected the created schedule, got %+v", list)
+	}
+
+	req = httptest.NewRequest("DELETE", "/schedules/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	handler.DeleteSchedule(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.DeleteSchedule(w, req)
+	assertErrorCode(t, w, "unknown_schedule")
+}
+
+func TestSunMode(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{Controller: &MockController{}, Logger: logger}
+
+	w := httptest.NewRecorder()
+	handler.SunMode(w, httptest.NewRequest("GET", "/sun", nil))
+	assertErrorCode(t, w, "invalid_sun_mode")
+
+	handler.Sun = solar.NewAuto(40.7128, -74.0060, handler.RunSunEvent, logger)
+	defer handler.Sun.Disable()
+
+	req := httptest.NewRequest("PUT", "/sun", strings.NewReader(`{"enabled": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.SetSunMode(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var status solar.Status
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !status.Enabled || status.Next == nil {
+		t.Errorf("expected mode enabled with a next event, got %+v", status)
+	}
+
+	if err := handler.RunSunEvent(solar.Sunset); err != nil {
+		t.Errorf("RunSunEvent(sunset) error = %v", err)
+	}
+	if err := handler.RunSunEvent("noon"); err == nil {
+		t.Error("expected an error for an unknown event")
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	maintenance := &middleware.Maintenance{}
+	handler := &AdminHandler{
+		Maintenance: maintenance,
+		Logger:      logger,
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedOn     bool
+	}{
+		{"enable", "/admin/maintenance?enabled=true", http.StatusOK, true},
+		{"enable again", "/admin/maintenance?enabled=true", http.StatusOK, true},
+		{"disable", "/admin/maintenance?enabled=false", http.StatusOK, false},
+		{"missing", "/admin/maintenance", http.StatusBadRequest, false},
+		{"invalid", "/admin/maintenance?enabled=soon", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.SetMaintenance(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				assertErrorCode(t, w, "invalid_maintenance")
+			}
+			if maintenance.Enabled() != tt.expectedOn {
+				t.Errorf("expected maintenance %v, got %v", tt.expectedOn, maintenance.Enabled())
+			}
+		})
+	}
+}
+
+func TestDisableDevice(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	disabled := controller.NewDisabledDevices(nil)
+	handler := &AdminHandler{
+		Disabled: disabled,
+		Logger:   logger,
+	}
+
+	req := httptest.NewRequest("PUT", "/admin/devices/AA:BB/disabled", nil)
+	req.SetPathValue("id", "AA:BB")
+	w := httptest.NewRecorder()
+	handler.DisableDevice(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !disabled.Disabled("aa:bb") {
+		t.Error("expected the device to be disabled")
+	}
+
+	w = httptest.NewRecorder()
+	handler.ListDisabled(w, httptest.NewRequest("GET", "/admin/devices/disabled", nil))
+	var list struct {
+		Disabled []string `json:"disabled"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !slices.Equal(list.Disabled, []string{"AA:BB"}) {
+		t.Errorf("expected [AA:BB], got %v", list.Disabled)
+	}
+
+	req = httptest.NewRequest("DELETE", "/admin/devices/aa:bb/disabled", nil)
+	req.SetPathValue("id", "aa:bb")
+	w = httptest.NewRecorder()
+	handler.EnableDevice(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if disabled.Disabled("AA:BB") {
+		t.Error("expected the device to be enabled again")
+	}
+}
+
+// fakeReloader returns a canned reload outcome
+type fakeReloader struct {
+	reloaded, restartRequired []string
+	err                       error
+}
+
+func (f fakeReloader) Reload() ([]string, []string, error) {
+	return f.reloaded, f.restartRequired, f.err
+}
+
+func TestReload(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+
+	handler := &AdminHandler{Reloader: fakeReloader{restartRequired: []string{"port"}}, Logger: logger}
+	w := httptest.NewRecorder()
+	handler.Reload(w, httptest.NewRequest("POST", "/admin/reload", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var response ReloadResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Reloaded == nil || len(response.Reloaded) != 0 {
+		t.Errorf("expected an empty reloaded list, got %v", response.Reloaded)
+	}
+	if !slices.Equal(response.RestartRequired, []string{"port"}) {
+		t.Errorf("expected port to need a restart, got %v", response.RestartRequired)
+	}
+
+	handler.Reloader = fakeReloader{err: errors.New("BEARER_TOKEN is required")}
+	w = httptest.NewRecorder()
+	handler.Reload(w, httptest.NewRequest("POST", "/admin/reload", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "reload_failed")
+}
+
+func TestLiveSettings(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	handler := &LightsHandler{
+		Controller: &MockController{},
+		Logger:     logger,
+		Live:       NewLiveSettings(Settings{DeviceOpDelay: time.Millisecond}),
+	}
+
+	namedColor := func() int {
+		req := httptest.NewRequest("POST", "/lights/color/teal", nil)
+		req.SetPathValue("name", "teal")
+		w := httptest.NewRecorder()
+		handler.NamedColor(w, req)
+		return w.Code
+	}
+	if code := namedColor(); code != http.StatusNotFound {
+		t.Fatalf("expected teal to be unknown before the reload, got %d", code)
+	}
+
+	table, err := colors.Load("teal=0,128,128", "")
+	if err != nil {
+		t.Fatalf("failed to load colors: %v", err)
+	}
+	handler.Live.Store(Settings{Colors: table})
+	if code := namedColor(); code != http.StatusOK {
+		t.Errorf("expected the reloaded preset to apply, got %d", code)
+	}
+}
+
+func TestDisabledDevicesSkipped(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	// Devices built outside the library all have an empty ID
+	handler := &LightsHandler{
+		Controller: staticController{&govee.Device{}},
+		Logger:     logger,
+		Disabled:   controller.NewDisabledDevices([]string{""}),
+	}
+	req := httptest.NewRequest("POST", "/lights/off", nil)
+	devices, ok := handler.targetDevices(httptest.NewRecorder(), req)
+	if !ok || len(devices) != 0 {
+		t.Fatalf("expected the disabled device to be skipped, got %d devices", len(devices))
+	}
+	// Status leaves the device out without asking it for its state
+	if statuses := handler.collectStatuses("test", true); len(statuses) != 0 {
+		t.Errorf("expected no statuses, got %v", statuses)
+	}
+
+	disabled := controller.NewDisabledDevices([]string{"AA:BB"})
+	handler = &LightsHandler{Controller: &MockController{}, Logger: logger, Disabled: disabled}
+
+	// Naming a disabled device explains why it can't be used
+	w := httptest.NewRecorder()
+	handler.TurnOff(w, httptest.NewRequest("POST", "/lights/off?device=aa:bb", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Code)
+	}
+	assertErrorCode(t, w, "device_disabled")
+
+	w = httptest.NewRecorder()
+	handler.Status(w, httptest.NewRequest("GET", "/lights/status", nil))
+	if got := w.Header().Get("Disabled-Devices"); got != "AA:BB" {
+		t.Errorf("expected the status response to name the disabled device, got %q", got)
+	}
+
+	health := &HealthHandler{Controller: &MockController{}, Logger: logger, Disabled: disabled}
+	w = httptest.NewRecorder()
+	health.Health(w, httptest.NewRequest("GET", "/health", nil))
+	var status HealthStatus
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if check := status.Checks["disabled_devices"]; check.Status != "ok" || !strings.Contains(check.Detail, "AA:BB") {
+		t.Errorf("expected an ok disabled_devices check naming the device, got %+v", status.Checks)
+	}
+}
+
+func TestDeviceAliases(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	aliases, err := controller.ParseAliases("AA:BB=desk-lamp,CC:DD=shelf")
+	if err != nil {
+		t.Fatalf("failed to parse aliases: %v", err)
+	}
+	disabled := controller.NewDisabledDevices(nil)
+	admin := &AdminHandler{Disabled: disabled, Aliases: aliases, Logger: logger}
+
+	// Disabling by alias records the device ID
+	req := httptest.NewRequest("PUT", "/admin/devices/desk-lamp/disabled", nil)
+	req.SetPathValue("id", "desk-lamp")
+	admin.DisableDevice(httptest.NewRecorder(), req)
+	if !disabled.Disabled("AA:BB") {
+		t.Fatal("expected the aliased device to be disabled by ID")
+	}
+
+	handler := &LightsHandler{Controller: &MockController{}, Logger: logger, Disabled: disabled, Aliases: aliases}
+	tests := []struct {
+		name           string
+		device         string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"alias of a disabled device", "desk-lamp", http.StatusConflict, "device_disabled"},
+		{"alias ignoring case", "Desk-Lamp", http.StatusConflict, "device_disabled"},
+		{"alias of an undiscovered device", "shelf", http.StatusNotFound, "unknown_device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.TurnOn(w, httptest.NewRequest("POST", "/lights/on?device="+tt.device, nil))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			body := w.Body.String()
+			assertErrorCode(t, w, tt.expectedCode)
+			// Errors name the device the way the client did
+			if !strings.Contains(body, tt.device) {
+				t.Errorf("expected the error to mention %q, got %s", tt.device, body)
+			}
+		})
+	}
+}
@@ -19,20 +19,56 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	govee "github.com/swrm-io/go-vee"
 )
 
 type HealthHandler struct {
 	Controller ControllerInterface
 	Logger     *slog.Logger
 	StartTime  time.Time
+	// Disabled devices are listed in a check so their absence from /lights/status is explained
+	Disabled *controller.DisabledDevices
+	// WarmupTimeout is how long /ready waits for a device to be discovered before
+	// reporting ready anyway; 0 disables the wait
+	WarmupTimeout time.Duration
+	// ProbeDevice asks a device for its status to check it is reachable, e.g.
+	// (*govee.Device).RequestStatus; nil skips the "devices" check
+	ProbeDevice func(*govee.Device) error
+	// Queue sends the probes, so they take their turn with light commands instead of
+	// colliding with them; nil probes directly
+	Queue *controller.DeviceQueue
+	// ProbeTimeout bounds the whole "devices" check; devices that haven't answered by
+	// then count as unreachable. 0 uses DefaultProbeTimeout.
+	ProbeTimeout time.Duration
+	// CacheTTL is how long the "devices" check result is reused, so frequent probes
+	// don't flood the devices; 0 probes them on every request
+	CacheTTL time.Duration
+	// Breakers lists devices whose circuit breaker has tripped in a check; nil skips it
+	Breakers *controller.Breakers
+
+	warmedUp atomic.Bool
+
+	// devicesMu also serializes probes, so concurrent health checks share one
+	devicesMu      sync.Mutex
+	devicesCheck   Check
+	devicesChecked time.Time
 }
 
+// DefaultProbeTimeout is how long the "devices" check waits for every probe to answer
+const DefaultProbeTimeout = 3 * time.Second
+
 type HealthStatus struct {
 	Status    string           `json:"status"`
 	Timestamp time.Time        `json:"timestamp"`
 	Uptime    string           `json:"uptime"`
 	Checks    map[string]Check `json:"checks"`
+	RequestID string           `json:"requestID"`
 }
 
 type Check struct {
@@ -41,9 +77,47 @@ type Check struct {
 }
 
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
-	requestID := getRequestID(r.Context())
-	h.Logger.Info("Health check requested", "requestID", requestID)
+	h.Logger.Info("Health check requested", "requestID", getRequestID(r.Context()))
+	h.writeHealth(w, r, h.checks(true))
+}
+
+// Live is Health without the device reachability check: it only reports whether the
+// process is up, so an unreachable light never gets the server restarted
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	h.Logger.Info("Liveness check requested", "requestID", getRequestID(r.Context()))
+	h.writeHealth(w, r, h.checks(false))
+}
+
+// Ready is Health with a warmup gate: while the server is younger than WarmupTimeout and
+// no device has been discovered, a failing "warmup" check keeps it at 503 so load
+// balancers hold traffic back. Once either condition is met the gate stays open.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	h.Logger.Info("Readiness check requested", "requestID", getRequestID(r.Context()))
+	checks := h.checks(true)
+	if h.warmingUp() {
+		remaining := h.WarmupTimeout - time.Since(h.StartTime)
+		checks["warmup"] = Check{
+			Status: "error",
+			Detail: fmt.Sprintf("waiting for device discovery, at most %s more", remaining.Round(time.Second)),
+		}
+	}
+	h.writeHealth(w, r, checks)
+}
+
+// warmingUp reports whether the warmup gate is still closed
+func (h *HealthHandler) warmingUp() bool {
+	if h.warmedUp.Load() {
+		return false
+	}
+	if time.Since(h.StartTime) >= h.WarmupTimeout || (h.Controller != nil && len(h.Controller.Devices()) > 0) {
+		h.warmedUp.Store(true)
+		return false
+	}
+	return true
+}
 
+// checks runs every health check, including the device reachability check if probe is set
+func (h *HealthHandler) checks(probe bool) map[string]Check {
 	checks := make(map[string]Check)
 
 	// Check controller and device connectivity
@@ -60,6 +134,12 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 				Detail: "Controller initialized, no devices currently connected",
 			}
 		}
+		checks["controller_state"] = controllerStateCheck(h.Controller.State())
+		if probe && h.ProbeDevice != nil {
+			if check, ok := h.devicesReachable(); ok {
+				checks["devices"] = check
+			}
+		}
 	} else {
 		checks["controller"] = Check{
 			Status: "error",
@@ -67,6 +147,103 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if h.Breakers != nil {
+		checks["circuit_breakers"] = h.breakersCheck()
+	}
+
+	if disabled := h.Disabled.List(); len(disabled) > 0 {
+		checks["disabled_devices"] = Check{
+			Status: "ok",
+			Detail: fmt.Sprintf("%d disabled: %s", len(disabled), strings.Join(disabled, ", ")),
+		}
+	}
+
+	return checks
+}
+
+// devicesReachable probes every enabled device, reusing the last result for CacheTTL.
+// It reports false when there are no devices to probe.
+func (h *HealthHandler) devicesReachable() (Check, bool) {
+	h.devicesMu.Lock()
+	defer h.devicesMu.Unlock()
+	if !h.devicesChecked.IsZero() && time.Since(h.devicesChecked) < h.CacheTTL {
+		return h.devicesCheck, h.devicesCheck.Status != ""
+	}
+
+	devices := h.Disabled.Filter(h.Controller.Devices())
+	unreachable := h.probeDevices(devices)
+
+	var check Check
+	switch {
+	case len(devices) == 0:
+		// Nothing to report; the controller check already says no devices are connected
+	case len(unreachable) == 0:
+		check = Check{Status: "ok", Detail: fmt.Sprintf("%d devices reachable", len(devices))}
+	case len(unreachable) < len(devices):
+		check = Check{
+			Status: "warn",
+			Detail: fmt.Sprintf("%d of %d unreachable: %s", len(unreachable), len(devices), strings.Join(unreachable, ", ")),
+		}
+	default:
+		check = Check{Status: "error", Detail: fmt.Sprintf("all %d devices unreachable", len(devices))}
+	}
+	h.devicesCheck, h.devicesChecked = check, time.Now()
+	return check, check.Status != ""
+}
+
+// probeDevices probes devices in parallel through the queue and returns the labels of
+// those that failed or didn't answer within ProbeTimeout, in the order of devices
+func (h *HealthHandler) probeDevices(devices []*govee.Device) []string {
+	timeout := h.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+
+	type result struct {
+		index int
+		err   error
+	}
+	// Buffered so probes still queued at the deadline can finish without a reader
+	results := make(chan result, len(devices))
+	for i, device := range devices {
+		go func() {
+			results <- result{i, h.Queue.Do(device, h.ProbeDevice)}
+		}()
+	}
+
+	failed := make([]error, len(devices))
+	answered := make([]bool, len(devices))
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+wait:
+	for range devices {
+		select {
+		case res := <-results:
+			failed[res.index], answered[res.index] = res.err, true
+		case <-deadline.C:
+			break wait
+		}
+	}
+
+	var unreachable []string
+	for i, device := range devices {
+		err := failed[i]
+		if !answered[i] {
+			err = fmt.Errorf("no answer within %s", timeout)
+		}
+		if err != nil {
+			h.Logger.Warn("Device unreachable", "device", controller.DeviceLabel(device), "error", err)
+			unreachable = append(unreachable, controller.DeviceLabel(device))
+		}
+	}
+	return unreachable
+}
+
+// writeHealth responds with the overall status of checks: 200 when they are ok or only
+// warn, 503 when any failed
+func (h *HealthHandler) writeHealth(w http.ResponseWriter, r *http.Request, checks map[string]Check) {
+	requestID := getRequestID(r.Context())
+
 	// Overall status determination
 	status := "ok"
 	for _, check := range checks {
@@ -84,6 +261,7 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		Timestamp: time.Now(),
 		Uptime:    time.Since(h.StartTime).String(),
 		Checks:    checks,
+		RequestID: requestID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -102,6 +280,42 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Error("Failed to encode health response",
 			"requestID", requestID,
 			"error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeJSONError(w, r, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+	}
+}
+
+// controllerStateCheck maps the controller's state to a check; only a controller that gave
+// up starting fails the health check
+func controllerStateCheck(state string) Check {
+	switch state {
+	case controller.StateReady:
+		return Check{Status: "ok", Detail: state}
+	case controller.StateStarting:
+		return Check{Status: "warn", Detail: "starting, no devices discovered yet"}
+	default:
+		return Check{Status: "error", Detail: state}
+	}
+}
+
+// breakersCheck warns while any device's circuit breaker is open or half-open
+func (h *HealthHandler) breakersCheck() Check {
+	tripped := h.Breakers.Tripped()
+	if len(tripped) == 0 {
+		return Check{Status: "ok", Detail: "all closed"}
+	}
+	labels := make(map[string]string)
+	if h.Controller != nil {
+		for _, device := range h.Controller.Devices() {
+			labels[device.DeviceID()] = controller.DeviceLabel(device)
+		}
+	}
+	open := make([]string, 0, len(tripped))
+	for _, deviceID := range tripped {
+		label, ok := labels[deviceID]
+		if !ok {
+			label = deviceID
+		}
+		open = append(open, fmt.Sprintf("%s (%s)", label, h.Breakers.State(deviceID)))
 	}
+	return Check{Status: "warn", Detail: fmt.Sprintf("%d open: %s", len(open), strings.Join(open, ", "))}
 }
@@ -17,71 +17,441 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/jwhitcraft/lights-http/colors"
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/effects"
+	"github.com/jwhitcraft/lights-http/groups"
+	"github.com/jwhitcraft/lights-http/history"
 	"github.com/jwhitcraft/lights-http/metrics"
+	"github.com/jwhitcraft/lights-http/notifications"
+	"github.com/jwhitcraft/lights-http/schedules"
+	"github.com/jwhitcraft/lights-http/snapshots"
+	"github.com/jwhitcraft/lights-http/solar"
 	govee "github.com/swrm-io/go-vee"
 )
 
 // ControllerInterface defines the methods needed for controlling lights
 type ControllerInterface interface {
 	Devices() []*govee.Device
+	// State is one of controller.StateStarting, StateReady or StateError
+	State() string
 }
 
 type LightsHandler struct {
-	Controller ControllerInterface
-	Logger     *slog.Logger
+	Controller     ControllerInterface
+	Logger         *slog.Logger
+	StreamInterval time.Duration
+	Notifier       *notifications.Notifier
+	Colors         *colors.Table
+	Groups         *groups.Registry
+	Effects        *effects.Manager
+	History        *history.Tracker
+	Snapshots      *snapshots.Store
+	Schedules      *schedules.Scheduler
+	// Sun switches the lights at sunrise and sunset; nil when no location is configured
+	Sun *solar.Auto
+	// TransitionSteps is how many intermediate colors a fade sends; 0 uses the default
+	TransitionSteps int
+	// PowerOnFadeMs is how long /lights/on ramps brightness up when the request doesn't say
+	PowerOnFadeMs int
+	// Faded remembers the brightness lights had before /lights/off faded them out, so
+	// turning them back on restores it; nil forgets it
+	Faded *controller.FadedBrightness
+	// Queue sends the devices one command at a time, as the govee library needs, keeping
+	// each device's commands in order; nil sends them directly
+	Queue *controller.DeviceQueue
+	// Breakers are the devices' circuit breakers, shown in status output; nil when disabled
+	Breakers *controller.Breakers
+	// DeviceOpRetries is how many times a failed device command is retried
+	DeviceOpRetries int
+	// DryRun skips every device command while still reporting success; requests can
+	// also opt in with ?dry_run=true
+	DryRun bool
+	// RequireDevices rejects light operations with a 503 while no devices are known,
+	// instead of reporting success for an operation that reached nothing
+	RequireDevices bool
+	// StatusCache lets status reads reuse a recent refresh; nil always asks the devices
+	StatusCache *controller.StatusCache
+	// Disabled devices are skipped by every operation and status read; nil disables none
+	Disabled *controller.DisabledDevices
+	// Aliases lets friendly names stand in for device IDs in ?device= and group members,
+	// and are shown in status output; nil has none
+	Aliases *controller.Aliases
+	// Live, when it holds settings, takes precedence over Colors so the presets can be
+	// reloaded without a restart
+	Live *LiveSettings
 }
 
-// parseAndValidateJSON parses JSON from request body and validates it
+// DeviceResult is the outcome of an operation on a single device
+type DeviceResult struct {
+	Device string `json:"device"`
+	Label  string `json:"-"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// deviceOperation picks the operation to apply to one device, along with a short
+// description of what it does to that device; the description may be empty
+type deviceOperation func(device *govee.Device) (controller.Operation, string)
+
+// uniformOperation applies the same operation to every device
+func uniformOperation(operationFunc controller.Operation) deviceOperation {
+	return func(*govee.Device) (controller.Operation, string) {
+		return operationFunc, ""
+	}
+}
+
+// Per-device results
+const (
+	deviceResultOK    = "ok"
+	deviceResultError = "error"
+)
+
+// MultiStatusResponse is returned with a 207 when an operation succeeded on some devices
+// but not others
+type MultiStatusResponse struct {
+	Status    string         `json:"status"`
+	Devices   []DeviceResult `json:"devices"`
+	RequestID string         `json:"requestID"`
+}
+
+// maxTransitionMs caps how long a single fade may take
+const maxTransitionMs = 10000
+
+// retryBackoff is the wait before the first retry of a failed device command; it
+// doubles with each further attempt
+const retryBackoff = 100 * time.Millisecond
+
+// parseAndValidateJSON parses JSON from request body and validates it. The body must be sent
+// as application/json, and fields v doesn't define are rejected so typos don't silently do
+// nothing.
 func (h *LightsHandler) parseAndValidateJSON(w http.ResponseWriter, r *http.Request, v interface{}, operationName string) bool {
 	requestID := getRequestID(r.Context())
-	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		h.Logger.Warn(fmt.Sprintf("Unsupported content type in %s request", operationName),
+			"requestID", requestID,
+			"contentType", r.Header.Get("Content-Type"))
+		writeJSONError(w, r, http.StatusUnsupportedMediaType, errCodeUnsupportedMedia, "Content-Type must be application/json")
+		return false
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
 		h.Logger.Error(fmt.Sprintf("Invalid JSON in %s request", operationName),
 			"requestID", requestID,
 			"error", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		message := "Invalid JSON"
+		// The decoder reports these as `json: unknown field "name"`
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			message = "unknown field " + field
+		}
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidJSON, message)
 		return false
 	}
 	return true
 }
 
-// executeLightOperation executes a light operation across all devices with proper error handling and metrics
-func (h *LightsHandler) executeLightOperation(w http.ResponseWriter, r *http.Request, operationName string, successMessage string, operationFunc func(device *govee.Device) error) {
+// executeLightOperation executes a light operation across all devices with proper error handling and metrics.
+// settings describes what the operation applies, for /lights/last; nil leaves the history untouched.
+func (h *LightsHandler) executeLightOperation(w http.ResponseWriter, r *http.Request, operationName string, successMessage string, operationFunc controller.Operation, settings *history.Settings) {
+	h.executeLightOperationPerDevice(w, r, operationName, successMessage, uniformOperation(operationFunc), settings)
+}
+
+// executeLightOperationPerDevice is executeLightOperation for operations that differ from
+// device to device: perDevice chooses what each targeted device gets.
+func (h *LightsHandler) executeLightOperationPerDevice(w http.ResponseWriter, r *http.Request, operationName string, successMessage string, perDevice deviceOperation, settings *history.Settings) {
 	requestID := getRequestID(r.Context())
 	h.Logger.Info(fmt.Sprintf("Executing %s operation", operationName), "requestID", requestID)
 
-	success := true
-	devices := h.Controller.Devices()
+	devices, ok := h.targetDevices(w, r)
+	if !ok {
+		return
+	}
+	h.applyOperation(w, r, operationName, successMessage, devices, perDevice, settings)
+}
+
+// applyOperation runs an operation on the given devices and writes the response: 200 if
+// every device succeeded, 500 if every device failed, and 207 with per-device detail if
+// only some did
+func (h *LightsHandler) applyOperation(w http.ResponseWriter, r *http.Request, operationName string, successMessage string, devices []*govee.Device, perDevice deviceOperation, settings *history.Settings) {
+	requestID := getRequestID(r.Context())
+	results := h.runOperationPerDevice(requestID, operationName, devices, perDevice, settings, h.dryRun(r))
+	if writeFailedOperation(w, r, operationName, results) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": successMessage, "requestID": requestID})
+}
+
+// writeFailedOperation writes a 500 if every device failed or a 207 with per-device detail
+// if only some did, and reports whether it wrote a response
+func writeFailedOperation(w http.ResponseWriter, r *http.Request, operationName string, results []DeviceResult) bool {
+	failed := failedDevices(results)
+	if len(failed) == 0 {
+		return false
+	}
+	if len(failed) == len(results) {
+		writeJSONError(w, r, http.StatusInternalServerError, errCodeOperationFailed, operationFailedMessage(operationName, failed))
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(MultiStatusResponse{Status: "partial", Devices: results, RequestID: getRequestID(r.Context())})
+	return true
+}
+
+// runOperation applies the same operation to every device; see runOperationPerDevice
+func (h *LightsHandler) runOperation(requestID string, operationName string, devices []*govee.Device, operationFunc controller.Operation, settings *history.Settings, dryRun bool) []DeviceResult {
+	return h.runOperationPerDevice(requestID, operationName, devices, uniformOperation(operationFunc), settings, dryRun)
+}
+
+// runOperationPerDevice applies the operation perDevice chooses to each device in turn,
+// recording metrics and sending a notification, and records settings as each device's last
+// operation when it succeeds. It returns the outcome for each device. In a dry run nothing
+// is sent to the devices; it only logs what would have happened.
+func (h *LightsHandler) runOperationPerDevice(requestID string, operationName string, devices []*govee.Device, perDevice deviceOperation, settings *history.Settings, dryRun bool) []DeviceResult {
+	if dryRun {
+		return h.dryRunOperation(requestID, operationName, devices, perDevice, settings)
+	}
+
+	results := make([]DeviceResult, len(devices))
+	errs := make([]error, len(devices))
+	// Every device's command is queued at once; the queue sends them one at a time and
+	// keeps each device's commands in order
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		operationFunc, detail := perDevice(device)
+		results[i] = DeviceResult{Device: device.DeviceID(), Label: controller.DeviceLabel(device), Result: deviceResultOK, Detail: detail}
+		wg.Go(func() {
+			errs[i] = h.apply(requestID, operationName, device, operationFunc)
+			// Even a failed command may have changed the device, so its cached status is stale
+			h.StatusCache.Invalidate(device.DeviceID())
+		})
+	}
+	wg.Wait()
+
+	failed := false
 	for i, device := range devices {
-		if err := operationFunc(device); err != nil {
+		if err := errs[i]; errors.Is(err, controller.ErrSuperseded) {
+			// A newer request is setting the device, so there's nothing to record for this one
+			results[i].Detail = err.Error()
+			metrics.LightDeviceOperationsTotal.WithLabelValues(operationName, "superseded", device.DeviceID()).Inc()
+		} else if err != nil {
 			h.Logger.Error(fmt.Sprintf("Failed to %s device", operationName),
-				"device", device.DeviceID(),
+				"device", controller.DeviceLabel(device),
 				"requestID", requestID,
 				"error", err)
-			success = false
-		}
-		// Add a small delay between device operations to prevent channel blocking
-		// This helps avoid "channel blocked or closed" errors when controlling multiple devices
-		if i < len(devices)-1 {
-			time.Sleep(100 * time.Millisecond)
+			failed = true
+			results[i].Result = deviceResultError
+			results[i].Error = err.Error()
+			metrics.LightDeviceOperationsTotal.WithLabelValues(operationName, "error", device.DeviceID()).Inc()
+		} else {
+			metrics.LightDeviceOperationsTotal.WithLabelValues(operationName, "success", device.DeviceID()).Inc()
+			if settings != nil {
+				h.History.Record(device.DeviceID(), operationName, *settings)
+			}
 		}
 	}
 
 	result := "success"
-	if !success {
+	if failed {
 		result = "error"
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("failed to %s some lights", operationName)})
-		return
 	}
+	metrics.LightOperationsTotal.WithLabelValues(operationName, result, colorLabel(settings)).Inc()
+	h.Notifier.Notify(notifications.Event{
+		Operation:   operationName,
+		Result:      result,
+		DeviceCount: len(devices),
+		Timestamp:   time.Now(),
+	})
+	return results
+}
+
+// dryRunOperation logs the operation for each device without sending it, recording
+// metrics with a "dry_run" result
+func (h *LightsHandler) dryRunOperation(requestID string, operationName string, devices []*govee.Device, perDevice deviceOperation, settings *history.Settings) []DeviceResult {
+	results := make([]DeviceResult, 0, len(devices))
+	for _, device := range devices {
+		_, detail := perDevice(device)
+		h.Logger.Info(fmt.Sprintf("Dry run: would %s device", operationName),
+			"device", controller.DeviceLabel(device),
+			"detail", detail,
+			"requestID", requestID)
+		metrics.LightDeviceOperationsTotal.WithLabelValues(operationName, "dry_run", device.DeviceID()).Inc()
+		results = append(results, DeviceResult{Device: device.DeviceID(), Label: controller.DeviceLabel(device), Result: deviceResultOK, Detail: detail})
+	}
+	metrics.LightOperationsTotal.WithLabelValues(operationName, "dry_run", colorLabel(settings)).Inc()
+	return results
+}
+
+// colorLabel is the color metrics label for an operation: the preset name for named
+// colors, "rgb" for any other color, and empty for operations that don't set a color
+func colorLabel(settings *history.Settings) string {
+	switch {
+	case settings == nil || settings.Color == nil:
+		return ""
+	case settings.ColorName != "":
+		return settings.ColorName
+	default:
+		return "rgb"
+	}
+}
 
-	metrics.LightOperationsTotal.WithLabelValues(operationName, result).Inc()
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": successMessage})
+// dryRun reports whether a request should skip device commands, either because
+// DRY_RUN is set or because it asked with ?dry_run=true
+func (h *LightsHandler) dryRun(r *http.Request) bool {
+	if h.DryRun {
+		return true
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// failedDevices returns the labels of the devices whose operation failed
+func failedDevices(results []DeviceResult) []string {
+	var failed []string
+	for _, result := range results {
+		if result.Result == deviceResultError {
+			failed = append(failed, result.Label)
+		}
+	}
+	return failed
+}
+
+// coalescable lists the operations that replace a single setting outright, so a waiting
+// one can give way to a newer one when the queue coalesces
+var coalescable = map[string]bool{"set_color": true, "set_color_temp": true, "set_brightness": true}
+
+// apply sends an operation to one device through its queue. Retries happen in the queue
+// too, so another request's command can't slip in between attempts.
+func (h *LightsHandler) apply(requestID string, operationName string, device *govee.Device, operationFunc controller.Operation) error {
+	kind := ""
+	if coalescable[operationName] {
+		kind = operationName
+	}
+	return h.Queue.DoCoalesced(device, kind, func(device *govee.Device) error {
+		return h.applyWithRetry(requestID, operationName, device, operationFunc)
+	})
+}
+
+// applyWithRetry runs an operation against one device, retrying with exponential backoff
+// up to DeviceOpRetries times. It returns the last error once retries are exhausted.
+func (h *LightsHandler) applyWithRetry(requestID string, operationName string, device *govee.Device, operationFunc controller.Operation) error {
+	err := operationFunc(device)
+	backoff := retryBackoff
+	for attempt := 1; err != nil && attempt <= h.DeviceOpRetries; attempt++ {
+		h.Logger.Warn(fmt.Sprintf("Retrying %s on device", operationName),
+			"device", controller.DeviceLabel(device),
+			"requestID", requestID,
+			"attempt", attempt,
+			"error", err)
+		metrics.LightDeviceOperationRetriesTotal.WithLabelValues(operationName, device.DeviceID()).Inc()
+		time.Sleep(backoff)
+		backoff *= 2
+		err = operationFunc(device)
+	}
+	return err
+}
+
+// operationFailedMessage describes which devices an operation failed on
+func operationFailedMessage(operationName string, failed []string) string {
+	return fmt.Sprintf("failed to %s some lights: %s", operationName, strings.Join(failed, ", "))
+}
+
+// targetDevices returns the devices an operation applies to: every device, only the
+// members of the group named by the "group" query parameter, or only the device named by
+// the "device" query parameter. It writes a 404 and returns false for unknown groups and
+// devices.
+func (h *LightsHandler) targetDevices(w http.ResponseWriter, r *http.Request) ([]*govee.Device, bool) {
+	if h.RequireDevices && len(h.Controller.Devices()) == 0 {
+		writeJSONError(w, r, http.StatusServiceUnavailable, errCodeNoDevices, "no devices available")
+		return nil, false
+	}
+	devices := h.devices()
+	if group := r.URL.Query().Get("group"); group != "" {
+		if _, ok := h.Groups.Get(group); !ok {
+			writeJSONError(w, r, http.StatusNotFound, errCodeUnknownGroup, fmt.Sprintf("unknown group %q", group))
+			return nil, false
+		}
+
+		var members []*govee.Device
+		for _, device := range devices {
+			if h.inGroup(group, device) {
+				members = append(members, device)
+			}
+		}
+		devices = members
+	}
+
+	if model := r.URL.Query().Get("model"); model != "" {
+		devices = controller.FilterByModel(devices, model)
+		if len(devices) == 0 {
+			writeJSONError(w, r, http.StatusNotFound, errCodeUnknownModel, fmt.Sprintf("no devices with model %q", model))
+			return nil, false
+		}
+	}
+
+	if deviceID := r.URL.Query().Get("device"); deviceID != "" {
+		device, ok := h.resolveDevice(w, r, devices, deviceID)
+		if !ok {
+			return nil, false
+		}
+		devices = []*govee.Device{device}
+	}
+	return devices, true
+}
+
+// inGroup reports whether the named group lists the device by ID or by alias
+func (h *LightsHandler) inGroup(group string, device *govee.Device) bool {
+	if h.Groups.Contains(group, device.DeviceID()) {
+		return true
+	}
+	alias := h.Aliases.Alias(device.DeviceID())
+	return alias != "" && h.Groups.Contains(group, alias)
+}
+
+// resolveDevice finds the device with the given ID or alias among devices, writing a 409
+// if it is disabled or a 404 if it is unknown
+func (h *LightsHandler) resolveDevice(w http.ResponseWriter, r *http.Request, devices []*govee.Device, name string) (*govee.Device, bool) {
+	deviceID := h.Aliases.Resolve(name)
+	device := findDevice(devices, deviceID)
+	if device == nil && h.Disabled.Disabled(deviceID) {
+		writeJSONError(w, r, http.StatusConflict, errCodeDeviceDisabled, fmt.Sprintf("device %q is disabled", name))
+		return nil, false
+	}
+	if device == nil {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownDevice, fmt.Sprintf("unknown device %q", name))
+		return nil, false
+	}
+	return device, true
+}
+
+// devices returns the known devices that haven't been disabled
+func (h *LightsHandler) devices() []*govee.Device {
+	return h.Disabled.Filter(h.Controller.Devices())
+}
+
+// findDevice returns the device with the given ID (case-insensitive), or nil
+func findDevice(devices []*govee.Device, deviceID string) *govee.Device {
+	for _, device := range devices {
+		if strings.EqualFold(device.DeviceID(), deviceID) {
+			return device
+		}
+	}
+	return nil
 }
 
 // getRequestID safely extracts request ID from context
@@ -92,38 +462,155 @@ func getRequestID(ctx context.Context) string {
 	return "unknown"
 }
 
+// TurnOn powers the lights on. An optional {"fade_ms": 1500} body ramps brightness up from
+// the lowest level instead of snapping on; without one, PowerOnFadeMs applies.
 func (h *LightsHandler) TurnOn(w http.ResponseWriter, r *http.Request) {
-	h.executeLightOperation(w, r, "turn_on", "lights turned on", func(device *govee.Device) error {
-		return device.TurnOn()
-	})
+	var req struct {
+		FadeMs *int `json:"fade_ms"`
+	}
+	if r.ContentLength != 0 && !h.parseAndValidateJSON(w, r, &req, "turn on") {
+		return
+	}
+	h.turnOn(w, r, req.FadeMs)
+}
+
+// turnOn switches the targeted devices on, ramping brightness up over fadeMs, or over
+// PowerOnFadeMs when fadeMs is nil
+func (h *LightsHandler) turnOn(w http.ResponseWriter, r *http.Request, fadeMs *int) {
+	if fadeMs == nil {
+		fadeMs = &h.PowerOnFadeMs
+	}
+	if *fadeMs < 0 || *fadeMs > maxTransitionMs {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidTransition, fmt.Sprintf("fade_ms must be between 0 and %d", maxTransitionMs))
+		return
+	}
+
+	operation := controller.FadeOn(time.Duration(*fadeMs)*time.Millisecond, h.transitionSteps(), h.Faded)
+	h.executeLightOperation(w, r, "turn_on", "lights turned on", operation, history.Power(true))
 }
 
+// TurnOff powers the lights off. An optional {"fade_ms": 2000} body ramps brightness down
+// to the lowest level before cutting power; without one, they switch off at once.
 func (h *LightsHandler) TurnOff(w http.ResponseWriter, r *http.Request) {
-	h.executeLightOperation(w, r, "turn_off", "lights turned off", func(device *govee.Device) error {
-		return device.TurnOff()
-	})
+	var req struct {
+		FadeMs *int `json:"fade_ms"`
+	}
+	if r.ContentLength != 0 && !h.parseAndValidateJSON(w, r, &req, "turn off") {
+		return
+	}
+	h.turnOff(w, r, req.FadeMs)
 }
 
+// turnOff switches the targeted devices off, ramping brightness down over fadeMs first
+// when it is set
+func (h *LightsHandler) turnOff(w http.ResponseWriter, r *http.Request, fadeMs *int) {
+	operation := h.Faded.TurnOff()
+	if fadeMs != nil {
+		if *fadeMs < 0 || *fadeMs > maxTransitionMs {
+			writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidTransition, fmt.Sprintf("fade_ms must be between 0 and %d", maxTransitionMs))
+			return
+		}
+		operation = controller.FadeOff(time.Duration(*fadeMs)*time.Millisecond, h.transitionSteps(), h.Faded)
+	}
+	h.executeLightOperation(w, r, "turn_off", "lights turned off", operation, history.Power(false))
+}
+
+// PowerRequest sets the power state from a boolean, for clients that hold it in a variable
+type PowerRequest struct {
+	On *bool `json:"on"`
+	// FadeMs ramps brightness up when turning on or down when turning off, as with
+	// /lights/on and /lights/off
+	FadeMs *int `json:"fade_ms,omitempty"`
+}
+
+// Power turns the targeted devices on or off from {"on": true|false}, behaving exactly
+// like /lights/on or /lights/off
+func (h *LightsHandler) Power(w http.ResponseWriter, r *http.Request) {
+	var req PowerRequest
+	if !h.parseAndValidateJSON(w, r, &req, "power") {
+		return
+	}
+	if req.On == nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidPower, `"on" must be true or false`)
+		return
+	}
+	if *req.On {
+		h.turnOn(w, r, req.FadeMs)
+		return
+	}
+	h.turnOff(w, r, req.FadeMs)
+}
+
+// SetColor applies a named color preset to the targeted devices
 func (h *LightsHandler) SetColor(w http.ResponseWriter, r *http.Request, color govee.Color, colorName string) {
-	h.executeLightOperation(w, r, "set_color", "lights set to "+colorName, func(device *govee.Device) error {
-		return device.SetColor(color)
-	})
+	h.executeLightOperation(w, r, "set_color", "lights set to "+colorName, controller.SetColor(color), history.NamedColor(colorName, color))
+}
+
+// setNamedColor looks up a preset in the color table and applies it
+func (h *LightsHandler) setNamedColor(w http.ResponseWriter, r *http.Request, name string) {
+	color, ok := h.colorTable().Lookup(name)
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownColor, fmt.Sprintf("unknown color %q", name))
+		return
+	}
+	h.SetColor(w, r, color, name)
+}
+
+// colorTable returns the configured color presets, or the defaults if none are configured
+func (h *LightsHandler) colorTable() *colors.Table {
+	if live := h.Live.Load(); live != nil && live.Colors != nil {
+		return live.Colors
+	}
+	if h.Colors == nil {
+		return colors.Default()
+	}
+	return h.Colors
+}
+
+// NamedColor applies the preset named by the {name} path segment
+func (h *LightsHandler) NamedColor(w http.ResponseWriter, r *http.Request) {
+	h.setNamedColor(w, r, r.PathValue("name"))
+}
+
+// CSSColor applies a CSS named color such as "cornflowerblue" from {"name": "tomato"}
+func (h *LightsHandler) CSSColor(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !h.parseAndValidateJSON(w, r, &req, "css color") {
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidColorName, "name is required; use a CSS color name such as \"tomato\"")
+		return
+	}
+	color, name, ok := colors.CSS(req.Name)
+	if !ok {
+		message := fmt.Sprintf("unknown color name %q; use a CSS color name such as \"tomato\"", req.Name)
+		if suggestion := colors.SuggestCSS(req.Name); suggestion != "" {
+			message = fmt.Sprintf("unknown color name %q; did you mean %q?", req.Name, suggestion)
+		}
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidColorName, message)
+		return
+	}
+	h.SetColor(w, r, color, name)
 }
 
 func (h *LightsHandler) Red(w http.ResponseWriter, r *http.Request) {
-	h.SetColor(w, r, govee.Color{R: 255, G: 0, B: 0}, "red")
+	h.setNamedColor(w, r, "red")
 }
 
 func (h *LightsHandler) Yellow(w http.ResponseWriter, r *http.Request) {
-	h.SetColor(w, r, govee.Color{R: 255, G: 255, B: 0}, "yellow")
+	h.setNamedColor(w, r, "yellow")
 }
 
 func (h *LightsHandler) Orange(w http.ResponseWriter, r *http.Request) {
-	h.SetColor(w, r, govee.Color{R: 139, G: 64, B: 0}, "orange")
+	h.setNamedColor(w, r, "orange")
 }
 
 func (h *LightsHandler) DarkRed(w http.ResponseWriter, r *http.Request) {
-	h.SetColor(w, r, govee.Color{R: 255, G: 11, B: 0}, "dark-red")
+	h.setNamedColor(w, r, "dark-red")
 }
 
 func (h *LightsHandler) RGB(w http.ResponseWriter, r *http.Request) {
@@ -131,9 +618,10 @@ func (h *LightsHandler) RGB(w http.ResponseWriter, r *http.Request) {
 	h.Logger.Info("Setting RGB color", "requestID", requestID)
 
 	var req struct {
-		R int `json:"r"`
-		G int `json:"g"`
-		B int `json:"b"`
+		R            int `json:"r"`
+		G            int `json:"g"`
+		B            int `json:"b"`
+		TransitionMs int `json:"transition_ms"`
 	}
 	if !h.parseAndValidateJSON(w, r, &req, "RGB") {
 		return
@@ -143,96 +631,313 @@ func (h *LightsHandler) RGB(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Warn("Invalid RGB values",
 			"requestID", requestID,
 			"r", req.R, "g", req.G, "b", req.B)
-		http.Error(w, "RGB values must be between 0 and 255", http.StatusBadRequest)
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidRGB, "RGB values must be between 0 and 255")
+		return
+	}
+
+	if req.TransitionMs < 0 || req.TransitionMs > maxTransitionMs {
+		h.Logger.Warn("Invalid transition", "requestID", requestID, "transition_ms", req.TransitionMs)
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidTransition, fmt.Sprintf("transition_ms must be between 0 and %d", maxTransitionMs))
 		return
 	}
 
 	color := govee.Color{R: uint(req.R), G: uint(req.G), B: uint(req.B)}
 	h.Logger.Info("Setting RGB color",
 		"requestID", requestID,
-		"color", fmt.Sprintf("rgb(%d,%d,%d)", req.R, req.G, req.B))
+		"color", fmt.Sprintf("rgb(%d,%d,%d)", req.R, req.G, req.B),
+		"transition_ms", req.TransitionMs)
+
+	operation := controller.SetColor(color)
+	if req.TransitionMs > 0 {
+		operation = controller.FadeColor(color, time.Duration(req.TransitionMs)*time.Millisecond, h.transitionSteps())
+	}
+	h.executeLightOperation(w, r, "set_color", "lights set to rgb", operation, history.Color(color))
+}
 
-	h.SetColor(w, r, color, "rgb")
+// transitionSteps returns the configured number of fade steps or the default
+func (h *LightsHandler) transitionSteps() int {
+	if h.TransitionSteps > 0 {
+		return h.TransitionSteps
+	}
+	return controller.DefaultTransitionSteps
 }
 
+// Supported color temperature range in Kelvin
+const (
+	minColorTemp = 2000
+	maxColorTemp = 9000
+)
+
+// colorTempRangeMessage is returned for any out-of-range color temperature
+var colorTempRangeMessage = fmt.Sprintf("Color temperature must be between %dK and %dK", minColorTemp, maxColorTemp)
+
+// ColorTemp sets the color temperature from a JSON body ({"temperature": 3000} in Kelvin or
+// {"mireds": 333}) or, for GET requests, from the kelvin query parameter (?kelvin=3000)
 func (h *LightsHandler) ColorTemp(w http.ResponseWriter, r *http.Request) {
+	if rejectHead(w, r) {
+		return
+	}
 	requestID := getRequestID(r.Context())
 	h.Logger.Info("Setting color temperature", "requestID", requestID)
 
+	if r.Method == http.MethodGet {
+		kelvin, err := strconv.Atoi(r.URL.Query().Get("kelvin"))
+		if err != nil {
+			h.Logger.Warn("Invalid kelvin query parameter", "requestID", requestID, "kelvin", r.URL.Query().Get("kelvin"))
+			writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidColorTemp, colorTempRangeMessage)
+			return
+		}
+		h.setColorTemp(w, r, kelvin)
+		return
+	}
+
 	var req struct {
-		Temperature int `json:"temperature"`
+		Temperature int  `json:"temperature"`
+		Mireds      *int `json:"mireds"`
 	}
 	if !h.parseAndValidateJSON(w, r, &req, "color temperature") {
 		return
 	}
+	if req.Mireds != nil {
+		if req.Temperature != 0 {
+			writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidColorTemp, "Specify either temperature or mireds, not both")
+			return
+		}
+		h.setColorTemp(w, r, controller.MiredsToKelvin(*req.Mireds))
+		return
+	}
+	h.setColorTemp(w, r, req.Temperature)
+}
 
-	if req.Temperature < 2000 || req.Temperature > 9000 {
+// setColorTemp validates a color temperature in Kelvin and applies it to the targeted devices
+func (h *LightsHandler) setColorTemp(w http.ResponseWriter, r *http.Request, kelvin int) {
+	requestID := getRequestID(r.Context())
+	if !validColorTemp(kelvin) {
 		h.Logger.Warn("Invalid color temperature",
 			"requestID", requestID,
-			"temperature", req.Temperature)
-		http.Error(w, "Color temperature must be between 2000K and 9000K", http.StatusBadRequest)
+			"temperature", kelvin)
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidColorTemp, colorTempRangeMessage)
 		return
 	}
 
-	colorTemp := govee.NewColorKelvin(uint(req.Temperature))
+	colorTemp := govee.NewColorKelvin(uint(kelvin))
 	h.Logger.Info("Setting color temperature",
 		"requestID", requestID,
-		"temperature", fmt.Sprintf("%dK", req.Temperature))
+		"temperature", fmt.Sprintf("%dK", kelvin))
 
-	h.executeLightOperation(w, r, "set_color_temp", "color temperature set", func(device *govee.Device) error {
-		return device.SetColorKelvin(colorTemp)
-	})
+	h.executeLightOperation(w, r, "set_color_temp", "color temperature set", controller.SetColorKelvin(colorTemp), history.Temperature(kelvin))
 }
 
+// validColorTemp reports whether kelvin is within the supported range
+func validColorTemp(kelvin int) bool {
+	return kelvin >= minColorTemp && kelvin <= maxColorTemp
+}
+
+// brightnessRangeMessage is returned for any out-of-range brightness
+const brightnessRangeMessage = "Brightness must be between 0 and 100"
+
+// Brightness sets the brightness from a JSON body ({"brightness": 75}) or, for GET
+// requests, from the level query parameter (?level=75)
 func (h *LightsHandler) Brightness(w http.ResponseWriter, r *http.Request) {
+	if rejectHead(w, r) {
+		return
+	}
 	requestID := getRequestID(r.Context())
 	h.Logger.Info("Setting brightness", "requestID", requestID)
 
+	if r.Method == http.MethodGet {
+		level, err := strconv.Atoi(r.URL.Query().Get("level"))
+		if err != nil {
+			h.Logger.Warn("Invalid level query parameter", "requestID", requestID, "level", r.URL.Query().Get("level"))
+			writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidBrightness, brightnessRangeMessage)
+			return
+		}
+		h.setBrightness(w, r, level)
+		return
+	}
+
 	var req struct {
 		Brightness int `json:"brightness"`
 	}
 	if !h.parseAndValidateJSON(w, r, &req, "brightness") {
 		return
 	}
+	h.setBrightness(w, r, req.Brightness)
+}
+
+// rejectHead answers HEAD with a 405 for handlers whose GET changes the lights. ServeMux
+// routes HEAD to GET patterns, and a HEAD must never have side effects.
+func rejectHead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodHead {
+		return false
+	}
+	w.Header().Set("Allow", "GET, POST, OPTIONS")
+	writeJSONError(w, r, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "HEAD is not allowed here; GET changes the lights")
+	return true
+}
 
-	if req.Brightness < 0 || req.Brightness > 100 {
+// setBrightness validates a brightness percentage and applies it to the targeted devices
+func (h *LightsHandler) setBrightness(w http.ResponseWriter, r *http.Request, brightness int) {
+	if brightness < 0 || brightness > 100 {
 		h.Logger.Warn("Invalid brightness value",
-			"requestID", requestID,
-			"brightness", req.Brightness)
-		http.Error(w, "Brightness must be between 0 and 100", http.StatusBadRequest)
+			"requestID", getRequestID(r.Context()),
+			"brightness", brightness)
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidBrightness, brightnessRangeMessage)
+		return
+	}
+
+	h.executeLightOperation(w, r, "set_brightness", "brightness set", h.Faded.SetBrightness(govee.Brightness(brightness)), history.Brightness(brightness))
+}
+
+// Identify flashes a single device, named by the "device" query parameter, so it can be
+// located, then restores its power state
+func (h *LightsHandler) Identify(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("device") == "" {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidDevice, "device query parameter is required")
+		return
+	}
+	dryRun := h.dryRun(r)
+	identify := func(device *govee.Device) (controller.Operation, string) {
+		wasOn := false
+		if !dryRun {
+			// Read the power state once, outside the retried operation, so a retry after a
+			// failed flash can't mistake the light for one that was off. Fall back to the last
+			// reported state if the device doesn't answer.
+			_ = h.Queue.Do(device, (*govee.Device).RequestStatus)
+			wasOn = controller.PoweredOn(device)
+		}
+		return controller.Identify(controller.DefaultIdentifyFlashes, controller.DefaultIdentifyInterval, wasOn), ""
+	}
+	h.executeLightOperationPerDevice(w, r, "identify", "device identified", identify, nil)
+}
+
+// OffAllExceptResponse reports which device was kept and how many were turned off
+type OffAllExceptResponse struct {
+	Status    string `json:"status"`
+	Kept      string `json:"kept"`
+	TurnedOff int    `json:"turnedOff"`
+	RequestID string `json:"requestID"`
+}
+
+// OffAllExcept turns off every device except the one named by the "device" query
+// parameter, which is left as it is, e.g. to spotlight a single fixture
+func (h *LightsHandler) OffAllExcept(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	deviceID := r.URL.Query().Get("device")
+	if deviceID == "" {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidDevice, "device query parameter is required")
+		return
+	}
+	devices := h.devices()
+	kept, ok := h.resolveDevice(w, r, devices, deviceID)
+	if !ok {
 		return
 	}
 
-	h.executeLightOperation(w, r, "set_brightness", "brightness set", func(device *govee.Device) error {
-		return device.SetBrightness(govee.Brightness(req.Brightness))
+	others := exceptDevice(devices, kept)
+	h.Logger.Info("Turning off all lights except one", "requestID", requestID, "kept", controller.DeviceLabel(kept), "devices", len(others))
+	results := h.runOperation(requestID, "turn_off", others, h.Faded.TurnOff(), history.Power(false), h.dryRun(r))
+	if writeFailedOperation(w, r, "turn_off", results) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(OffAllExceptResponse{
+		Status:    "other lights turned off",
+		Kept:      kept.DeviceID(),
+		TurnedOff: len(others),
+		RequestID: requestID,
 	})
 }
 
+// exceptDevice returns devices without kept
+func exceptDevice(devices []*govee.Device, kept *govee.Device) []*govee.Device {
+	var others []*govee.Device
+	for _, device := range devices {
+		if device != kept {
+			others = append(others, device)
+		}
+	}
+	return others
+}
+
 func (h *LightsHandler) Status(w http.ResponseWriter, r *http.Request) {
 	requestID := getRequestID(r.Context())
 	h.Logger.Info("Getting lights status", "requestID", requestID)
+	metrics.SetDeviceCount(len(h.Controller.Devices()))
+
+	// Disabled devices are left out of the payload; name them so their absence is explained
+	if disabled := h.Disabled.List(); len(disabled) > 0 {
+		w.Header().Set("Disabled-Devices", strings.Join(disabled, ","))
+	}
+	// Dashboards polling with If-None-Match get a 304 until some device changes
+	writeJSONWithETag(w, r, h.collectStatuses(requestID, wantFresh(r)))
+}
+
+// Summary returns a one-line rollup of every device's state
+func (h *LightsHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	h.Logger.Info("Getting lights summary", "requestID", requestID)
 
+	summary := controller.Summarize(h.refreshDevices(requestID, wantFresh(r)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// Last returns the last successful operation applied to each device, keyed by device ID.
+// It answers from memory without contacting the devices.
+func (h *LightsHandler) Last(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	h.Logger.Info("Getting last operations", "requestID", requestID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.History.All())
+}
+
+// collectStatuses refreshes every device's status and returns the status payload
+func (h *LightsHandler) collectStatuses(requestID string, fresh bool) []map[string]interface{} {
 	var statuses []map[string]interface{}
-	for _, device := range h.Controller.Devices() {
+	for _, device := range h.refreshDevices(requestID, fresh) {
+		status := controller.DeviceStatus(device)
+		if alias := h.Aliases.Alias(device.DeviceID()); alias != "" {
+			status["alias"] = alias
+		}
+		if h.Breakers != nil {
+			status["breaker"] = h.Breakers.State(device.DeviceID())
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// refreshDevices requests a status from every device, updates the device gauges and
+// returns the devices that responded. Devices refreshed within the status cache TTL are
+// returned as they are unless fresh is set.
+func (h *LightsHandler) refreshDevices(requestID string, fresh bool) []*govee.Device {
+	var refreshed, requested []*govee.Device
+	for _, device := range h.devices() {
+		if !fresh && h.StatusCache.Fresh(device.DeviceID()) {
+			refreshed = append(refreshed, device)
+			continue
+		}
 		err := device.RequestStatus()
 		if err != nil {
-			h.Logger.Error("Failed to request status", "device", device.DeviceID(), "requestID", requestID, "error", err)
+			h.Logger.Error("Failed to request status", "device", controller.DeviceLabel(device), "requestID", requestID, "error", err)
 			continue
 		}
-		color := device.Color()
-		status := map[string]interface{}{
-			"deviceID":   device.DeviceID(),
-			"onOff":      device.Active(),
-			"brightness": int(device.Brightness()),
-			"color": map[string]int{
-				"r": int(color.R),
-				"g": int(color.G),
-				"b": int(color.B),
-			},
-			"colortemp": device.ColorKelvin().String(),
-		}
-		statuses = append(statuses, status)
+		h.StatusCache.Mark(device.DeviceID())
+		refreshed = append(refreshed, device)
+		requested = append(requested, device)
 	}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(statuses)
+	controller.UpdateDeviceMetrics(requested)
+	return refreshed
+}
+
+// wantFresh reports whether the request asked to bypass the status cache with ?fresh=true
+func wantFresh(r *http.Request) bool {
+	fresh, _ := strconv.ParseBool(r.URL.Query().Get("fresh"))
+	return fresh
 }
@@ -0,0 +1,138 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/history"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// PatchRGB is the color in a PATCH /lights request
+type PatchRGB struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// PatchRequest is a partial light state; absent fields are left unchanged
+type PatchRequest struct {
+	Power      *string   `json:"power"`
+	Brightness *int      `json:"brightness"`
+	RGB        *PatchRGB `json:"rgb"`
+	Kelvin     *int      `json:"kelvin"`
+}
+
+// PatchResponse lists the fields that were applied, in the order they were applied
+type PatchResponse struct {
+	Status    string   `json:"status"`
+	Applied   []string `json:"applied"`
+	RequestID string   `json:"requestID"`
+}
+
+// Patch applies whichever of power, rgb, kelvin and brightness the body provides as one
+// operation per device: power on first, then the color or temperature, then brightness.
+// Turning a light off comes last instead, since setting a color switches it back on.
+func (h *LightsHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	var req PatchRequest
+	if !h.parseAndValidateJSON(w, r, &req, "patch") {
+		return
+	}
+	if req.Power == nil && req.Brightness == nil && req.RGB == nil && req.Kelvin == nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidPatch, "at least one of power, brightness, rgb or kelvin is required")
+		return
+	}
+	if req.Power != nil && *req.Power != "on" && *req.Power != "off" {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidPower, `power must be "on" or "off"`)
+		return
+	}
+	if req.RGB != nil && req.Kelvin != nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidPatch, "rgb and kelvin cannot both be set")
+		return
+	}
+	if rgb := req.RGB; rgb != nil && (rgb.R < 0 || rgb.R > 255 || rgb.G < 0 || rgb.G > 255 || rgb.B < 0 || rgb.B > 255) {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidRGB, "RGB values must be between 0 and 255")
+		return
+	}
+	if req.Kelvin != nil && !validColorTemp(*req.Kelvin) {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidColorTemp, colorTempRangeMessage)
+		return
+	}
+	if req.Brightness != nil && (*req.Brightness < 0 || *req.Brightness > 100) {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidBrightness, brightnessRangeMessage)
+		return
+	}
+
+	devices, ok := h.targetDevices(w, r)
+	if !ok {
+		return
+	}
+
+	applied, operation, settings := patchOperation(req, h.Faded)
+	h.Logger.Info("Executing patch operation", "requestID", requestID, "fields", applied)
+
+	results := h.runOperation(requestID, "patch", devices, operation, settings, h.dryRun(r))
+	if writeFailedOperation(w, r, "patch", results) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(PatchResponse{Status: "lights updated", Applied: applied, RequestID: requestID})
+}
+
+// patchOperation turns a validated PatchRequest into the fields it sets, in the order they
+// are applied, a single operation applying them and the settings to record. Power and
+// brightness changes go through faded, so a light faded off comes back at its old level.
+func patchOperation(req PatchRequest, faded *controller.FadedBrightness) ([]string, controller.Operation, *history.Settings) {
+	var applied []string
+	var ops []controller.Operation
+	settings := &history.Settings{}
+
+	off := req.Power != nil && *req.Power == "off"
+	if req.Power != nil && !off {
+		applied = append(applied, "power")
+		ops = append(ops, faded.TurnOn())
+	}
+	if req.RGB != nil {
+		color := govee.Color{R: uint(req.RGB.R), G: uint(req.RGB.G), B: uint(req.RGB.B)}
+		applied = append(applied, "rgb")
+		ops = append(ops, controller.SetColor(color))
+		settings.Color = &color
+	}
+	if req.Kelvin != nil {
+		applied = append(applied, "kelvin")
+		ops = append(ops, controller.SetColorKelvin(govee.NewColorKelvin(uint(*req.Kelvin))))
+		settings.Temperature = req.Kelvin
+	}
+	if req.Brightness != nil {
+		applied = append(applied, "brightness")
+		ops = append(ops, faded.SetBrightness(govee.Brightness(*req.Brightness)))
+		settings.Brightness = req.Brightness
+	}
+	if off {
+		applied = append(applied, "power")
+		ops = append(ops, faded.TurnOff())
+	}
+	if req.Power != nil {
+		on := !off
+		settings.On = &on
+	}
+	return applied, controller.Sequence(ops...), settings
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/history"
+	"github.com/jwhitcraft/lights-http/schedules"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// CreateSchedule adds a recurring action from {"cron": "0 8 * * *", "action": {...}}. The
+// action takes the same form as a batch step; a brightness on any op other than
+// "brightness" is applied after it.
+func (h *LightsHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	var req struct {
+		Cron   string           `json:"cron"`
+		Action schedules.Action `json:"action"`
+	}
+	if !h.parseAndValidateJSON(w, r, &req, "schedule") {
+		return
+	}
+	if _, err := h.scheduledOperations(req.Action); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidSchedule, err.Error())
+		return
+	}
+
+	schedule, err := h.Schedules.Add(req.Cron, req.Action)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidSchedule, err.Error())
+		return
+	}
+	h.Logger.Info("Schedule created", "schedule", schedule.ID, "cron", schedule.Cron, "op", schedule.Action.Op, "requestID", requestID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// ListSchedules returns every schedule with the time it next runs
+func (h *LightsHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Schedules.List())
+}
+
+// DeleteSchedule stops and removes the schedule with the ID in the path
+func (h *LightsHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	id := r.PathValue("id")
+	ok, err := h.Schedules.Delete(id)
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownSchedule, fmt.Sprintf("unknown schedule %q", id))
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to delete schedule", "schedule", id, "requestID", requestID, "error", err)
+		writeJSONError(w, r, http.StatusInternalServerError, errCodeInternal, "failed to save schedules")
+		return
+	}
+	h.Logger.Info("Schedule deleted", "schedule", id, "requestID", requestID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunSchedule applies a schedule's action to every device. It is the schedules.Runner
+// for the scheduler.
+func (h *LightsHandler) RunSchedule(schedule schedules.Schedule) error {
+	steps, err := h.scheduledOperations(schedule.Action)
+	if err != nil {
+		return err
+	}
+	requestID := "schedule-" + schedule.ID
+	devices := h.devices()
+	for _, step := range steps {
+		if failed := failedDevices(h.runOperation(requestID, step.name, devices, step.operation, step.settings, h.DryRun)); len(failed) > 0 {
+			return errors.New(operationFailedMessage(step.name, failed))
+		}
+	}
+	return nil
+}
+
+// scheduledOperation is one operation a schedule applies
+type scheduledOperation struct {
+	name      string
+	operation controller.Operation
+	settings  *history.Settings
+}
+
+// scheduledOperations validates an action and returns the operations it applies: the
+// action's op, then its brightness when the op doesn't set brightness itself
+func (h *LightsHandler) scheduledOperations(action schedules.Action) ([]scheduledOperation, error) {
+	name, operation, settings, err := h.batchOperation(BatchStep(action))
+	if err != nil {
+		return nil, err
+	}
+	steps := []scheduledOperation{{name, operation, settings}}
+
+	if action.Op != "brightness" && action.Brightness != 0 {
+		if action.Brightness < 0 || action.Brightness > 100 {
+			return nil, fmt.Errorf("brightness must be between 0 and 100")
+		}
+		steps = append(steps, scheduledOperation{"set_brightness", h.Faded.SetBrightness(govee.Brightness(action.Brightness)), history.Brightness(action.Brightness)})
+	}
+	return steps, nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/colors"
+)
+
+// Settings are the handler settings POST /admin/reload can replace while the server runs
+type Settings struct {
+	Colors *colors.Table
+	// DeviceOpDelay is the gap the device queue leaves between commands, across all devices
+	DeviceOpDelay time.Duration
+}
+
+// LiveSettings holds the current Settings. Handlers load it on every request, so a
+// reload swaps the whole set at once and never shows a half-applied one.
+type LiveSettings struct {
+	current atomic.Pointer[Settings]
+}
+
+// NewLiveSettings returns a LiveSettings holding s
+func NewLiveSettings(s Settings) *LiveSettings {
+	live := &LiveSettings{}
+	live.Store(s)
+	return live
+}
+
+// Load returns the current settings, or nil if none have been stored; a nil
+// LiveSettings holds none
+func (l *LiveSettings) Load() *Settings {
+	if l == nil {
+		return nil
+	}
+	return l.current.Load()
+}
+
+// Store replaces the current settings
+func (l *LiveSettings) Store(s Settings) {
+	l.current.Store(&s)
+}
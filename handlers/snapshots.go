@@ -0,0 +1,113 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/snapshots"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// CreateSnapshot captures the power, color and brightness of the targeted devices into a
+// snapshot named by the "name" query parameter, replacing any snapshot with that name
+func (h *LightsHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidSnapshot, "name query parameter is required")
+		return
+	}
+
+	devices, ok := h.targetDevices(w, r)
+	if !ok {
+		return
+	}
+
+	snapshot := snapshots.Snapshot{Name: name, CreatedAt: time.Now(), Devices: make(map[string]snapshots.DeviceState)}
+	var failed []string
+	for _, device := range devices {
+		if err := device.RequestStatus(); err != nil {
+			h.Logger.Error("Failed to request status", "device", controller.DeviceLabel(device), "requestID", requestID, "error", err)
+			failed = append(failed, controller.DeviceLabel(device))
+			continue
+		}
+		snapshot.Devices[device.DeviceID()] = snapshots.Capture(device)
+	}
+	if len(failed) > 0 {
+		writeJSONError(w, r, http.StatusInternalServerError, errCodeOperationFailed, operationFailedMessage("snapshot", failed))
+		return
+	}
+
+	if err := h.Snapshots.Save(snapshot); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidSnapshot, err.Error())
+		return
+	}
+	h.Logger.Info("Snapshot saved", "snapshot", name, "devices", len(snapshot.Devices), "requestID", requestID)
+
+	saved, _ := h.Snapshots.Get(name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// RestoreSnapshot reapplies the snapshot named by the "name" query parameter to every
+// device it captured that is still known
+func (h *LightsHandler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	name := r.URL.Query().Get("name")
+	snapshot, ok := h.Snapshots.Get(name)
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownSnapshot, fmt.Sprintf("unknown snapshot %q", name))
+		return
+	}
+	h.Logger.Info("Restoring snapshot", "snapshot", snapshot.Name, "requestID", requestID)
+
+	var devices []*govee.Device
+	for _, device := range h.devices() {
+		if _, ok := snapshot.Devices[device.DeviceID()]; ok {
+			devices = append(devices, device)
+		}
+	}
+
+	restore := func(device *govee.Device) (controller.Operation, string) {
+		state := snapshot.Devices[device.DeviceID()]
+		return h.Faded.Forget(controller.SetState(state.On, state.Color, govee.Brightness(state.Brightness))), ""
+	}
+	h.applyOperation(w, r, "restore_snapshot", "snapshot "+snapshot.Name+" restored", devices, restore, nil)
+}
+
+// ListSnapshots returns every snapshot sorted by name
+func (h *LightsHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Snapshots.List())
+}
+
+// DeleteSnapshot removes the snapshot named in the path
+func (h *LightsHandler) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+	name := r.PathValue("name")
+	if !h.Snapshots.Delete(name) {
+		writeJSONError(w, r, http.StatusNotFound, errCodeUnknownSnapshot, fmt.Sprintf("unknown snapshot %q", name))
+		return
+	}
+	h.Logger.Info("Snapshot deleted", "snapshot", name, "requestID", requestID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jwhitcraft/lights-http/metrics"
+)
+
+const (
+	// defaultStreamInterval is used when LightsHandler.StreamInterval is not set
+	defaultStreamInterval = 5 * time.Second
+	// eventsHeartbeatInterval keeps idle proxies from closing the event stream
+	eventsHeartbeatInterval = 15 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// Stream upgrades the connection to a WebSocket and pushes a status snapshot every StreamInterval
+func (h *LightsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	// The deadline carries over to the hijacked connection, so lift it before upgrading
+	clearWriteDeadline(w)
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response to the client
+		h.Logger.Error("Failed to upgrade stream connection", "requestID", requestID, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	h.Logger.Info("Status stream opened", "requestID", requestID)
+	metrics.StreamSubscribers.WithLabelValues("websocket").Inc()
+	defer metrics.StreamSubscribers.WithLabelValues("websocket").Dec()
+
+	// Drain incoming frames so close messages are processed; a read error means the client is gone
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(h.streamInterval())
+	defer ticker.Stop()
+
+	for {
+		if err := conn.WriteJSON(h.collectStatuses(requestID, false)); err != nil {
+			h.Logger.Warn("Failed to write status to stream", "requestID", requestID, "error", err)
+			return
+		}
+
+		select {
+		case <-done:
+			h.Logger.Info("Status stream closed by client", "requestID", requestID)
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Events streams status snapshots as Server-Sent Events every StreamInterval
+func (h *LightsHandler) Events(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.Logger.Error("Streaming unsupported by response writer", "requestID", requestID)
+		writeJSONError(w, r, http.StatusInternalServerError, errCodeStreamingUnsupported, "Streaming unsupported")
+		return
+	}
+
+	clearWriteDeadline(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	h.Logger.Info("Event stream opened", "requestID", requestID)
+	metrics.StreamSubscribers.WithLabelValues("sse").Inc()
+	defer metrics.StreamSubscribers.WithLabelValues("sse").Dec()
+
+	ticker := time.NewTicker(h.streamInterval())
+	defer ticker.Stop()
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		payload, err := json.Marshal(h.collectStatuses(requestID, false))
+		if err != nil {
+			h.Logger.Error("Failed to encode status event", "requestID", requestID, "error", err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", payload); err != nil {
+			h.Logger.Warn("Failed to write status event", "requestID", requestID, "error", err)
+			return
+		}
+		flusher.Flush()
+
+	wait:
+		for {
+			select {
+			case <-r.Context().Done():
+				h.Logger.Info("Event stream closed by client", "requestID", requestID)
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-ticker.C:
+				break wait
+			}
+		}
+	}
+}
+
+// streamInterval returns the configured push interval for the streaming endpoints
+func (h *LightsHandler) streamInterval() time.Duration {
+	if h.StreamInterval <= 0 {
+		return defaultStreamInterval
+	}
+	return h.StreamInterval
+}
+
+// clearWriteDeadline lifts the server's WriteTimeout for a long-lived stream. Writers that
+// don't support deadlines, such as httptest's recorder, are left as they are.
+func clearWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
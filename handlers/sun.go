@@ -0,0 +1,87 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jwhitcraft/lights-http/solar"
+)
+
+// sunActions are the batch ops run at each event: warm white at sunset, off at sunrise
+var sunActions = map[string]BatchStep{
+	solar.Sunset:  {Op: "warm"},
+	solar.Sunrise: {Op: "off"},
+}
+
+// SunMode reports whether sunrise/sunset mode is enabled and when it next acts
+func (h *LightsHandler) SunMode(w http.ResponseWriter, r *http.Request) {
+	if h.Sun == nil {
+		writeSunUnconfigured(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.Sun.Status())
+}
+
+// SetSunMode enables or disables sunrise/sunset mode from {"enabled": true}
+func (h *LightsHandler) SetSunMode(w http.ResponseWriter, r *http.Request) {
+	if h.Sun == nil {
+		writeSunUnconfigured(w, r)
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if !h.parseAndValidateJSON(w, r, &req, "sun mode") {
+		return
+	}
+	if req.Enabled == nil {
+		writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidSunMode, "enabled is required")
+		return
+	}
+
+	if *req.Enabled {
+		h.Sun.Enable()
+	} else {
+		h.Sun.Disable()
+	}
+	h.SunMode(w, r)
+}
+
+// RunSunEvent applies the action for a sunrise or sunset to every device. It is the
+// callback for solar.Auto.
+func (h *LightsHandler) RunSunEvent(event string) error {
+	step, ok := sunActions[event]
+	if !ok {
+		return fmt.Errorf("unknown solar event %q", event)
+	}
+	name, operation, settings, err := h.batchOperation(step)
+	if err != nil {
+		return err
+	}
+	if failed := failedDevices(h.runOperation("sun-"+event, name, h.devices(), operation, settings, h.DryRun)); len(failed) > 0 {
+		return errors.New(operationFailedMessage(name, failed))
+	}
+	return nil
+}
+
+func writeSunUnconfigured(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, r, http.StatusBadRequest, errCodeInvalidSunMode, "set LATITUDE and LONGITUDE to use sunrise/sunset mode")
+}
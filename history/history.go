@@ -0,0 +1,111 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package history remembers the last successful light operation applied to each device.
+package history
+
+import (
+	"sync"
+	"time"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Settings are the values an operation applied; fields an operation doesn't set are nil
+type Settings struct {
+	On          *bool        `json:"on,omitempty"`
+	Color       *govee.Color `json:"color,omitempty"`
+	ColorName   string       `json:"color_name,omitempty"`
+	Brightness  *int         `json:"brightness,omitempty"`
+	Temperature *int         `json:"temperature,omitempty"`
+}
+
+// Power returns the settings of an operation that turns devices on or off
+func Power(on bool) *Settings {
+	return &Settings{On: &on}
+}
+
+// Color returns the settings of an operation that sets an RGB color
+func Color(color govee.Color) *Settings {
+	return &Settings{Color: &color}
+}
+
+// NamedColor returns the settings of an operation that applies a named color preset
+func NamedColor(name string, color govee.Color) *Settings {
+	return &Settings{Color: &color, ColorName: name}
+}
+
+// Brightness returns the settings of an operation that sets brightness
+func Brightness(brightness int) *Settings {
+	return &Settings{Brightness: &brightness}
+}
+
+// Temperature returns the settings of an operation that sets a color temperature in Kelvin
+func Temperature(kelvin int) *Settings {
+	return &Settings{Temperature: &kelvin}
+}
+
+// Entry is the last successful operation on a device
+type Entry struct {
+	Operation string `json:"operation"`
+	Settings
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Tracker records the last successful operation per device ID. It is safe for concurrent
+// use, and a nil Tracker records nothing.
+type Tracker struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// NewTracker returns an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{entries: make(map[string]Entry)}
+}
+
+// Record stores operation as the last one applied to the device
+func (t *Tracker) Record(deviceID string, operation string, settings Settings) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[deviceID] = Entry{Operation: operation, Settings: settings, Timestamp: time.Now()}
+}
+
+// Get returns the last operation applied to the device
+func (t *Tracker) Get(deviceID string) (Entry, bool) {
+	if t == nil {
+		return Entry{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	entry, ok := t.entries[deviceID]
+	return entry, ok
+}
+
+// All returns a copy of every device's last operation, keyed by device ID
+func (t *Tracker) All() map[string]Entry {
+	all := make(map[string]Entry)
+	if t == nil {
+		return all
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for deviceID, entry := range t.entries {
+		all[deviceID] = entry
+	}
+	return all
+}
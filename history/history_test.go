@@ -0,0 +1,50 @@
+package history
+
+import (
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+
+	brightness := 40
+	tracker.Record("AA", "set_brightness", Settings{Brightness: &brightness})
+	tracker.Record("BB", "set_color", Settings{Color: &govee.Color{R: 255}})
+	tracker.Record("AA", "turn_off", Settings{})
+
+	entry, ok := tracker.Get("AA")
+	if !ok {
+		t.Fatal("expected an entry for AA")
+	}
+	if entry.Operation != "turn_off" || entry.Brightness != nil {
+		t.Errorf("expected the latest operation to replace the earlier one, got %+v", entry)
+	}
+	if entry.Timestamp.IsZero() {
+		t.Error("expected a timestamp")
+	}
+
+	all := tracker.All()
+	if len(all) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(all))
+	}
+	if all["BB"].Color == nil || all["BB"].Color.R != 255 {
+		t.Errorf("expected BB's color to be recorded, got %+v", all["BB"])
+	}
+	delete(all, "BB")
+	if _, ok := tracker.Get("BB"); !ok {
+		t.Error("expected All to return a copy")
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Record("AA", "turn_on", Settings{})
+	if _, ok := tracker.Get("AA"); ok {
+		t.Error("expected a nil tracker to record nothing")
+	}
+	if len(tracker.All()) != 0 {
+		t.Error("expected a nil tracker to be empty")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging builds the application's structured logger.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Supported values for Options.Output
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+	OutputBoth   = "both"
+)
+
+// Options controls where logs are written and how log files are rotated
+type Options struct {
+	// Output is one of OutputStdout, OutputFile or OutputBoth
+	Output string
+	// File is the log file path, required for OutputFile and OutputBoth
+	File string
+
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+
+	// Level is the lowest level logged; nil means slog.LevelInfo. Pass a *slog.LevelVar
+	// to change the level while the server runs.
+	Level slog.Leveler
+}
+
+// New returns a JSON logger writing to the destinations in opts
+func New(opts Options) (*slog.Logger, error) {
+	w, err := writer(opts)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:     opts.Level,
+		AddSource: true,
+	})), nil
+}
+
+// writer resolves opts to the destination writer
+func writer(opts Options) (io.Writer, error) {
+	if opts.Output == "" || opts.Output == OutputStdout {
+		return os.Stdout, nil
+	}
+	if opts.Output != OutputFile && opts.Output != OutputBoth {
+		return nil, fmt.Errorf("unknown log output %q", opts.Output)
+	}
+	if opts.File == "" {
+		return nil, fmt.Errorf("log output %q requires a log file", opts.Output)
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   opts.File,
+		MaxSize:    opts.MaxSizeMB,
+		MaxBackups: opts.MaxBackups,
+		MaxAge:     opts.MaxAgeDays,
+	}
+	if opts.Output == OutputFile {
+		return file, nil
+	}
+	return io.MultiWriter(os.Stdout, file), nil
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewWritesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lights.log")
+	logger, err := New(Options{Output: OutputFile, File: path, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Info("hello", "requestID", "abc")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("expected a JSON log line, got %q", data)
+	}
+	if entry["msg"] != "hello" || entry["requestID"] != "abc" {
+		t.Errorf("unexpected log entry %v", entry)
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"unknown output", Options{Output: "syslog"}},
+		{"file output without file", Options{Output: OutputFile}},
+		{"both output without file", Options{Output: OutputBoth}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Errorf("expected error for %+v", tt.opts)
+			}
+		})
+	}
+}
+
+func TestNewDefaultsToStdout(t *testing.T) {
+	w, err := writer(Options{})
+	if err != nil {
+		t.Fatalf("writer() error = %v", err)
+	}
+	if w != os.Stdout {
+		t.Errorf("expected stdout writer by default")
+	}
+}
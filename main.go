@@ -15,47 +15,330 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/jwhitcraft/lights-http/colors"
 	"github.com/jwhitcraft/lights-http/config"
 	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/effects"
+	"github.com/jwhitcraft/lights-http/groups"
 	"github.com/jwhitcraft/lights-http/handlers"
+	"github.com/jwhitcraft/lights-http/history"
+	"github.com/jwhitcraft/lights-http/logging"
+	"github.com/jwhitcraft/lights-http/metrics"
 	"github.com/jwhitcraft/lights-http/middleware"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/jwhitcraft/lights-http/mqtt"
+	"github.com/jwhitcraft/lights-http/notifications"
+	"github.com/jwhitcraft/lights-http/openapi"
+	"github.com/jwhitcraft/lights-http/schedules"
+	"github.com/jwhitcraft/lights-http/snapshots"
+	"github.com/jwhitcraft/lights-http/solar"
+	"github.com/jwhitcraft/lights-http/version"
+	"github.com/prometheus/client_golang/prometheus"
+	govee "github.com/swrm-io/go-vee"
+	"golang.org/x/crypto/acme/autocert"
 )
 
+// statusRecorder records the response status. Statuses matched by intercept are
+// swallowed, along with their body, so the caller can write a replacement response.
 type statusRecorder struct {
 	http.ResponseWriter
-	status int
+	status      int
+	intercept   func(status int) bool
+	intercepted bool
 }
 
 func (sr *statusRecorder) WriteHeader(code int) {
 	sr.status = code
+	if sr.intercept != nil && sr.intercept(code) {
+		sr.intercepted = true
+		return
+	}
 	sr.ResponseWriter.WriteHeader(code)
 }
 
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.intercepted {
+		return len(b), nil
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers push buffered data to the client
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades take over the underlying connection
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. so streams can
+// lift the write deadline
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// notFoundHandler replaces 404 and 405 responses from next that weren't already written as
+// JSON. Browsers (Accept: text/html) are redirected to target for a 404; everyone else gets
+// a JSON error. The mux's Allow header is kept on a 405.
+func notFoundHandler(next http.Handler, target string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srw := &statusRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+			intercept: func(status int) bool {
+				return (status == http.StatusNotFound || status == http.StatusMethodNotAllowed) &&
+					w.Header().Get("Content-Type") != "application/json"
+			},
+		}
+		next.ServeHTTP(srw, r)
+		if !srw.intercepted {
+			return
+		}
+
+		// Drop the headers set for the swallowed body
+		w.Header().Del("Content-Type")
+		w.Header().Del("X-Content-Type-Options")
+		if srw.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "method_not_allowed"})
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "not_found"})
+	})
+}
+
+// withBasePath serves next under basePath, e.g. /api/v1/lights/on for /lights/on. Any
+// rootPaths are also served without the prefix. An empty basePath returns next unchanged.
+func withBasePath(basePath string, next http.Handler, rootPaths ...string) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	mux := http.NewServeMux()
+	mux.Handle(basePath+"/", http.StripPrefix(basePath, next))
+	for _, path := range rootPaths {
+		mux.Handle(path, next)
+	}
+	return mux
+}
+
+// newServer returns a server for handler on addr with the configured timeouts. Streaming
+// handlers lift the write timeout for their own connections.
+func newServer(addr string, handler http.Handler, cfg *config.Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// registerPprof mounts the net/http/pprof handlers under /debug/pprof/ on mux, each wrapped
+// by wrap so they share the metrics server's authentication
+func registerPprof(mux *http.ServeMux, wrap func(http.Handler) http.Handler) {
+	mux.Handle("/debug/pprof/", wrap(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", wrap(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", wrap(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", wrap(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", wrap(http.HandlerFunc(pprof.Trace)))
+}
+
+// checkConfig reports whether the configuration loaded, printing a summary with secrets
+// redacted. It returns the process exit code for -check-config.
+func checkConfig(w io.Writer, cfg *config.Config, err error) int {
+	if err != nil {
+		fmt.Fprintf(w, "configuration invalid: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(w, cfg)
+	fmt.Fprintln(w, "configuration OK")
+	return 0
+}
+
+// configReloader serves POST /admin/reload and SIGHUP. running is the configuration the
+// server is using: reloaded settings are updated in it, while the rest keep their startup
+// values so a change to them is reported as needing a restart until the server is restarted.
+type configReloader struct {
+	load   func() (*config.Config, error)
+	level  *slog.LevelVar
+	live   *handlers.LiveSettings
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	running config.Config
+}
+
+// Reload loads the configuration again and applies the log level, color presets and
+// command gap
+func (c *configReloader) Reload() (reloaded, restartRequired []string, err error) {
+	next, err := c.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The presets file is read again even if its path is unchanged, to pick up edits
+	colorTable, err := colors.Load(next.ColorOverrides, next.ColorsFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load color presets: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, change := range c.running.Changes(next) {
+		c.logger.Info("Config setting changed", "setting", change.Key, "old", change.Old, "new", change.New, "reloaded", change.Reloadable)
+		if change.Reloadable {
+			reloaded = append(reloaded, change.Key)
+		} else {
+			restartRequired = append(restartRequired, change.Key)
+		}
+	}
+	c.level.Set(next.LogLevel)
+	c.live.Store(handlers.Settings{Colors: colorTable, DeviceOpDelay: next.DeviceOpDelay})
+	c.running.LogLevel = next.LogLevel
+	c.running.DeviceOpDelay = next.DeviceOpDelay
+	c.running.ColorOverrides = next.ColorOverrides
+	c.running.ColorsFile = next.ColorsFile
+	return reloaded, restartRequired, nil
+}
+
+// reloadOnHangup reloads the configuration each time a signal arrives on hangup, until
+// ctx is cancelled. main feeds it SIGHUP, the usual way to tell a daemon to re-read its config.
+func reloadOnHangup(ctx context.Context, hangup <-chan os.Signal, reloader handlers.Reloader, logger *slog.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			reloaded, restartRequired, err := reloader.Reload()
+			if err != nil {
+				logger.Error("Failed to reload config on SIGHUP", "error", err)
+				continue
+			}
+			logger.Info("Reloaded config on SIGHUP", "reloaded", reloaded, "restartRequired", restartRequired)
+			if len(restartRequired) > 0 {
+				logger.Warn("Changed settings need a restart to take effect", "settings", restartRequired)
+			}
+		}
+	}
+}
+
 func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level:     slog.LevelInfo,
-		AddSource: true,
-	}))
+	checkConfigOnly := flag.Bool("check-config", false, "validate the configuration, print a summary and exit")
+	flag.Parse()
+
+	// Log to stdout until the config says where logs should go
+	logger, _ := logging.New(logging.Options{Output: logging.OutputStdout})
 
 	cfg, err := config.Load()
+	if *checkConfigOnly {
+		os.Exit(checkConfig(os.Stdout, cfg, err))
+	}
 	if err != nil {
 		logger.Error("Failed to load config", "error", err)
 		os.Exit(1)
 	}
 
+	// A LevelVar so POST /admin/reload can change the level of the running logger
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel)
+	configuredLogger, err := logging.New(logging.Options{
+		Output:     cfg.LogOutput,
+		File:       cfg.LogFile,
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxBackups: cfg.LogMaxBackups,
+		MaxAgeDays: cfg.LogMaxAgeDays,
+		Level:      logLevel,
+	})
+	if err != nil {
+		logger.Error("Failed to configure logging", "error", err)
+		os.Exit(1)
+	}
+	logger = configuredLogger
+	logger.Info("Starting lights-http", "version", version.Version, "commit", version.Commit, "buildDate", version.BuildDate, "goVersion", runtime.Version())
+	// Every setting as parsed, defaults included, so a variable that didn't take effect shows up
+	logger.Info("Effective configuration", "config", cfg)
+
+	// Before anything records a metric, which would be lost when the collectors are rebuilt
+	var constLabels prometheus.Labels
+	if cfg.InstanceName != "" {
+		constLabels = prometheus.Labels{metrics.InstanceLabel: cfg.InstanceName}
+	}
+	metrics.Init(cfg.MetricsNamespace, constLabels)
+	metrics.SetBuildInfo(version.Version, version.Commit)
+
+	colorTable, err := colors.Load(cfg.ColorOverrides, cfg.ColorsFile)
+	if err != nil {
+		logger.Error("Failed to load color presets", "error", err)
+		os.Exit(1)
+	}
+
+	var defaultState controller.Operation
+	if cfg.DefaultState != "" {
+		defaultState, err = controller.ParseDefaultState(cfg.DefaultState, colorTable)
+		if err != nil {
+			logger.Error("Invalid default state", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	groupRegistry, err := groups.Load(cfg.Groups)
+	if err != nil {
+		logger.Error("Failed to load groups", "error", err)
+		os.Exit(1)
+	}
+
+	deviceAliases, err := controller.ParseAliases(cfg.DeviceAliases)
+	if err != nil {
+		logger.Error("Failed to load device aliases", "error", err)
+		os.Exit(1)
+	}
+
 	goveeController := controller.NewGoveeController(logger)
+	// DISABLED_DEVICES may name devices by alias too
+	disabledIDs := make([]string, 0, len(cfg.DisabledDevices))
+	for _, name := range cfg.DisabledDevices {
+		disabledIDs = append(disabledIDs, deviceAliases.Resolve(name))
+	}
+	disabledDevices := controller.NewDisabledDevices(disabledIDs)
 
+	startCtx, stopStarting := context.WithCancel(context.Background())
 	go func() {
-		err := goveeController.Start()
+		err := goveeController.StartWithRetry(startCtx, cfg.ControllerStartAttempts, cfg.ControllerStartBackoff)
 		if err != nil {
-			logger.Error("Failed to start controller", "error", err)
+			logger.Error("Failed to start controller", "attempts", cfg.ControllerStartAttempts, "error", err)
 		}
 	}()
 
@@ -66,65 +349,328 @@ func main() {
 		}
 		logger.Info("Controller shutdown complete")
 	}()
+	// Stop retrying before the controller is shut down
+	defer stopStarting()
+
+	// Colors and the gap between commands can be swapped by POST /admin/reload
+	liveSettings := handlers.NewLiveSettings(handlers.Settings{Colors: colorTable, DeviceOpDelay: cfg.DeviceOpDelay})
+	// Checked by the device queue, so a device that keeps failing stops getting commands
+	// whichever way they arrive
+	breakers := controller.NewBreakers(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	// Shared by the HTTP handlers, the MQTT bridge and the startup and shutdown states, so
+	// only one command is sent at a time whichever way it arrives
+	deviceQueue := controller.NewDeviceQueue(func() time.Duration { return liveSettings.Load().DeviceOpDelay }, cfg.CoalesceCommands, breakers)
+	// Shared by the HTTP handlers and the MQTT bridge, so a light faded off over HTTP comes
+	// back at its old brightness however it is turned on
+	faded := controller.NewFadedBrightness()
+
+	pollCtx, stopPolling := context.WithCancel(context.Background())
+	defer stopPolling()
+	// Always wait out discovery so the device count gauge is set, even without a default state
+	go goveeController.AfterDiscovery(pollCtx, controller.DefaultDiscoveryWait, func(devices []*govee.Device) {
+		if defaultState == nil {
+			return
+		}
+		devices = disabledDevices.Filter(devices)
+		logger.Info("Applying default state", "state", cfg.DefaultState, "devices", len(devices))
+		if err := controller.ApplyAll(deviceQueue, devices, defaultState, controller.DefaultStateTimeout); err != nil {
+			logger.Error("Failed to apply default state", "error", err)
+		}
+	})
+	if cfg.PollInterval > 0 {
+		poller := controller.NewPoller(goveeController.Controller, cfg.PollInterval, logger)
+		go poller.Run(pollCtx)
+	}
+
+	// Shared by the HTTP handlers and the MQTT bridge so a write through either one
+	// makes the next status read ask the devices again
+	statusCache := controller.NewStatusCache(cfg.StatusCacheTTL)
+
+	if cfg.MQTTBroker != "" {
+		bridge := mqtt.NewBridge(mqtt.Options{
+			Broker:      cfg.MQTTBroker,
+			ClientID:    cfg.MQTTClientID,
+			Username:    cfg.MQTTUsername,
+			Password:    cfg.MQTTPassword,
+			TopicPrefix: cfg.MQTTTopicPrefix,
+
+			Discovery:       cfg.HADiscovery,
+			DiscoveryPrefix: cfg.HADiscoveryPrefix,
+
+			StatusCache: statusCache,
+			Queue:       deviceQueue,
+			Faded:       faded,
+		}, disabledDevices.Enabled(goveeController.Controller), logger)
+		if err := bridge.Start(); err != nil {
+			logger.Error("Failed to start MQTT bridge", "broker", cfg.MQTTBroker, "error", err)
+		} else {
+			defer bridge.Stop()
+		}
+	}
+
+	notifier := notifications.New(cfg.WebhookURL, notifications.DefaultQueueSize, logger)
+	defer notifier.Close()
 
 	lightsHandler := &handlers.LightsHandler{
-		Controller: goveeController.Controller,
-		Logger:     logger,
+		Controller:     goveeController,
+		Logger:         logger,
+		StreamInterval: cfg.StreamInterval,
+		Notifier:       notifier,
+		Groups:         groupRegistry,
+		Effects:        effects.NewManager(),
+		History:        history.NewTracker(),
+		Snapshots:      snapshots.New(),
+
+		TransitionSteps: cfg.TransitionSteps,
+		PowerOnFadeMs:   cfg.PowerOnFadeMs,
+		Faded:           faded,
+		DeviceOpRetries: cfg.DeviceOpRetries,
+		DryRun:          cfg.DryRun,
+		RequireDevices:  !cfg.AllowNoDevices,
+		StatusCache:     statusCache,
+		Disabled:        disabledDevices,
+		Aliases:         deviceAliases,
+		Queue:           deviceQueue,
+		Breakers:        breakers,
+		Live:            liveSettings,
+	}
+
+	// Sample goroutine and effect counts so leaks show up on the metrics server
+	go metrics.RunSampler(pollCtx, metrics.DefaultSampleInterval, lightsHandler.Effects.Count)
+
+	scheduler, err := schedules.New(cfg.SchedulesFile, lightsHandler.RunSchedule, logger)
+	if err != nil {
+		logger.Error("Failed to load schedules", "error", err)
+		os.Exit(1)
+	}
+	lightsHandler.Schedules = scheduler
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	if cfg.Latitude != nil {
+		lightsHandler.Sun = solar.NewAuto(*cfg.Latitude, *cfg.Longitude, lightsHandler.RunSunEvent, logger)
+		if cfg.SunAuto {
+			lightsHandler.Sun.Enable()
+		}
+		defer lightsHandler.Sun.Disable()
 	}
 
+	startTime := time.Now()
+	metrics.SetStartTime(startTime)
 	healthHandler := &handlers.HealthHandler{
-		Controller: goveeController.Controller,
+		Controller: goveeController,
 		Logger:     logger,
-		StartTime:  time.Now(),
+		StartTime:  startTime,
+		Disabled:   disabledDevices,
+
+		WarmupTimeout: cfg.WarmupTimeout,
+		ProbeDevice:   (*govee.Device).RequestStatus,
+		Queue:         deviceQueue,
+		CacheTTL:      cfg.HealthCacheTTL,
+		Breakers:      breakers,
 	}
 
-	loggingMiddleware := &middleware.LoggingMiddleware{Logger: logger}
-	metricsMiddleware := &middleware.MetricsMiddleware{}
+	// Validate has already checked every entry
+	trustedProxies, _ := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	loggingMiddleware := &middleware.LoggingMiddleware{Logger: logger, SampleRate: cfg.LogSampleRate, ExcludedPaths: cfg.ExcludedPaths, TrustedProxies: trustedProxies}
+	metricsMiddleware := &middleware.MetricsMiddleware{ExcludedPaths: cfg.ExcludedPaths}
+	// Only logs anything at LOG_LEVEL=debug
+	bodyLogger := &middleware.BodyLogger{Logger: logger}
+
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(cfg.IdempotencyTTL, middleware.DefaultIdempotencyMaxEntries)
+
+	auth := &middleware.Auth{Token: cfg.BearerToken, Scopes: cfg.BearerTokenScopes, AllowBasic: cfg.AllowBasicAuth}
+	// authMiddleware requires a token granting scope; signed requests have full access
+	authMiddleware := func(scope string) func(http.Handler) http.Handler {
+		required := auth.Require(scope)
+		if cfg.HMACSecret != "" {
+			return middleware.SignatureAuthMiddleware(cfg.HMACSecret, required)
+		}
+		return required
+	}
+
+	// Shared by POST /admin/reload and SIGHUP
+	reloader := &configReloader{
+		load:    config.Load,
+		level:   logLevel,
+		live:    lightsHandler.Live,
+		logger:  logger,
+		running: *cfg,
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go reloadOnHangup(pollCtx, hangup, reloader, logger)
+
+	maintenance := &middleware.Maintenance{}
+	adminHandler := &handlers.AdminHandler{
+		Maintenance: maintenance,
+		Disabled:    disabledDevices,
+		Aliases:     deviceAliases,
+		Reloader:    reloader,
+		Logger:      logger,
+	}
+
+	// scopedRoute wraps an authenticated handler with auth for scope, logging, debug body
+	// logging, metrics and idempotency
+	scopedRoute := func(scope string, handler http.HandlerFunc) http.Handler {
+		return authMiddleware(scope)(loggingMiddleware.Middleware(bodyLogger.Middleware(metricsMiddleware.Middleware(idempotencyMiddleware.Middleware(handler)))))
+	}
+	// apiRoute needs the write scope; readRoute is for endpoints that only report state
+	apiRoute := func(handler http.HandlerFunc) http.Handler {
+		return scopedRoute(middleware.ScopeWrite, handler)
+	}
+	readRoute := func(handler http.HandlerFunc) http.Handler {
+		return scopedRoute(middleware.ScopeRead, handler)
+	}
+	// lightsRoute and lightsReadRoute are rejected while maintenance mode is on
+	lightsRoute := func(handler http.HandlerFunc) http.Handler {
+		return apiRoute(maintenance.Middleware(handler).ServeHTTP)
+	}
+	lightsReadRoute := func(handler http.HandlerFunc) http.Handler {
+		return readRoute(maintenance.Middleware(handler).ServeHTTP)
+	}
 
 	// API server mux (with auth and metrics middleware)
 	apiMux := http.NewServeMux()
-	apiMux.Handle("/health", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(healthHandler.Health))))
-	apiMux.Handle("/ready", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(healthHandler.Health))))
-	apiMux.Handle("/live", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(healthHandler.Health))))
-	apiMux.Handle("/lights/on", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.TurnOn)))))
-	apiMux.Handle("/lights/off", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.TurnOff)))))
-	apiMux.Handle("/lights/red", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.Red)))))
-	apiMux.Handle("/lights/yellow", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.Yellow)))))
-	apiMux.Handle("/lights/orange", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.Orange)))))
-	apiMux.Handle("/lights/dark-red", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.DarkRed)))))
-	apiMux.Handle("/lights/rgb", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.RGB)))))
-	apiMux.Handle("/lights/colortemp", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.ColorTemp)))))
-	apiMux.Handle("/lights/brightness", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.Brightness)))))
-	apiMux.Handle("/lights/status", middleware.AuthMiddleware(cfg.BearerToken)(loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(lightsHandler.Status)))))
-
-	// Metrics server mux (no auth, separate port)
+	apiMux.Handle("GET /{$}", loggingMiddleware.Middleware(metricsMiddleware.Middleware(openapi.IndexHandler(cfg.BasePath))))
+	apiMux.Handle("GET /health", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(healthHandler.Health))))
+	apiMux.Handle("GET /ready", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(healthHandler.Ready))))
+	apiMux.Handle("GET /live", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(healthHandler.Live))))
+	apiMux.Handle("GET /version", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(version.Handler))))
+	apiMux.Handle("GET /openapi.json", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(openapi.SpecHandler))))
+	apiMux.Handle("GET /docs", loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(openapi.DocsHandler))))
+	apiMux.Handle("POST /lights/on", lightsRoute(lightsHandler.TurnOn))
+	apiMux.Handle("POST /lights/off", lightsRoute(lightsHandler.TurnOff))
+	apiMux.Handle("POST /lights/power", lightsRoute(lightsHandler.Power))
+	apiMux.Handle("POST /lights/red", lightsRoute(lightsHandler.Red))
+	apiMux.Handle("POST /lights/yellow", lightsRoute(lightsHandler.Yellow))
+	apiMux.Handle("POST /lights/orange", lightsRoute(lightsHandler.Orange))
+	apiMux.Handle("POST /lights/dark-red", lightsRoute(lightsHandler.DarkRed))
+	apiMux.Handle("POST /lights/color/{name}", lightsRoute(lightsHandler.NamedColor))
+	apiMux.Handle("POST /lights/named", lightsRoute(lightsHandler.CSSColor))
+	apiMux.Handle("POST /lights/rgb", lightsRoute(lightsHandler.RGB))
+	apiMux.Handle("GET /lights/colortemp", lightsRoute(lightsHandler.ColorTemp))
+	apiMux.Handle("POST /lights/colortemp", lightsRoute(lightsHandler.ColorTemp))
+	apiMux.Handle("GET /lights/brightness", lightsRoute(lightsHandler.Brightness))
+	apiMux.Handle("POST /lights/brightness", lightsRoute(lightsHandler.Brightness))
+	apiMux.Handle("POST /lights/batch", lightsRoute(lightsHandler.Batch))
+	apiMux.Handle("PATCH /lights", lightsRoute(lightsHandler.Patch))
+	apiMux.Handle("POST /lights/identify", lightsRoute(lightsHandler.Identify))
+	apiMux.Handle("POST /lights/off-all-except", lightsRoute(lightsHandler.OffAllExcept))
+	apiMux.Handle("GET /lights/status", lightsReadRoute(lightsHandler.Status))
+	apiMux.Handle("GET /lights/summary", lightsReadRoute(lightsHandler.Summary))
+	apiMux.Handle("GET /lights/last", lightsReadRoute(lightsHandler.Last))
+	apiMux.Handle("GET /lights/groups", lightsReadRoute(lightsHandler.ListGroups))
+	apiMux.Handle("GET /lights/groups/{name}", lightsReadRoute(lightsHandler.GetGroup))
+	apiMux.Handle("PUT /lights/groups/{name}", lightsRoute(lightsHandler.PutGroup))
+	apiMux.Handle("DELETE /lights/groups/{name}", lightsRoute(lightsHandler.DeleteGroup))
+	apiMux.Handle("POST /lights/snapshot", lightsRoute(lightsHandler.CreateSnapshot))
+	apiMux.Handle("POST /lights/restore", lightsRoute(lightsHandler.RestoreSnapshot))
+	apiMux.Handle("GET /lights/snapshots", lightsReadRoute(lightsHandler.ListSnapshots))
+	apiMux.Handle("DELETE /lights/snapshots/{name}", lightsRoute(lightsHandler.DeleteSnapshot))
+	apiMux.Handle("POST /lights/effect/breathe", lightsRoute(lightsHandler.Breathe))
+	apiMux.Handle("POST /lights/effect/wave", lightsRoute(lightsHandler.Wave))
+	apiMux.Handle("DELETE /lights/effect/{id}", lightsRoute(lightsHandler.StopEffect))
+	apiMux.Handle("GET /lights/effects", lightsReadRoute(lightsHandler.ListEffects))
+	apiMux.Handle("POST /schedules", apiRoute(lightsHandler.CreateSchedule))
+	apiMux.Handle("GET /schedules", readRoute(lightsHandler.ListSchedules))
+	apiMux.Handle("DELETE /schedules/{id}", apiRoute(lightsHandler.DeleteSchedule))
+	apiMux.Handle("GET /sun", readRoute(lightsHandler.SunMode))
+	apiMux.Handle("PUT /sun", apiRoute(lightsHandler.SetSunMode))
+	apiMux.Handle("POST /admin/maintenance", apiRoute(adminHandler.SetMaintenance))
+	apiMux.Handle("POST /admin/reload", apiRoute(adminHandler.Reload))
+	apiMux.Handle("GET /admin/devices/disabled", readRoute(adminHandler.ListDisabled))
+	apiMux.Handle("PUT /admin/devices/{id}/disabled", apiRoute(adminHandler.DisableDevice))
+	apiMux.Handle("DELETE /admin/devices/{id}/disabled", apiRoute(adminHandler.EnableDevice))
+	apiMux.Handle("GET /lights/stream", lightsReadRoute(lightsHandler.Stream))
+	apiMux.Handle("GET /lights/events", lightsReadRoute(lightsHandler.Events))
+	// OPTIONS on a light route describes its methods and body from the spec, which is
+	// public anyway, so it needs no token
+	for _, path := range openapi.Paths("/lights") {
+		apiMux.Handle(http.MethodOptions+" "+path, loggingMiddleware.Middleware(metricsMiddleware.Middleware(openapi.DescribeHandler(path))))
+	}
+
+	// Metrics server mux (separate port, bearer auth only when METRICS_BEARER_TOKEN is set)
 	metricsMux := http.NewServeMux()
-	metricsMux.Handle("/metrics", promhttp.Handler())
+	metricsAuth := func(next http.Handler) http.Handler { return next }
+	if cfg.MetricsBearerToken != "" {
+		metricsAuth = middleware.AuthMiddleware(cfg.MetricsBearerToken, false)
+	}
+	metricsMux.Handle("/metrics", metricsAuth(metrics.Handler()))
+	if cfg.EnablePprof {
+		// Profiles expose internals, so they stay off the public API port
+		registerPprof(metricsMux, metricsAuth)
+		logger.Info("Serving pprof on the metrics server", "path", "/debug/pprof/")
+	}
 
-	// Custom handler to redirect 404 and 401 to xkcd
-	apiHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		srw := &statusRecorder{ResponseWriter: w, status: 200}
-		apiMux.ServeHTTP(srw, r)
-		if srw.status == 404 || srw.status == 401 {
-			http.Redirect(w, r, "https://xkcd.com/random/", http.StatusFound)
-		}
-	})
+	var rootPaths []string
+	if cfg.BasePathExemptHealth {
+		rootPaths = []string{"/health", "/ready", "/live"}
+	}
+	apiHandler := notFoundHandler(withBasePath(cfg.BasePath, apiMux, rootPaths...), cfg.NotFoundRedirectURL)
 
 	// Start metrics server in background
-	metricsAddr := fmt.Sprintf("%s:%s", cfg.Host, cfg.MetricsPort)
+	metricsAddr := fmt.Sprintf("%s:%s", cfg.MetricsHost, cfg.MetricsPort)
+	metricsServer := newServer(metricsAddr, metricsMux, cfg)
 	go func() {
 		logger.Info("Starting metrics server", "addr", metricsAddr)
-		if err := http.ListenAndServe(metricsAddr, metricsMux); err != nil {
+		if err := metricsServer.ListenAndServe(); err != nil {
 			logger.Error("Metrics server failed", "error", err)
 			os.Exit(1)
 		}
 	}()
 
 	// Start main API server
-	apiAddr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-	logger.Info("Starting API server", "addr", apiAddr, "metrics_addr", metricsAddr)
-	if err := http.ListenAndServe(apiAddr, apiHandler); err != nil {
+	apiServer := newServer(fmt.Sprintf("%s:%s", cfg.Host, cfg.Port), apiHandler, cfg)
+	serve := apiServer.ListenAndServe
+	if len(cfg.ACMEDomains) > 0 {
+		// Certificates are obtained on first request via the TLS-ALPN-01 challenge,
+		// so the API must be reachable on port 443 for each domain.
+		certManager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.ACMEDomains...),
+			Cache:      autocert.DirCache(cfg.ACMECacheDir),
+			Email:      cfg.ACMEEmail,
+		}
+		apiServer.TLSConfig = certManager.TLSConfig()
+		apiServer.TLSConfig.MinVersion = tls.VersionTLS12
+		logger.Info("Starting API server", "addr", apiServer.Addr, "metrics_addr", metricsAddr, "tls", true, "acme_domains", cfg.ACMEDomains)
+		serve = func() error { return apiServer.ListenAndServeTLS("", "") }
+	} else if cfg.TLSCertFile != "" {
+		apiServer.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		logger.Info("Starting API server", "addr", apiServer.Addr, "metrics_addr", metricsAddr, "tls", true)
+		serve = func() error { return apiServer.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile) }
+	} else {
+		logger.Info("Starting API server", "addr", apiServer.Addr, "metrics_addr", metricsAddr)
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	select {
+	case err := <-serveErr:
 		logger.Error("API server failed", "error", err)
 		os.Exit(1)
+	case <-signalCtx.Done():
+	}
+
+	// Stop taking requests, then let the deferred cleanup shut the controller down
+	logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), controller.DefaultShutdownTimeout)
+	defer cancel()
+	if err := apiServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to shut down API server", "error", err)
+	}
+
+	if cfg.TurnOffOnShutdown {
+		logger.Info("Turning lights off before exit")
+		if err := controller.ApplyAll(deviceQueue, disabledDevices.Filter(goveeController.Controller.Devices()), controller.TurnOff(), controller.DefaultShutdownTimeout); err != nil {
+			logger.Error("Failed to turn off every light", "error", err)
+		}
 	}
 }
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/config"
+	"github.com/jwhitcraft/lights-http/handlers"
+	"github.com/jwhitcraft/lights-http/middleware"
+	"github.com/jwhitcraft/lights-http/openapi"
+)
+
+func TestNotFoundHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/ok", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	mux.Handle("/secure", middleware.AuthMiddleware("secret", false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	})))
+	mux.Handle("POST /lights/on", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("on"))
+	}))
+	mux.Handle("/json-404", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"unknown_color"}}`))
+	}))
+
+	const target = "https://example.com/lost"
+
+	tests := []struct {
+		name             string
+		path             string
+		accept           string
+		expectedStatus   int
+		expectedLocation string
+		expectedError    string
+	}{
+		{"success passes through", "/ok", "", http.StatusOK, "", ""},
+		{"unknown route from browser redirects", "/missing", "text/html,application/xhtml+xml", http.StatusFound, target, ""},
+		{"unknown route from API client returns JSON", "/missing", "application/json", http.StatusNotFound, "", "not_found"},
+		{"unknown route without Accept returns JSON", "/missing", "", http.StatusNotFound, "", "not_found"},
+		{"handler JSON 404 passes through", "/json-404", "text/html", http.StatusNotFound, "", ""},
+		{"unauthorized is never redirected", "/secure", "text/html", http.StatusUnauthorized, "", "unauthorized"},
+		{"wrong method returns JSON 405", "/lights/on", "text/html", http.StatusMethodNotAllowed, "", "method_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			notFoundHandler(mux, target).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tt.expectedLocation {
+				t.Errorf("expected Location %q, got %q", tt.expectedLocation, location)
+			}
+			if tt.expectedError != "" {
+				if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected Content-Type application/json, got %s", ct)
+				}
+				var body map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if body["error"] != tt.expectedError {
+					t.Errorf("expected error %q, got %q", tt.expectedError, body["error"])
+				}
+			}
+			if tt.expectedStatus == http.StatusMethodNotAllowed {
+				if allow := w.Header().Get("Allow"); allow != "POST" {
+					t.Errorf("expected Allow POST, got %q", allow)
+				}
+			}
+		})
+	}
+}
+
+// TestOpenAPISpecCoversRoutes keeps openapi.json in sync with the routes registered in main
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	source, err := os.ReadFile("main.go")
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+
+	routePattern := regexp.MustCompile(`apiMux\.Handle\("(?:([A-Z]+) )?([^"]+)"`)
+	routes := make(map[string]bool)
+	for _, match := range routePattern.FindAllStringSubmatch(string(source), -1) {
+		// "/{$}" only matches the root path exactly
+		path := strings.TrimSuffix(match[2], "{$}")
+		if path == "/openapi.json" || path == "/docs" {
+			continue
+		}
+		routes[path] = true
+	}
+
+	var doc struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+
+	var missing, extra []string
+	for path := range routes {
+		if _, ok := doc.Paths[path]; !ok {
+			missing = append(missing, path)
+		}
+	}
+	for path := range doc.Paths {
+		if !routes[path] {
+			extra = append(extra, path)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from openapi.json: %s", strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		t.Errorf("openapi.json documents unregistered routes: %s", strings.Join(extra, ", "))
+	}
+}
+
+func TestWithBasePath(t *testing.T) {
+	mux := http.NewServeMux()
+	for _, path := range []string{"/health", "/lights/on"} {
+		mux.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.Path))
+		}))
+	}
+
+	tests := []struct {
+		name           string
+		basePath       string
+		rootPaths      []string
+		path           string
+		expectedStatus int
+		expectedPath   string
+	}{
+		{"no base path", "", nil, "/lights/on", http.StatusOK, "/lights/on"},
+		{"prefixed route", "/api/v1", nil, "/api/v1/lights/on", http.StatusOK, "/lights/on"},
+		{"unprefixed route is not found", "/api/v1", nil, "/lights/on", http.StatusNotFound, ""},
+		{"prefixed health", "/api/v1", nil, "/api/v1/health", http.StatusOK, "/health"},
+		{"exempt health at the root", "/api/v1", []string{"/health"}, "/health", http.StatusOK, "/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			notFoundHandler(withBasePath(tt.basePath, mux, tt.rootPaths...), "https://example.com/lost").ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedPath != "" && w.Body.String() != tt.expectedPath {
+				t.Errorf("expected handler to see %q, got %q", tt.expectedPath, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegisterPprof(t *testing.T) {
+	mux := http.NewServeMux()
+	registerPprof(mux, middleware.AuthMiddleware("metrics-token", false))
+
+	req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without the metrics token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/debug/pprof/", nil)
+	req.Header.Set("Authorization", "Bearer metrics-token")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 with the metrics token, got %d", w.Code)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	var out strings.Builder
+	cfg := &config.Config{Host: "0.0.0.0", Port: "8080", BearerToken: "super-secret", LogOutput: "stdout"}
+	if code := checkConfig(&out, cfg, nil); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if strings.Contains(out.String(), "super-secret") {
+		t.Errorf("summary leaked the bearer token:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "configuration OK") {
+		t.Errorf("summary missing OK line:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := checkConfig(&out, nil, errors.New("PORT must be set")); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(out.String(), "PORT must be set") {
+		t.Errorf("expected error in output, got %q", out.String())
+	}
+}
+
+func TestConfigReloader(t *testing.T) {
+	running := config.Config{Port: "8080", LogLevel: slog.LevelInfo, DeviceOpDelay: 100 * time.Millisecond}
+	next := running
+	next.Port = "9090"
+	next.LogLevel = slog.LevelDebug
+	next.DeviceOpDelay = 0
+	next.ColorOverrides = "teal=0,128,128"
+
+	level := new(slog.LevelVar)
+	reloader := &configReloader{
+		load:    func() (*config.Config, error) { c := next; return &c, nil },
+		level:   level,
+		live:    &handlers.LiveSettings{},
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		running: running,
+	}
+
+	reloaded, restartRequired, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"device_op_delay", "log_level", "color_overrides"}; !slices.Equal(reloaded, want) {
+		t.Errorf("reloaded = %v, want %v", reloaded, want)
+	}
+	if want := []string{"port"}; !slices.Equal(restartRequired, want) {
+		t.Errorf("restartRequired = %v, want %v", restartRequired, want)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("expected the log level to change to debug, got %s", level.Level())
+	}
+	live := reloader.live.Load()
+	if live == nil || live.DeviceOpDelay != 0 {
+		t.Fatalf("expected the new delay to be stored, got %+v", live)
+	}
+	if _, ok := live.Colors.Lookup("teal"); !ok {
+		t.Error("expected the reloaded color override")
+	}
+
+	// Reloading again still reports the port, which only a restart applies
+	reloaded, restartRequired, _ = reloader.Reload()
+	if len(reloaded) != 0 || !slices.Equal(restartRequired, []string{"port"}) {
+		t.Errorf("second reload = %v, %v; want no reloads and port", reloaded, restartRequired)
+	}
+
+	reloader.load = func() (*config.Config, error) { return nil, errors.New("BEARER_TOKEN is required") }
+	if _, _, err := reloader.Reload(); err == nil {
+		t.Error("expected the load error")
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Error("expected a failed reload to keep the running settings")
+	}
+}
+
+// reloadCounter reports each reload on the channel
+type reloadCounter chan struct{}
+
+func (c reloadCounter) Reload() ([]string, []string, error) {
+	c <- struct{}{}
+	return nil, nil, nil
+}
+
+func TestReloadOnHangup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	hangup := make(chan os.Signal, 1)
+	reloads := make(reloadCounter, 1)
+	done := make(chan struct{})
+	go func() {
+		reloadOnHangup(ctx, hangup, reloads, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		close(done)
+	}()
+
+	for range 2 {
+		hangup <- syscall.SIGHUP
+		select {
+		case <-reloads:
+		case <-time.After(time.Second):
+			t.Fatal("expected SIGHUP to trigger a reload")
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected reloadOnHangup to return once ctx is cancelled")
+	}
+}
@@ -1,44 +1,327 @@
+// Package metrics defines the Prometheus collectors exported by the server.
+//
+// Most collectors use low-cardinality labels. LightDeviceOperationsTotal is the
+// exception, along with the device gauges: they carry a device label (the Govee
+// DeviceID), so their series count grows with the number of fixtures. That is fine
+// for a household-sized setup, but avoid adding further per-device labels to them.
 package metrics
 
 import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	govee "github.com/swrm-io/go-vee"
 )
 
 var (
 	// HTTPRequestsTotal counts total HTTP requests
-	HTTPRequestsTotal = promauto.NewCounterVec(
+	HTTPRequestsTotal *prometheus.CounterVec
+
+	// HTTPRequestDuration measures HTTP request duration
+	HTTPRequestDuration *prometheus.HistogramVec
+
+	// HTTPResponseBytes measures HTTP response body sizes
+	HTTPResponseBytes *prometheus.HistogramVec
+
+	// LightOperationsTotal counts light control operations; color is the preset name or
+	// "rgb" for color operations and empty otherwise
+	LightOperationsTotal *prometheus.CounterVec
+
+	// LightDeviceOperationsTotal counts light control operations per device
+	LightDeviceOperationsTotal *prometheus.CounterVec
+
+	// LightDeviceOperationRetriesTotal counts retried light control operations per device
+	LightDeviceOperationRetriesTotal *prometheus.CounterVec
+
+	// DeviceBrightness reports the last known brightness (0-100) of each device
+	DeviceBrightness *prometheus.GaugeVec
+
+	// DevicePower reports whether each device was last reported on (1) or off (0)
+	DevicePower *prometheus.GaugeVec
+
+	// DeviceBreakerState reports each device's circuit breaker: 0 closed, 1 half-open, 2 open
+	DeviceBreakerState *prometheus.GaugeVec
+
+	// BuildInfo is always 1 and carries the running build's version details as labels
+	BuildInfo *prometheus.GaugeVec
+
+	// ActiveConnections tracks current active connections
+	ActiveConnections prometheus.Gauge
+
+	// ActiveEffects reports how many light effects are running, updated by RunSampler
+	ActiveEffects prometheus.Gauge
+
+	// DevicesTotal reports how many devices have been discovered, set by SetDeviceCount
+	DevicesTotal prometheus.Gauge
+
+	// StreamSubscribers tracks open status streams; transport is "websocket" or "sse"
+	StreamSubscribers *prometheus.GaugeVec
+
+	// Goroutines reports runtime.NumGoroutine, updated by RunSampler
+	Goroutines prometheus.Gauge
+
+	// StartTime is the Unix time the server started, set by SetStartTime
+	StartTime prometheus.Gauge
+
+	// Uptime reports seconds since the time passed to SetStartTime, computed at scrape time
+	Uptime prometheus.GaugeFunc
+)
+
+// DefaultNamespace prefixes every metric name unless Init is given another
+const DefaultNamespace = "lights"
+
+// InstanceLabel names the const label identifying a deployment. It isn't "instance",
+// which Prometheus sets to the scrape target and would rename ours to exported_instance.
+const InstanceLabel = "instance_name"
+
+// registry holds the collectors built by the last Init. Each Init starts a new one: the
+// default registry remembers a metric's label names even after it is unregistered, so it
+// would refuse the same metric rebuilt with const labels.
+var registry *prometheus.Registry
+
+// labeled registers into registry with Init's const labels, for collectors built elsewhere
+var labeled prometheus.Registerer
+
+func init() {
+	Init(DefaultNamespace, nil)
+}
+
+// Init builds every collector under namespace, e.g. "lights" for lights_http_requests_total,
+// with constLabels on every series, including the Go runtime and process metrics, and
+// registers them in place of any built before. The package starts out with
+// DefaultNamespace and no labels; call Init once at startup, before anything records a
+// metric or calls Handler, since values recorded under the old collectors are dropped.
+func Init(namespace string, constLabels prometheus.Labels) {
+	registry = prometheus.NewRegistry()
+	labeled = prometheus.WrapRegistererWith(constLabels, registry)
+	labeled.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	HTTPRequestsTotal = register(prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "lights_http_requests_total",
-			Help: "Total number of HTTP requests",
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_requests_total",
+			Help:        "Total number of HTTP requests",
 		},
 		[]string{"method", "endpoint", "status"},
-	)
+	))
 
-	// HTTPRequestDuration measures HTTP request duration
-	HTTPRequestDuration = promauto.NewHistogramVec(
+	HTTPRequestDuration = register(prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "lights_http_request_duration_seconds",
-			Help:    "HTTP request duration in seconds",
-			Buckets: prometheus.DefBuckets,
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_request_duration_seconds",
+			Help:        "HTTP request duration in seconds",
+			Buckets:     prometheus.DefBuckets,
 		},
 		[]string{"method", "endpoint"},
-	)
+	))
 
-	// LightOperationsTotal counts light control operations
-	LightOperationsTotal = promauto.NewCounterVec(
+	HTTPResponseBytes = register(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_response_bytes",
+			Help:        "HTTP response body size in bytes",
+			Buckets:     prometheus.ExponentialBuckets(100, 10, 6),
+		},
+		[]string{"method", "endpoint"},
+	))
+
+	LightOperationsTotal = register(prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "lights_operations_total",
-			Help: "Total number of light control operations",
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "operations_total",
+			Help:        "Total number of light control operations",
 		},
-		[]string{"operation", "result"},
-	)
+		[]string{"operation", "result", "color"},
+	))
 
-	// ActiveConnections tracks current active connections
-	ActiveConnections = promauto.NewGauge(
+	LightDeviceOperationsTotal = register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "device_operations_total",
+			Help:        "Total number of light control operations per device",
+		},
+		[]string{"operation", "result", "device"},
+	))
+
+	LightDeviceOperationRetriesTotal = register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "device_operation_retries_total",
+			Help:        "Total number of retried light control operations per device",
+		},
+		[]string{"operation", "device"},
+	))
+
+	DeviceBrightness = register(prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "lights_http_active_connections",
-			Help: "Number of active HTTP connections",
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "device_brightness",
+			Help:        "Last reported brightness of each device (0-100)",
 		},
-	)
-)
+		[]string{"device"},
+	))
+
+	DevicePower = register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "device_power",
+			Help:        "Last reported power state of each device (1 = on, 0 = off)",
+		},
+		[]string{"device"},
+	))
+
+	DeviceBreakerState = register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "device_circuit_breaker_state",
+			Help:        "Circuit breaker state of each device (0 = closed, 1 = half-open, 2 = open)",
+		},
+		[]string{"device"},
+	))
+
+	BuildInfo = register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_build_info",
+			Help:        "Build information for the running binary (always 1)",
+		},
+		[]string{"version", "commit", "go_version"},
+	))
+
+	ActiveConnections = register(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_active_connections",
+			Help:        "Number of active HTTP connections",
+		},
+	))
+
+	ActiveEffects = register(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "active_effects",
+			Help:        "Number of running light effects",
+		},
+	))
+
+	DevicesTotal = register(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "devices_total",
+			Help:        "Number of discovered devices",
+		},
+	))
+
+	StreamSubscribers = register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "stream_subscribers",
+			Help:        "Number of open status streams",
+		},
+		[]string{"transport"},
+	))
+
+	Goroutines = register(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_goroutines",
+			Help:        "Number of goroutines, sampled periodically",
+		},
+	))
+
+	StartTime = register(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_start_time_seconds",
+			Help:        "Unix time the server started",
+		},
+	))
+
+	Uptime = register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        "http_uptime_seconds",
+			Help:        "Seconds since the server started",
+		},
+		func() float64 {
+			start, ok := startTime.Load().(time.Time)
+			if !ok {
+				return 0
+			}
+			return time.Since(start).Seconds()
+		},
+	))
+}
+
+// register registers c with the current registry and returns it
+func register[C prometheus.Collector](c C) C {
+	registry.MustRegister(c)
+	return c
+}
+
+// Gatherer returns the registry built by the last Init
+func Gatherer() prometheus.Gatherer {
+	return registry
+}
+
+// Handler serves the metrics built by the last Init in the Prometheus text format,
+// counting its own scrapes like promhttp.Handler does
+func Handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(labeled, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// startTime holds the time.Time passed to SetStartTime
+var startTime atomic.Value
+
+// SetDeviceState sets a device's brightness and power gauges
+func SetDeviceState(deviceID string, brightness govee.Brightness, on bool) {
+	DeviceBrightness.WithLabelValues(deviceID).Set(float64(brightness))
+	power := 0.0
+	if on {
+		power = 1
+	}
+	DevicePower.WithLabelValues(deviceID).Set(power)
+}
+
+// SetDeviceCount records how many devices the controller currently knows about.
+// Disabled devices still count; they are on the network, just out of service.
+func SetDeviceCount(n int) {
+	DevicesTotal.Set(float64(n))
+}
+
+// SetStartTime records when the server started, for the start time and uptime gauges.
+// Pass the same time the health handler reports uptime from.
+func SetStartTime(t time.Time) {
+	startTime.Store(t)
+	StartTime.Set(float64(t.Unix()))
+}
+
+// SetBuildInfo records the running build's version and commit. Call once at startup.
+func SetBuildInfo(version, commit string) {
+	BuildInfo.WithLabelValues(version, commit, runtime.Version()).Set(1)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// registeredNames returns the names of the metrics in the current registry
+func registeredNames(t *testing.T) map[string]bool {
+	t.Helper()
+	families, err := Gatherer().Gather()
+	if err != nil {
+		t.Fatalf("gathering metrics: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	return names
+}
+
+func TestInitNamespace(t *testing.T) {
+	t.Cleanup(func() { Init(DefaultNamespace, nil) })
+
+	HTTPRequestsTotal.WithLabelValues("GET", "/health", "200").Inc()
+	if names := registeredNames(t); !names["lights_http_requests_total"] || !names["lights_devices_total"] {
+		t.Fatalf("expected the default lights_ prefix, got %v", names)
+	}
+
+	Init("home", nil)
+	HTTPRequestsTotal.WithLabelValues("GET", "/health", "200").Inc()
+	names := registeredNames(t)
+	for _, name := range []string{"home_http_requests_total", "home_devices_total", "home_http_uptime_seconds"} {
+		if !names[name] {
+			t.Errorf("expected %s to be registered, got %v", name, names)
+		}
+	}
+	// The collectors built under the old namespace are gone rather than duplicated
+	for _, name := range []string{"lights_http_requests_total", "lights_devices_total"} {
+		if names[name] {
+			t.Errorf("expected %s to be unregistered", name)
+		}
+	}
+}
+
+func TestInitConstLabels(t *testing.T) {
+	t.Cleanup(func() { Init(DefaultNamespace, nil) })
+
+	Init(DefaultNamespace, prometheus.Labels{InstanceLabel: "living-room"})
+	HTTPRequestsTotal.WithLabelValues("GET", "/health", "200").Inc()
+	SetDeviceCount(3)
+
+	families, err := Gatherer().Gather()
+	if err != nil {
+		t.Fatalf("gathering metrics: %v", err)
+	}
+	seen := 0
+	for _, family := range families {
+		switch family.GetName() {
+		case "lights_http_requests_total", "lights_devices_total":
+		default:
+			continue
+		}
+		seen++
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, pair := range m.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			if labels[InstanceLabel] != "living-room" {
+				t.Errorf("%s: expected %s=living-room, got %v", family.GetName(), InstanceLabel, labels)
+			}
+		}
+	}
+	if seen != 2 {
+		t.Errorf("expected both metrics to be gathered, saw %d", seen)
+	}
+	// Series are still addressed by their own labels only
+	if got := testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues("GET", "/health", "200")); got != 1 {
+		t.Errorf("expected 1 request, got %v", got)
+	}
+}
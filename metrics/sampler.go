@@ -0,0 +1,47 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// DefaultSampleInterval is how often RunSampler updates the gauges
+const DefaultSampleInterval = 15 * time.Second
+
+// RunSampler updates the goroutine gauge, and the active effects gauge from activeEffects,
+// every interval until ctx is cancelled. Rising values that never come back down point to
+// leaked effect or stream goroutines.
+func RunSampler(ctx context.Context, interval time.Duration, activeEffects func() int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sample(activeEffects)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample sets the sampled gauges once
+func sample(activeEffects func() int) {
+	Goroutines.Set(float64(runtime.NumGoroutine()))
+	ActiveEffects.Set(float64(activeEffects()))
+}
@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSample(t *testing.T) {
+	sample(func() int { return 3 })
+
+	if got := testutil.ToFloat64(ActiveEffects); got != 3 {
+		t.Errorf("expected 3 active effects, got %v", got)
+	}
+	if got := testutil.ToFloat64(Goroutines); got < 1 {
+		t.Errorf("expected at least one goroutine, got %v", got)
+	}
+}
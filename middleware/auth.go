@@ -15,20 +15,119 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"net/http"
 	"strings"
 )
 
-// AuthMiddleware enforces Bearer token authentication
-func AuthMiddleware(token string) func(http.Handler) http.Handler {
+// basicRealm is the realm advertised in Basic auth challenges
+const basicRealm = "lights-http"
+
+// Scopes a token can be limited to. ScopeWrite includes ScopeRead, so a token that can
+// control the lights can also see their state.
+const (
+	// ScopeRead allows status reads and other GET endpoints that don't change anything
+	ScopeRead = "read"
+	// ScopeWrite allows every endpoint
+	ScopeWrite = "write"
+)
+
+// Auth authenticates requests by bearer token. Token grants every scope; each token in
+// Scopes grants only the scope it maps to.
+// WebSocket upgrade requests may pass the token as a "token" query parameter instead,
+// since browsers cannot set headers on WebSocket connections.
+// When AllowBasic is set, HTTP Basic credentials are accepted too: any username, with the
+// token as the password.
+type Auth struct {
+	Token      string
+	Scopes     map[string]string
+	AllowBasic bool
+}
+
+// AuthMiddleware enforces Bearer token authentication with a single full-access token
+func AuthMiddleware(token string, allowBasic bool) func(http.Handler) http.Handler {
+	return (&Auth{Token: token, AllowBasic: allowBasic}).Require(ScopeWrite)
+}
+
+// Require returns middleware that admits requests whose token grants scope. A missing or
+// unknown token gets a 401; a valid token without the scope gets a 403.
+func (a *Auth) Require(scope string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
-			if !strings.HasPrefix(header, "Bearer ") || strings.TrimPrefix(header, "Bearer ") != token {
-				http.Error(w, "Unauthorized: missing or invalid token", http.StatusUnauthorized)
+			if header == "" && isWebSocketUpgrade(r) {
+				if queryToken := r.URL.Query().Get("token"); queryToken != "" {
+					header = "Bearer " + queryToken
+				}
+			}
+
+			granted, authorized := "", false
+			if bearer, ok := strings.CutPrefix(header, "Bearer "); ok {
+				granted, authorized = a.scopeOf(bearer)
+			} else if _, password, ok := r.BasicAuth(); ok && a.AllowBasic {
+				granted, authorized = a.scopeOf(password)
+			}
+			if !authorized {
+				writeUnauthorized(w, a.AllowBasic)
+				return
+			}
+			if !scopeAllows(granted, scope) {
+				writeInsufficientScope(w, scope)
 				return
 			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// scopeOf returns the scope granted to a presented token, and whether it is known at all.
+// Every configured token is compared, so the time taken doesn't reveal which one matched.
+func (a *Auth) scopeOf(presented string) (string, bool) {
+	granted, authorized := "", false
+	if tokenMatches(presented, a.Token) {
+		granted, authorized = ScopeWrite, true
+	}
+	for token, scope := range a.Scopes {
+		if tokenMatches(presented, token) && !authorized {
+			granted, authorized = scope, true
+		}
+	}
+	return granted, authorized
+}
+
+// scopeAllows reports whether a token with the granted scope may use a route requiring scope
+func scopeAllows(granted, scope string) bool {
+	return granted == scope || granted == ScopeWrite
+}
+
+// tokenMatches compares a presented token against the configured one in constant time
+func tokenMatches(presented, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// writeUnauthorized rejects the request with a JSON 401 and a Bearer challenge, plus a
+// Basic challenge when Basic auth is allowed
+func writeUnauthorized(w http.ResponseWriter, allowBasic bool) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	if allowBasic {
+		w.Header().Add("WWW-Authenticate", `Basic realm="`+basicRealm+`"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+}
+
+// writeInsufficientScope rejects an authenticated request whose token lacks scope with a
+// JSON 403. There is no WWW-Authenticate challenge: other credentials are needed, not a
+// retry with the same ones, so the missing scope is named in the body instead.
+func writeInsufficientScope(w http.ResponseWriter, scope string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": "insufficient_scope", "scope": scope})
+}
+
+// isWebSocketUpgrade reports whether the request is asking to upgrade to a WebSocket
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,7 +9,7 @@ import (
 
 func TestAuthMiddleware(t *testing.T) {
 	token := "test-token"
-	middleware := AuthMiddleware(token)
+	middleware := AuthMiddleware(token, false)
 
 	tests := []struct {
 		name           string
@@ -54,6 +55,238 @@ func TestAuthMiddleware(t *testing.T) {
 			if w.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
 			}
+			if tt.expectedStatus == http.StatusUnauthorized {
+				if challenge := w.Header().Get("WWW-Authenticate"); challenge != "Bearer" {
+					t.Errorf("expected WWW-Authenticate Bearer, got %q", challenge)
+				}
+				if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected Content-Type application/json, got %s", ct)
+				}
+				var body map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if body["error"] != "unauthorized" {
+					t.Errorf("expected error unauthorized, got %q", body["error"])
+				}
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareWebSocketQueryToken(t *testing.T) {
+	middleware := AuthMiddleware("test-token", false)
+
+	tests := []struct {
+		name           string
+		url            string
+		upgrade        bool
+		expectedStatus int
+	}{
+		{
+			name:           "valid query token on upgrade",
+			url:            "/lights/stream?token=test-token",
+			upgrade:        true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid query token on upgrade",
+			url:            "/lights/stream?token=wrong-token",
+			upgrade:        true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "query token ignored without upgrade",
+			url:            "/lights/status?token=test-token",
+			upgrade:        false,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.upgrade {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			w := httptest.NewRecorder()
+
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareBasic(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowBasic     bool
+		username       string
+		password       string
+		expectedStatus int
+	}{
+		{
+			name:           "valid basic credentials",
+			allowBasic:     true,
+			username:       "home-assistant",
+			password:       "test-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid basic password",
+			allowBasic:     true,
+			username:       "home-assistant",
+			password:       "wrong-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "basic auth disabled",
+			allowBasic:     false,
+			username:       "home-assistant",
+			password:       "test-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.SetBasicAuth(tt.username, tt.password)
+			w := httptest.NewRecorder()
+
+			handler := AuthMiddleware("test-token", tt.allowBasic)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusUnauthorized {
+				challenges := w.Header().Values("WWW-Authenticate")
+				if tt.allowBasic && len(challenges) != 2 {
+					t.Errorf("expected Bearer and Basic challenges, got %v", challenges)
+				}
+				if !tt.allowBasic && len(challenges) != 1 {
+					t.Errorf("expected only a Bearer challenge, got %v", challenges)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthScopes(t *testing.T) {
+	auth := &Auth{
+		Token:  "admin-token",
+		Scopes: map[string]string{"dashboard-token": ScopeRead, "switch-token": ScopeWrite},
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		scope          string
+		expectedStatus int
+	}{
+		{"full token reads", "admin-token", ScopeRead, http.StatusOK},
+		{"full token writes", "admin-token", ScopeWrite, http.StatusOK},
+		{"read token reads", "dashboard-token", ScopeRead, http.StatusOK},
+		{"read token writes", "dashboard-token", ScopeWrite, http.StatusForbidden},
+		{"write token reads", "switch-token", ScopeRead, http.StatusOK},
+		{"write token writes", "switch-token", ScopeWrite, http.StatusOK},
+		{"unknown token", "guess", ScopeRead, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/off", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			handler := auth.Require(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusForbidden {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body["error"] != "insufficient_scope" || body["scope"] != ScopeWrite {
+				t.Errorf("expected insufficient_scope naming write, got %v", body)
+			}
+		})
+	}
+}
+
+func TestAuthStatusCodes(t *testing.T) {
+	auth := &Auth{
+		Token:      "admin-token",
+		Scopes:     map[string]string{"dashboard-token": ScopeRead},
+		AllowBasic: true,
+	}
+
+	tests := []struct {
+		name           string
+		setAuth        func(r *http.Request)
+		expectedStatus int
+		expectedError  string
+	}{
+		{"missing credentials", func(r *http.Request) {}, http.StatusUnauthorized, "unauthorized"},
+		{"malformed header", func(r *http.Request) { r.Header.Set("Authorization", "Token admin-token") }, http.StatusUnauthorized, "unauthorized"},
+		{"unknown token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") }, http.StatusUnauthorized, "unauthorized"},
+		{"unknown basic password", func(r *http.Request) { r.SetBasicAuth("ha", "guess") }, http.StatusUnauthorized, "unauthorized"},
+		{"read token on a write route", func(r *http.Request) { r.Header.Set("Authorization", "Bearer dashboard-token") }, http.StatusForbidden, "insufficient_scope"},
+		{"read token over basic auth", func(r *http.Request) { r.SetBasicAuth("ha", "dashboard-token") }, http.StatusForbidden, "insufficient_scope"},
+		{"full token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") }, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/off", nil)
+			tt.setAuth(req)
+			w := httptest.NewRecorder()
+
+			handler := auth.Require(ScopeWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			// Only a 401 asks the client to authenticate
+			challenges := w.Header().Values("WWW-Authenticate")
+			if tt.expectedStatus == http.StatusUnauthorized && len(challenges) == 0 {
+				t.Error("expected a WWW-Authenticate challenge on 401")
+			}
+			if tt.expectedStatus != http.StatusUnauthorized && len(challenges) != 0 {
+				t.Errorf("expected no WWW-Authenticate challenge, got %v", challenges)
+			}
+			if tt.expectedError == "" {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body["error"] != tt.expectedError {
+				t.Errorf("expected error %q, got %q", tt.expectedError, body["error"])
+			}
 		})
 	}
 }
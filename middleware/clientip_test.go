@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.2", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(proxies) != 3 || proxies[1].String() != "192.168.1.2/32" {
+		t.Errorf("unexpected proxies %v", proxies)
+	}
+
+	if _, err := ParseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("expected an error for a hostname")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{"direct client", "203.0.113.7:51000", nil, "203.0.113.7"},
+		{"untrusted peer's header is ignored", "203.0.113.7:51000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"rightmost untrusted hop wins over a spoofed one", "10.0.0.1:443", []string{"6.6.6.6, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"header split across lines", "10.0.0.1:443", []string{"6.6.6.6", "198.51.100.1"}, "198.51.100.1"},
+		{"every hop trusted", "10.0.0.1:443", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"garbled hop", "10.0.0.1:443", []string{"198.51.100.1, not-an-ip, 10.0.0.2"}, "10.0.0.2"},
+		{"trusted proxy without a header", "10.0.0.1:443", nil, "10.0.0.1"},
+		{"IPv4-mapped peer", "[::ffff:10.0.0.1]:443", []string{"198.51.100.1"}, "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/lights/status", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := proxies.ClientIP(req); got != tt.expectedIP {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.expectedIP)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/lights/status", nil)
+	req.RemoteAddr = "10.0.0.1:443"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	if got := TrustedProxies(nil).ClientIP(req); got != "10.0.0.1" {
+		t.Errorf("expected X-Forwarded-For to be ignored, got %q", got)
+	}
+}
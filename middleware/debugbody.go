@@ -0,0 +1,130 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// DefaultDebugBodyLimit is how much of a request body BodyLogger logs
+const DefaultDebugBodyLimit = 4096
+
+// redactedValue replaces sensitive fields in logged bodies
+const redactedValue = "***"
+
+// sensitiveFields are JSON keys whose values are never logged; a key matches if it
+// contains any of them, ignoring case
+var sensitiveFields = []string{"password", "secret", "token", "authorization", "key"}
+
+// BodyLogger logs the JSON body of POST, PUT and PATCH requests at debug level, so client
+// integrations can be debugged against exactly what they sent. Sensitive fields are
+// redacted, bodies over Limit bytes are not logged, and headers are never logged. It does
+// nothing unless the logger is enabled for debug.
+type BodyLogger struct {
+	Logger *slog.Logger
+	// Limit caps the logged body size; 0 uses DefaultDebugBodyLimit
+	Limit int
+}
+
+func (m *BodyLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasBody(r.Method) || !m.Logger.Enabled(r.Context(), slog.LevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit := m.Limit
+		if limit <= 0 {
+			limit = DefaultDebugBodyLimit
+		}
+
+		// Read one byte past the limit to tell a full body from a truncated one, then hand
+		// the handler everything, including whatever wasn't read
+		head, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+
+		requestID, _ := r.Context().Value("requestID").(string)
+		attrs := []any{"method", r.Method, "path", r.URL.Path, "requestID", requestID}
+		switch {
+		case err != nil:
+			attrs = append(attrs, "error", err)
+		case len(head) > limit:
+			attrs = append(attrs, "body", "omitted, over the debug body limit", "limit", limit)
+		default:
+			attrs = append(attrs, "body", redactBody(head))
+		}
+		m.Logger.Debug("Request body", attrs...)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasBody reports whether requests with this method carry a body worth logging
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// redactBody returns a JSON body with sensitive fields masked. A body that isn't JSON
+// can't be redacted reliably, so only its size is reported.
+func redactBody(body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "omitted, not JSON"
+	}
+	redacted, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return "omitted, not JSON"
+	}
+	return string(redacted)
+}
+
+// redactValue masks sensitive fields anywhere in a decoded JSON value
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if sensitiveField(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+func sensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range sensitiveFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
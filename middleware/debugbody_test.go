@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLogger(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		level    slog.Level
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "logs JSON bodies at debug",
+			method:   "POST",
+			body:     `{"r": 255, "g": 0, "b": 0}`,
+			level:    slog.LevelDebug,
+			contains: []string{"Request body", `\"r\":255`, "requestID=abc"},
+		},
+		{
+			name:     "redacts sensitive fields",
+			method:   "PUT",
+			body:     `{"name": "desk", "webhook": {"api_key": "k-123", "Token": "t-456"}}`,
+			level:    slog.LevelDebug,
+			contains: []string{`\"name\":\"desk\"`, `\"api_key\":\"***\"`},
+			excludes: []string{"k-123", "t-456"},
+		},
+		{
+			name:     "skips bodies over the limit",
+			method:   "POST",
+			body:     `{"name": "` + strings.Repeat("a", 200) + `"}`,
+			level:    slog.LevelDebug,
+			contains: []string{"over the debug body limit"},
+			excludes: []string{"aaaa"},
+		},
+		{
+			name:     "skips bodies that aren't JSON",
+			method:   "PATCH",
+			body:     `password=hunter2`,
+			level:    slog.LevelDebug,
+			contains: []string{"not JSON"},
+			excludes: []string{"hunter2"},
+		},
+		{
+			name:     "silent above debug",
+			method:   "POST",
+			body:     `{"r": 255}`,
+			level:    slog.LevelInfo,
+			excludes: []string{"Request body"},
+		},
+		{
+			name:     "ignores requests without a body",
+			method:   "GET",
+			level:    slog.LevelDebug,
+			excludes: []string{"Request body"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: tt.level}))
+			m := &BodyLogger{Logger: logger, Limit: 128}
+
+			var received string
+			handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/lights/rgb", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret-token")
+			req = req.WithContext(context.WithValue(req.Context(), "requestID", "abc"))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if received != tt.body {
+				t.Errorf("expected the handler to receive the full body %q, got %q", tt.body, received)
+			}
+			out := logs.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected logs to contain %q, got %q", want, out)
+				}
+			}
+			for _, unwanted := range append(tt.excludes, "secret-token") {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("expected logs not to contain %q, got %q", unwanted, out)
+				}
+			}
+		})
+	}
+}
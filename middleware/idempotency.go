@@ -0,0 +1,191 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyMaxEntries bounds how many responses are remembered at once
+const DefaultIdempotencyMaxEntries = 1000
+
+// IdempotencyMiddleware replays the stored response when a POST is retried with the same
+// Idempotency-Key header, so a retried request doesn't drive the lights twice. Responses are
+// kept for TTL in a least-recently-used cache. Server errors (5xx) are not stored so the
+// request can be retried for real. Reusing a key with a different body is rejected with
+// 422 rather than answered with the other request's response.
+type IdempotencyMiddleware struct {
+	TTL        time.Duration
+	MaxEntries int
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List // front is most recently used
+	inFlight map[string]bool
+}
+
+// storedResponse is a captured response kept for replay
+type storedResponse struct {
+	key string
+	// bodyHash is the SHA-256 of the request body the response answered
+	bodyHash [sha256.Size]byte
+	status   int
+	header   http.Header
+	body     []byte
+	expires  time.Time
+}
+
+// NewIdempotencyMiddleware creates a middleware keeping up to maxEntries responses for ttl
+func NewIdempotencyMiddleware(ttl time.Duration, maxEntries int) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		TTL:        ttl,
+		MaxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		inFlight:   make(map[string]bool),
+	}
+}
+
+func (m *IdempotencyMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cacheKey := r.Method + " " + r.URL.RequestURI() + " " + key
+
+		// Hand the handler the whole body again, including whatever couldn't be read
+		body, err := io.ReadAll(r.Body)
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil {
+			// Without the whole body the request can't be matched; let the handler report it
+			next.ServeHTTP(w, r)
+			return
+		}
+		bodyHash := sha256.Sum256(body)
+
+		stored, busy := m.begin(cacheKey)
+		if stored != nil && stored.bodyHash != bodyHash {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Idempotency-Key was already used with a different request body"})
+			return
+		}
+		if stored != nil {
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+		if busy {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "request with this Idempotency-Key is already in progress"})
+			return
+		}
+
+		// Deferred so a panicking handler doesn't leave the key busy until restart
+		defer m.release(cacheKey)
+		recorder := &idempotencyResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		m.finish(cacheKey, bodyHash, recorder)
+	})
+}
+
+// begin returns the stored response for key, or marks key as in flight. busy is true when
+// another request with the same key is still being handled.
+func (m *IdempotencyMiddleware) begin(key string) (stored *storedResponse, busy bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		entry := element.Value.(*storedResponse)
+		if time.Now().Before(entry.expires) {
+			m.order.MoveToFront(element)
+			return entry, false
+		}
+		m.order.Remove(element)
+		delete(m.entries, key)
+	}
+	if m.inFlight[key] {
+		return nil, true
+	}
+	m.inFlight[key] = true
+	return nil, false
+}
+
+// release clears key's in-flight marker
+func (m *IdempotencyMiddleware) release(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.inFlight, key)
+}
+
+// finish stores the recorded response for key and the hash of the body it answered
+func (m *IdempotencyMiddleware) finish(key string, bodyHash [sha256.Size]byte, recorder *idempotencyResponseWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if recorder.status >= http.StatusInternalServerError {
+		return
+	}
+	header := recorder.Header().Clone()
+	// A replay keeps the request ID of the request it answers
+	header.Del("X-Request-ID")
+	m.entries[key] = m.order.PushFront(&storedResponse{
+		key:      key,
+		bodyHash: bodyHash,
+		status:   recorder.status,
+		header:   header,
+		body:     recorder.body.Bytes(),
+		expires:  time.Now().Add(m.TTL),
+	})
+	for m.MaxEntries > 0 && m.order.Len() > m.MaxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*storedResponse).key)
+	}
+}
+
+// idempotencyResponseWriter passes the response through while keeping a copy for replay
+type idempotencyResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *idempotencyResponseWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingHandler counts calls and echoes the call number in the body
+func countingHandler(calls *int, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call": %d}`, *calls)
+	})
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	calls := 0
+	handler := NewIdempotencyMiddleware(time.Minute, 10).Middleware(countingHandler(&calls, http.StatusOK))
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/lights/on", nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("POST", "abc")
+	replay := send("POST", "abc")
+	if calls != 1 {
+		t.Errorf("expected handler to run once, ran %d times", calls)
+	}
+	if replay.Body.String() != first.Body.String() || replay.Code != first.Code {
+		t.Errorf("expected replay %d %q to match %d %q", replay.Code, replay.Body.String(), first.Code, first.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected replayed response to be marked")
+	}
+	if replay.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected stored headers to be replayed")
+	}
+
+	send("POST", "other")
+	send("POST", "")
+	send("GET", "abc")
+	if calls != 4 {
+		t.Errorf("expected new keys, missing keys and non-POSTs to run the handler, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareExpires(t *testing.T) {
+	calls := 0
+	m := NewIdempotencyMiddleware(time.Millisecond, 10)
+	handler := m.Middleware(countingHandler(&calls, http.StatusOK))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/lights/on", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls != 2 {
+		t.Errorf("expected expired key to run the handler again, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareSkipsServerErrors(t *testing.T) {
+	calls := 0
+	handler := NewIdempotencyMiddleware(time.Minute, 10).Middleware(countingHandler(&calls, http.StatusInternalServerError))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/lights/on", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 2 {
+		t.Errorf("expected failed requests to be retried, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareEvictsLeastRecentlyUsed(t *testing.T) {
+	calls := 0
+	handler := NewIdempotencyMiddleware(time.Minute, 2).Middleware(countingHandler(&calls, http.StatusOK))
+
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		req := httptest.NewRequest("POST", "/lights/on", nil)
+		req.Header.Set("Idempotency-Key", key)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	// a, b and c run once each; "b" was evicted by "c" (a was used more recently) and runs again
+	if calls != 4 {
+		t.Errorf("expected 4 handler runs, got %d", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareConflict(t *testing.T) {
+	m := NewIdempotencyMiddleware(time.Minute, 10)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go func() {
+		req := httptest.NewRequest("POST", "/lights/on", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	req := httptest.NewRequest("POST", "/lights/on", nil)
+	req.Header.Set("Idempotency-Key", "abc")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	close(release)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for concurrent duplicate, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesKeyOnPanic(t *testing.T) {
+	calls := 0
+	handler := NewIdempotencyMiddleware(time.Minute, 10).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/lights/on", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	func() {
+		defer func() { recover() }()
+		serve()
+	}()
+	if w := serve(); w.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected the retry to run after a panic, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsDifferentBody(t *testing.T) {
+	var bodies []string
+	handler := NewIdempotencyMiddleware(time.Minute, 10).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/lights/rgb", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "abc")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	send(`{"r": 255, "g": 0, "b": 0}`)
+	if w := send(`{"r": 255, "g": 0, "b": 0}`); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the same body to be replayed, got %d", w.Code)
+	}
+	if w := send(`{"r": 0, "g": 0, "b": 255}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for a different body, got %d", w.Code)
+	}
+	// The handler still sees the body the middleware read
+	if len(bodies) != 1 || bodies[0] != `{"r": 255, "g": 0, "b": 0}` {
+		t.Errorf("expected the handler to run once with the full body, got %q", bodies)
+	}
+}
+
+func TestIdempotencyMiddlewareKeepsRequestID(t *testing.T) {
+	calls := 0
+	m := NewIdempotencyMiddleware(time.Minute, 10)
+	send := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/lights/on", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		w := httptest.NewRecorder()
+		// As LoggingMiddleware does, ahead of the idempotency middleware
+		w.Header().Set("X-Request-ID", requestID)
+		m.Middleware(countingHandler(&calls, http.StatusOK)).ServeHTTP(w, req)
+		return w
+	}
+
+	send("first")
+	if replay := send("second"); replay.Header().Get("X-Request-ID") != "second" {
+		t.Errorf("expected the replay to keep its own request ID, got %q", replay.Header().Get("X-Request-ID"))
+	}
+}
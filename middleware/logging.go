@@ -15,38 +15,73 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 )
 
+// logSampleEvery is the fraction of successful requests logged once SampleRate is exceeded
+const logSampleEvery = 10
+
 type LoggingMiddleware struct {
 	Logger *slog.Logger
+	// SampleRate is how many successful requests are logged per second before only one
+	// in logSampleEvery is; requests that don't end in a 2xx are always logged. 0 logs
+	// every request.
+	SampleRate int
+	// ExcludedPaths are served without logging, e.g. health checks polled by an
+	// orchestrator. They still get a request ID.
+	ExcludedPaths []string
+	// TrustedProxies are the proxies whose X-Forwarded-For header names the client logged
+	// as clientIP; with none, clientIP is the connection's address
+	TrustedProxies TrustedProxies
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
 }
 
 func (m *LoggingMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Generate request ID
-		requestID := generateRequestID()
+		// Reuse an upstream request ID so traces can be followed across proxies
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = generateRequestID()
+		}
 		ctx := context.WithValue(r.Context(), "requestID", requestID)
 		r = r.WithContext(ctx)
 
 		// Set request ID in response header
 		w.Header().Set("X-Request-ID", requestID)
 
-		// Log request
-		m.Logger.Info("Request started",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"requestID", requestID,
-			"userAgent", r.UserAgent(),
-			"remoteAddr", r.RemoteAddr,
-		)
+		if slices.Contains(m.ExcludedPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Log request. The outcome isn't known yet, so a request sampled out here only
+		// gets its completion logged if it fails.
+		sampled := m.sample(start)
+		if sampled {
+			m.Logger.Info("Request started",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"requestID", requestID,
+				"userAgent", r.UserAgent(),
+				"remoteAddr", r.RemoteAddr,
+				"clientIP", m.TrustedProxies.ClientIP(r),
+			)
+		}
 
 		// Wrap response writer to capture status
 		wrapped := &responseWriter{ResponseWriter: w, status: 200}
@@ -54,30 +89,109 @@ func (m *LoggingMiddleware) Middleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wrapped, r)
 
 		// Log response
+		if !sampled && wrapped.status >= 200 && wrapped.status < 300 {
+			return
+		}
 		duration := time.Since(start)
 		m.Logger.Info("Request completed",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"requestID", requestID,
 			"status", wrapped.status,
+			"bytes", wrapped.bytes,
 			"duration", duration,
 		)
 	})
 }
 
+// sample reports whether a request arriving at now should be logged: every request while
+// fewer than SampleRate have arrived in the current one-second window, then one in
+// logSampleEvery
+func (m *LoggingMiddleware) sample(now time.Time) bool {
+	if m.SampleRate <= 0 {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.windowStart) >= time.Second {
+		m.windowStart = now
+		m.windowCount = 0
+	}
+	m.windowCount++
+	over := m.windowCount - m.SampleRate
+	return over <= 0 || over%logSampleEvery == 0
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int
 }
 
+// The wrapper must not hide the streaming interfaces of the writer it wraps
+var (
+	_ http.Flusher  = (*responseWriter)(nil)
+	_ http.Hijacker = (*responseWriter)(nil)
+)
+
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// generateRequestID creates a simple 8-character hex request ID
+// Write counts the response body bytes as they pass through
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers push buffered data to the client
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades take over the underlying connection
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// maxRequestIDLength bounds incoming request IDs so they can't bloat logs
+const maxRequestIDLength = 64
+
+// validRequestID reports whether an incoming request ID is short and only contains
+// letters, digits, hyphens or underscores
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// generateRequestID creates a random 32-character hex request ID. If the system's
+// random source fails it falls back to a timestamp-based ID rather than failing the request.
 func generateRequestID() string {
-	bytes := make([]byte, 4)
-	rand.Read(bytes)
-	return fmt.Sprintf("%x", bytes)
-}
\ No newline at end of file
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("ts-%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
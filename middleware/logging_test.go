@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggingMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		incoming   string
+		passedThru bool
+	}{
+		{"no incoming ID", "", false},
+		{"valid incoming ID", "abc-123_XYZ", true},
+		{"incoming UUID", "3f2504e0-4f89-41d3-9a0c-0305e82c3301", true},
+		{"invalid characters", "abc 123<script>", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+			m := &LoggingMiddleware{Logger: logger}
+
+			var contextID string
+			handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID, _ = r.Context().Value("requestID").(string)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			headerID := w.Header().Get("X-Request-ID")
+			if headerID == "" {
+				t.Fatal("expected X-Request-ID response header")
+			}
+			if headerID != contextID {
+				t.Errorf("expected context ID %q to match header %q", contextID, headerID)
+			}
+			if tt.passedThru && headerID != tt.incoming {
+				t.Errorf("expected incoming ID %q to be reused, got %q", tt.incoming, headerID)
+			}
+			if !tt.passedThru && headerID == tt.incoming {
+				t.Errorf("expected a generated ID, got incoming %q", headerID)
+			}
+		})
+	}
+}
+
+func TestGenerateRequestID(t *testing.T) {
+	id := generateRequestID()
+	if len(id) != 32 {
+		t.Errorf("expected 32-character ID, got %q", id)
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		t.Errorf("expected hex ID, got %q", id)
+	}
+	if other := generateRequestID(); other == id {
+		t.Errorf("expected unique IDs, got %q twice", id)
+	}
+}
+
+func TestLoggingMiddlewareLogsBytes(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+	m := &LoggingMiddleware{Logger: logger}
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello "))
+		w.Write([]byte("world"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(logs.String(), "bytes=11") {
+		t.Errorf("expected the completed-request log to include bytes=11, got %q", logs.String())
+	}
+}
+
+func TestLoggingMiddlewareSample(t *testing.T) {
+	m := &LoggingMiddleware{SampleRate: 3}
+	start := time.Now()
+
+	logged := 0
+	for i := 0; i < 33; i++ {
+		if m.sample(start.Add(time.Duration(i) * time.Millisecond)) {
+			logged++
+		}
+	}
+	// The first 3, then every 10th of the remaining 30
+	if logged != 6 {
+		t.Errorf("expected 6 sampled requests, got %d", logged)
+	}
+
+	if !m.sample(start.Add(time.Second)) {
+		t.Error("expected a new window to log requests again")
+	}
+
+	unlimited := &LoggingMiddleware{}
+	for i := 0; i < 100; i++ {
+		if !unlimited.sample(start) {
+			t.Fatal("expected every request to be logged without a sample rate")
+		}
+	}
+}
+
+func TestLoggingMiddlewareSamplingKeepsErrors(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+	m := &LoggingMiddleware{Logger: logger, SampleRate: 1}
+
+	status := http.StatusOK
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	// Use up the window, then send a request that will be sampled out
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	if got := strings.Count(logs.String(), "Request completed"); got != 1 {
+		t.Fatalf("expected the second successful request to be sampled out, got %d completions logged", got)
+	}
+
+	status = http.StatusServiceUnavailable
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	if !strings.Contains(logs.String(), "status=503") {
+		t.Errorf("expected the failed request to be logged, got %q", logs.String())
+	}
+}
+
+func TestLoggingMiddlewareExcludedPaths(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+	m := &LoggingMiddleware{Logger: logger, ExcludedPaths: []string{"/health"}}
+
+	var contextID string
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID, _ = r.Context().Value("requestID").(string)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if logs.Len() != 0 {
+		t.Errorf("expected excluded paths not to be logged, got %q", logs.String())
+	}
+	if contextID == "" || w.Header().Get("X-Request-ID") != contextID {
+		t.Error("expected excluded paths to still get a request ID")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	if !strings.Contains(logs.String(), "Request completed") {
+		t.Errorf("expected other paths to be logged, got %q", logs.String())
+	}
+}
+
+func TestLoggingMiddlewareClientIP(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := &LoggingMiddleware{Logger: logger, TrustedProxies: proxies}
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.1:443"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(logs.String(), "clientIP=198.51.100.1") {
+		t.Errorf("expected the forwarded client IP to be logged, got %q", logs.String())
+	}
+	if !strings.Contains(logs.String(), "remoteAddr=10.0.0.1:443") {
+		t.Errorf("expected the proxy address to be logged too, got %q", logs.String())
+	}
+}
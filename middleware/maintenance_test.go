@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaintenance(t *testing.T) {
+	maintenance := &Maintenance{}
+	handler := maintenance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/lights/on", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 outside maintenance, got %d", w.Code)
+	}
+
+	if !maintenance.Set(true) {
+		t.Error("expected enabling maintenance to report a change")
+	}
+	if maintenance.Set(true) {
+		t.Error("expected enabling maintenance twice to report no change")
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/lights/on", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 in maintenance, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `{"error":"maintenance mode"}`) {
+		t.Errorf("expected maintenance mode error, got %s", w.Body.String())
+	}
+
+	maintenance.Set(false)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/lights/on", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 after maintenance, got %d", w.Code)
+	}
+}
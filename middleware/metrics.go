@@ -15,17 +15,61 @@
 package middleware
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jwhitcraft/lights-http/metrics"
 )
 
-type MetricsMiddleware struct{}
+type MetricsMiddleware struct {
+	// ExcludedPaths are served without recording any metrics, e.g. health checks polled
+	// by an orchestrator
+	ExcludedPaths []string
+}
+
+// unmatchedRoute labels requests that didn't match a registered route, so unknown paths
+// can't grow the metric label set without bound
+const unmatchedRoute = "unmatched"
+
+type routeKey struct{}
+
+// SetRoute overrides the endpoint label the metrics middleware records for this request.
+// It has no effect outside MetricsMiddleware.
+func SetRoute(r *http.Request, route string) {
+	if label, ok := r.Context().Value(routeKey{}).(*string); ok {
+		*label = route
+	}
+}
+
+// routeLabel returns the endpoint label for a request: a route set with SetRoute, else
+// the pattern the ServeMux matched without its method (e.g. /lights/color/{name} for
+// /lights/color/red), else unmatchedRoute
+func routeLabel(r *http.Request, override string) string {
+	if override != "" {
+		return override
+	}
+	if r.Pattern == "" {
+		return unmatchedRoute
+	}
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
 
 func (m *MetricsMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(m.ExcludedPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
 		start := time.Now()
 
 		// Increment active connections
@@ -35,23 +79,62 @@ func (m *MetricsMiddleware) Middleware(next http.Handler) http.Handler {
 		// Wrap response writer to capture status
 		wrapped := &metricsResponseWriter{ResponseWriter: w, status: 200}
 
+		var override string
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, &override))
 		next.ServeHTTP(wrapped, r)
 
 		// Record metrics
 		duration := time.Since(start).Seconds()
 		status := strconv.Itoa(wrapped.status)
+		endpoint := routeLabel(r, override)
 
-		metrics.HTTPRequestsTotal.WithLabelValues(r.Method, r.URL.Path, status).Inc()
-		metrics.HTTPRequestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(duration)
+		metrics.HTTPRequestsTotal.WithLabelValues(r.Method, endpoint, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, endpoint).Observe(duration)
+		metrics.HTTPResponseBytes.WithLabelValues(r.Method, endpoint).Observe(float64(wrapped.bytes))
 	})
 }
 
 type metricsResponseWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int
 }
 
+// The wrapper must not hide the streaming interfaces of the writer it wraps
+var (
+	_ http.Flusher  = (*metricsResponseWriter)(nil)
+	_ http.Hijacker = (*metricsResponseWriter)(nil)
+)
+
 func (rw *metricsResponseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
-}
\ No newline at end of file
+}
+
+// Write counts the response body bytes as they pass through
+func (rw *metricsResponseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers push buffered data to the client
+func (rw *metricsResponseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades take over the underlying connection
+func (rw *metricsResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (rw *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jwhitcraft/lights-http/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWrappersPreserveStreamingInterfaces(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	loggingMiddleware := &LoggingMiddleware{Logger: logger}
+	metricsMiddleware := &MetricsMiddleware{}
+
+	var flushed, hijackErr bool
+	handler := loggingMiddleware.Middleware(metricsMiddleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected the wrapped writer to implement http.Flusher")
+		}
+		w.Write([]byte("data: {}\n\n"))
+		flusher.Flush()
+		flushed = true
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("expected the wrapped writer to implement http.Hijacker")
+		}
+		// httptest.ResponseRecorder can't be hijacked, so the error must come from it
+		_, _, err := hijacker.Hijack()
+		hijackErr = err != nil
+	})))
+
+	req := httptest.NewRequest("GET", "/lights/events", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if !flushed || !w.Flushed {
+		t.Error("expected Flush to reach the underlying writer")
+	}
+	if !hijackErr {
+		t.Error("expected Hijack to report that the underlying writer can't be hijacked")
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	mux := http.NewServeMux()
+	var got string
+	capture := func(w http.ResponseWriter, r *http.Request) {
+		got = routeLabel(r, "")
+	}
+	mux.HandleFunc("/lights/color/{name}", capture)
+	mux.HandleFunc("DELETE /lights/groups/{name}", capture)
+
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{"POST", "/lights/color/red", "/lights/color/{name}"},
+		{"DELETE", "/lights/groups/desk", "/lights/groups/{name}"},
+	}
+	for _, tt := range tests {
+		got = ""
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if got != tt.expected {
+			t.Errorf("routeLabel(%s %s) = %q, want %q", tt.method, tt.path, got, tt.expected)
+		}
+	}
+
+	if label := routeLabel(httptest.NewRequest("GET", "/random/path", nil), ""); label != unmatchedRoute {
+		t.Errorf("expected unmatched requests to be labeled %q, got %q", unmatchedRoute, label)
+	}
+	if label := routeLabel(httptest.NewRequest("GET", "/random/path", nil), "/custom"); label != "/custom" {
+		t.Errorf("expected an override to win, got %q", label)
+	}
+}
+
+func TestSetRoute(t *testing.T) {
+	m := &MetricsMiddleware{}
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "/custom")
+	}))
+	before := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/custom", "200"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/anything", nil))
+	after := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/custom", "200"))
+	if after != before+1 {
+		t.Errorf("expected the request to be counted under /custom, got %v -> %v", before, after)
+	}
+}
+
+func TestMetricsMiddlewareExcludedPaths(t *testing.T) {
+	m := &MetricsMiddleware{ExcludedPaths: []string{"/health"}}
+	mux := http.NewServeMux()
+	served := 0
+	count := func(w http.ResponseWriter, r *http.Request) { served++ }
+	mux.Handle("GET /health", m.Middleware(http.HandlerFunc(count)))
+	mux.Handle("GET /version", m.Middleware(http.HandlerFunc(count)))
+
+	before := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/health", "200"))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if after := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/health", "200")); after != before {
+		t.Errorf("expected excluded paths not to be counted, got %v -> %v", before, after)
+	}
+
+	before = testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/version", "200"))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/version", nil))
+	if after := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/version", "200")); after != before+1 {
+		t.Errorf("expected other paths to be counted, got %v -> %v", before, after)
+	}
+
+	if served != 2 {
+		t.Errorf("expected both requests to be served, got %d", served)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignatureAuthMiddleware(t *testing.T) {
+	secret := "shared-secret"
+	body := `{"r": 255, "g": 0, "b": 0}`
+
+	tests := []struct {
+		name           string
+		signature      string
+		authHeader     string
+		expectedStatus int
+	}{
+		{
+			name:           "valid signature",
+			signature:      Sign(secret, []byte(body)),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "signature from wrong secret",
+			signature:      Sign("other-secret", []byte(body)),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "signature without prefix",
+			signature:      strings.TrimPrefix(Sign(secret, []byte(body)), "sha256="),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid signature takes precedence over bearer token",
+			signature:      "sha256=zz",
+			authHeader:     "Bearer test-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "bearer token without signature",
+			authHeader:     "Bearer test-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no credentials",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/lights/rgb", strings.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+
+			var received string
+			handler := SignatureAuthMiddleware(secret, AuthMiddleware("test-token", false))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				received = string(b)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && received != body {
+				t.Errorf("expected handler to receive the body %q, got %q", body, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mqtt bridges the light controller to an MQTT broker so lights can be
+// driven from home-automation systems without going through the HTTP API.
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/jwhitcraft/lights-http/controller"
+	"github.com/jwhitcraft/lights-http/metrics"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// Controller defines the methods the bridge needs from the light controller
+type Controller interface {
+	Devices() []*govee.Device
+}
+
+// Options configures the broker connection and topic layout
+type Options struct {
+	Broker      string
+	ClientID    string
+	Username    string
+	Password    string
+	TopicPrefix string
+
+	// Discovery publishes Home Assistant discovery configs under DiscoveryPrefix
+	Discovery       bool
+	DiscoveryPrefix string
+
+	// StatusCache is invalidated for every device a command touches; nil disables it
+	StatusCache *controller.StatusCache
+
+	// Queue, shared with the HTTP handlers, sends one command at a time so HTTP and MQTT
+	// commands never collide in the govee library; nil sends them directly
+	Queue *controller.DeviceQueue
+
+	// Faded, shared with the HTTP handlers, restores the brightness a light had before it
+	// was faded off when it is turned back on; nil forgets it
+	Faded *controller.FadedBrightness
+}
+
+// discoveryInterval is how often newly found devices are announced to Home Assistant
+const discoveryInterval = 60 * time.Second
+
+// Bridge subscribes to <prefix>/set and publishes device state to <prefix>/state
+type Bridge struct {
+	controller Controller
+	logger     *slog.Logger
+	client     paho.Client
+	prefix     string
+
+	discovery       bool
+	discoveryPrefix string
+	announcedMu     sync.Mutex
+	announced       map[string]bool
+	stop            chan struct{}
+
+	statusCache *controller.StatusCache
+	queue       *controller.DeviceQueue
+	faded       *controller.FadedBrightness
+}
+
+// command is the payload accepted on the set topic
+type command struct {
+	Action      string `json:"action,omitempty"`
+	R           *int   `json:"r,omitempty"`
+	G           *int   `json:"g,omitempty"`
+	B           *int   `json:"b,omitempty"`
+	Brightness  *int   `json:"brightness,omitempty"`
+	Temperature *int   `json:"temperature,omitempty"`
+}
+
+// NewBridge creates a bridge for the given controller; call Start to connect
+func NewBridge(opts Options, ctrl Controller, logger *slog.Logger) *Bridge {
+	prefix := opts.TopicPrefix
+	if prefix == "" {
+		prefix = "lights"
+	}
+	clientID := opts.ClientID
+	if clientID == "" {
+		clientID = "lights-http"
+	}
+
+	discoveryPrefix := opts.DiscoveryPrefix
+	if discoveryPrefix == "" {
+		discoveryPrefix = "homeassistant"
+	}
+
+	b := &Bridge{
+		controller:      ctrl,
+		logger:          logger.With("component", "mqtt"),
+		prefix:          prefix,
+		discovery:       opts.Discovery,
+		discoveryPrefix: discoveryPrefix,
+		announced:       make(map[string]bool),
+		stop:            make(chan struct{}),
+		statusCache:     opts.StatusCache,
+		queue:           opts.Queue,
+		faded:           opts.Faded,
+	}
+
+	clientOpts := paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(clientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetAutoReconnect(true).
+		SetWill(b.availabilityTopic(), "offline", 1, true)
+
+	// Resubscribe on every (re)connect so a broker restart doesn't silently drop the subscription
+	clientOpts.SetOnConnectHandler(func(client paho.Client) {
+		b.logger.Info("Connected to MQTT broker", "broker", opts.Broker)
+		client.Publish(b.availabilityTopic(), 1, true, "online")
+
+		b.subscribe(client, b.setTopic(), b.handleSet)
+		if b.discovery {
+			b.subscribe(client, b.prefix+"/+/set", b.handleDeviceSet)
+			// Re-announce everything in case the broker lost its retained messages
+			b.announcedMu.Lock()
+			b.announced = make(map[string]bool)
+			b.announcedMu.Unlock()
+			b.announceDevices()
+		}
+	})
+	clientOpts.SetConnectionLostHandler(func(_ paho.Client, err error) {
+		b.logger.Warn("Lost connection to MQTT broker", "error", err)
+	})
+	b.client = paho.NewClient(clientOpts)
+	return b
+}
+
+// Start connects to the broker
+func (b *Bridge) Start() error {
+	token := b.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("timed out connecting to MQTT broker")
+	}
+	if err := token.Error(); err != nil {
+		return err
+	}
+
+	if b.discovery {
+		go b.discoveryLoop()
+	}
+	return nil
+}
+
+// Stop marks the bridge offline and disconnects from the broker
+func (b *Bridge) Stop() {
+	close(b.stop)
+	b.client.Publish(b.availabilityTopic(), 1, true, "offline").WaitTimeout(time.Second)
+	b.client.Disconnect(250)
+}
+
+func (b *Bridge) setTopic() string          { return b.prefix + "/set" }
+func (b *Bridge) stateTopic() string        { return b.prefix + "/state" }
+func (b *Bridge) availabilityTopic() string { return b.prefix + "/availability" }
+
+func (b *Bridge) subscribe(client paho.Client, topic string, handler paho.MessageHandler) {
+	token := client.Subscribe(topic, 1, handler)
+	token.Wait()
+	if err := token.Error(); err != nil {
+		b.logger.Error("Failed to subscribe", "topic", topic, "error", err)
+	}
+}
+
+// discoveryLoop periodically announces devices found since the last pass
+func (b *Bridge) discoveryLoop() {
+	ticker := time.NewTicker(discoveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.announceDevices()
+		}
+	}
+}
+
+// announceDevices publishes a retained Home Assistant discovery config for each device not yet announced
+func (b *Bridge) announceDevices() {
+	b.announcedMu.Lock()
+	defer b.announcedMu.Unlock()
+
+	for _, device := range b.controller.Devices() {
+		deviceID := device.DeviceID()
+		if deviceID == "" || b.announced[deviceID] {
+			continue
+		}
+		payload, err := json.Marshal(buildDiscoveryConfig(b.prefix, deviceID, device.SKU()))
+		if err != nil {
+			b.logger.Error("Failed to encode discovery config", "device", deviceID, "error", err)
+			continue
+		}
+		b.client.Publish(discoveryTopic(b.discoveryPrefix, deviceID), 1, true, payload)
+		b.announced[deviceID] = true
+		b.logger.Info("Announced device to Home Assistant", "device", deviceID)
+	}
+}
+
+// handleDeviceSet applies a Home Assistant JSON-schema command to a single device
+func (b *Bridge) handleDeviceSet(_ paho.Client, msg paho.Message) {
+	id := strings.TrimSuffix(strings.TrimPrefix(msg.Topic(), b.prefix+"/"), "/set")
+
+	var device *govee.Device
+	for _, d := range b.controller.Devices() {
+		if objectID(d.DeviceID()) == id {
+			device = d
+			break
+		}
+	}
+	if device == nil {
+		b.logger.Warn("Command for unknown device", "topic", msg.Topic())
+		return
+	}
+
+	ops, err := parseHACommand(msg.Payload(), b.faded)
+	if err != nil {
+		b.logger.Warn("Invalid Home Assistant command", "topic", msg.Topic(), "error", err)
+		return
+	}
+
+	// Even a partly applied command leaves the cached status stale
+	defer b.statusCache.Invalidate(device.DeviceID())
+	// The queue spaces the ops out, so they need no pause of their own
+	for _, op := range ops {
+		if err := b.queue.Do(device, op); err != nil {
+			b.logger.Error("Failed to apply Home Assistant command", "device", controller.DeviceLabel(device), "error", err)
+			metrics.LightOperationsTotal.WithLabelValues("home_assistant", "error", "").Inc()
+			return
+		}
+	}
+	metrics.LightOperationsTotal.WithLabelValues("home_assistant", "success", "").Inc()
+
+	if err := device.RequestStatus(); err != nil {
+		b.logger.Error("Failed to request status", "device", controller.DeviceLabel(device), "error", err)
+		return
+	}
+	payload, err := json.Marshal(buildHAState(device))
+	if err != nil {
+		b.logger.Error("Failed to encode state", "device", controller.DeviceLabel(device), "error", err)
+		return
+	}
+	b.client.Publish(deviceStateTopic(b.prefix, device.DeviceID()), 1, true, payload)
+}
+
+func (b *Bridge) handleSet(_ paho.Client, msg paho.Message) {
+	operationName, op, err := parseCommand(msg.Payload(), b.faded)
+	if err != nil {
+		b.logger.Warn("Invalid MQTT command", "topic", msg.Topic(), "error", err)
+		return
+	}
+
+	b.logger.Info(fmt.Sprintf("Executing %s operation", operationName), "source", "mqtt")
+
+	// Every device's command is queued at once; the queue sends them one at a time
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for _, device := range b.controller.Devices() {
+		wg.Go(func() {
+			err := b.queue.Do(device, op)
+			b.statusCache.Invalidate(device.DeviceID())
+			if err != nil {
+				b.logger.Error(fmt.Sprintf("Failed to %s device", operationName),
+					"device", controller.DeviceLabel(device),
+					"error", err)
+				failed.Store(true)
+				metrics.LightDeviceOperationsTotal.WithLabelValues(operationName, "error", device.DeviceID()).Inc()
+			} else {
+				metrics.LightDeviceOperationsTotal.WithLabelValues(operationName, "success", device.DeviceID()).Inc()
+			}
+		})
+	}
+	wg.Wait()
+
+	result := "success"
+	if failed.Load() {
+		result = "error"
+	}
+	// MQTT colors are always raw RGB values
+	color := ""
+	if operationName == "set_color" {
+		color = "rgb"
+	}
+	metrics.LightOperationsTotal.WithLabelValues(operationName, result, color).Inc()
+
+	b.publishState()
+}
+
+// publishState publishes the latest status of every device to the state topic
+func (b *Bridge) publishState() {
+	var statuses []map[string]interface{}
+	for _, device := range b.controller.Devices() {
+		if err := device.RequestStatus(); err != nil {
+			b.logger.Error("Failed to request status", "device", controller.DeviceLabel(device), "error", err)
+			continue
+		}
+		statuses = append(statuses, controller.DeviceStatus(device))
+	}
+
+	payload, err := json.Marshal(statuses)
+	if err != nil {
+		b.logger.Error("Failed to encode state", "error", err)
+		return
+	}
+	b.client.Publish(b.stateTopic(), 1, true, payload)
+}
+
+// parseCommand converts a set-topic payload into an operation name and the operation to run.
+// Power and brightness go through faded, so a light faded off comes back at its old level.
+func parseCommand(payload []byte, faded *controller.FadedBrightness) (string, controller.Operation, error) {
+	var cmd command
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	switch {
+	case cmd.Action != "":
+		switch cmd.Action {
+		case "on":
+			return "turn_on", faded.TurnOn(), nil
+		case "off":
+			return "turn_off", faded.TurnOff(), nil
+		default:
+			return "", nil, fmt.Errorf("unknown action %q", cmd.Action)
+		}
+	case cmd.R != nil || cmd.G != nil || cmd.B != nil:
+		if cmd.R == nil || cmd.G == nil || cmd.B == nil {
+			return "", nil, fmt.Errorf("r, g and b must all be provided")
+		}
+		for _, v := range []int{*cmd.R, *cmd.G, *cmd.B} {
+			if v < 0 || v > 255 {
+				return "", nil, fmt.Errorf("RGB values must be between 0 and 255")
+			}
+		}
+		return "set_color", controller.SetColor(govee.Color{R: uint(*cmd.R), G: uint(*cmd.G), B: uint(*cmd.B)}), nil
+	case cmd.Brightness != nil:
+		if *cmd.Brightness < 0 || *cmd.Brightness > 100 {
+			return "", nil, fmt.Errorf("brightness must be between 0 and 100")
+		}
+		return "set_brightness", faded.SetBrightness(govee.Brightness(*cmd.Brightness)), nil
+	case cmd.Temperature != nil:
+		if *cmd.Temperature < 2000 || *cmd.Temperature > 9000 {
+			return "", nil, fmt.Errorf("color temperature must be between 2000K and 9000K")
+		}
+		return "set_color_temp", controller.SetColorKelvin(govee.NewColorKelvin(uint(*cmd.Temperature))), nil
+	}
+	return "", nil, fmt.Errorf("payload must contain action, r/g/b, brightness or temperature")
+}
@@ -0,0 +1,45 @@
+package mqtt
+
+import (
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		wantOperation string
+		wantErr       bool
+	}{
+		{"turn on", `{"action":"on"}`, "turn_on", false},
+		{"turn off", `{"action":"off"}`, "turn_off", false},
+		{"rgb", `{"r":255,"g":128,"b":0}`, "set_color", false},
+		{"brightness", `{"brightness":50}`, "set_brightness", false},
+		{"temperature", `{"temperature":3000}`, "set_color_temp", false},
+		{"unknown action", `{"action":"toggle"}`, "", true},
+		{"partial rgb", `{"r":255}`, "", true},
+		{"rgb out of range", `{"r":256,"g":0,"b":0}`, "", true},
+		{"brightness out of range", `{"brightness":101}`, "", true},
+		{"temperature out of range", `{"temperature":1000}`, "", true},
+		{"empty payload", `{}`, "", true},
+		{"invalid json", `not json`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operationName, op, err := parseCommand([]byte(tt.payload), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if operationName != tt.wantOperation {
+				t.Errorf("expected operation %s, got %s", tt.wantOperation, operationName)
+			}
+			if op == nil {
+				t.Errorf("expected an operation to be returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// discoveryConfig is the Home Assistant MQTT discovery payload for a JSON-schema light
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	Schema              string          `json:"schema"`
+	CommandTopic        string          `json:"command_topic"`
+	StateTopic          string          `json:"state_topic"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	Brightness          bool            `json:"brightness"`
+	BrightnessScale     int             `json:"brightness_scale"`
+	SupportedColorModes []string        `json:"supported_color_modes"`
+	Device              discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model,omitempty"`
+	Name         string   `json:"name"`
+}
+
+// haCommand is the Home Assistant JSON-schema command payload
+type haCommand struct {
+	State      string `json:"state"`
+	Brightness *int   `json:"brightness,omitempty"`
+	Color      *struct {
+		R int `json:"r"`
+		G int `json:"g"`
+		B int `json:"b"`
+	} `json:"color,omitempty"`
+}
+
+// haState is the Home Assistant JSON-schema state payload
+type haState struct {
+	State      string `json:"state"`
+	Brightness int    `json:"brightness"`
+	ColorMode  string `json:"color_mode"`
+	Color      struct {
+		R uint `json:"r"`
+		G uint `json:"g"`
+		B uint `json:"b"`
+	} `json:"color"`
+}
+
+// objectID converts a Govee device ID (e.g. 35:CF:DC:6E:00:86:3C:94) into a Home Assistant safe identifier
+func objectID(deviceID string) string {
+	return strings.ToLower(strings.ReplaceAll(deviceID, ":", ""))
+}
+
+// discoveryTopic returns the retained config topic Home Assistant watches for a device
+func discoveryTopic(discoveryPrefix, deviceID string) string {
+	return fmt.Sprintf("%s/light/%s/config", discoveryPrefix, objectID(deviceID))
+}
+
+// deviceCommandTopic returns the per-device topic Home Assistant publishes commands to
+func deviceCommandTopic(prefix, deviceID string) string {
+	return fmt.Sprintf("%s/%s/set", prefix, objectID(deviceID))
+}
+
+// deviceStateTopic returns the per-device topic the bridge publishes state to
+func deviceStateTopic(prefix, deviceID string) string {
+	return fmt.Sprintf("%s/%s/state", prefix, objectID(deviceID))
+}
+
+// buildDiscoveryConfig describes a device as an MQTT light with rgb and brightness support
+func buildDiscoveryConfig(prefix, deviceID, sku string) discoveryConfig {
+	name := "Govee " + deviceID
+	if sku != "" {
+		name = fmt.Sprintf("Govee %s %s", sku, deviceID)
+	}
+	id := objectID(deviceID)
+	return discoveryConfig{
+		Name:                name,
+		UniqueID:            "lights_http_" + id,
+		Schema:              "json",
+		CommandTopic:        deviceCommandTopic(prefix, deviceID),
+		StateTopic:          deviceStateTopic(prefix, deviceID),
+		AvailabilityTopic:   prefix + "/availability",
+		Brightness:          true,
+		BrightnessScale:     100,
+		SupportedColorModes: []string{"rgb"},
+		Device: discoveryDevice{
+			Identifiers:  []string{"govee_" + id},
+			Manufacturer: "Govee",
+			Model:        sku,
+			Name:         name,
+		},
+	}
+}
+
+// parseHACommand converts a Home Assistant JSON-schema command into the operations to run, in order.
+// Power and brightness go through faded, so a light faded off comes back at its old level.
+func parseHACommand(payload []byte, faded *controller.FadedBrightness) ([]controller.Operation, error) {
+	var cmd haCommand
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var ops []controller.Operation
+	switch cmd.State {
+	case "OFF":
+		// Color and brightness are meaningless when turning off
+		return []controller.Operation{faded.TurnOff()}, nil
+	case "ON":
+		ops = append(ops, faded.TurnOn())
+	default:
+		return nil, fmt.Errorf("unknown state %q", cmd.State)
+	}
+
+	if cmd.Color != nil {
+		c := cmd.Color
+		if c.R < 0 || c.R > 255 || c.G < 0 || c.G > 255 || c.B < 0 || c.B > 255 {
+			return nil, fmt.Errorf("RGB values must be between 0 and 255")
+		}
+		ops = append(ops, controller.SetColor(govee.Color{R: uint(c.R), G: uint(c.G), B: uint(c.B)}))
+	}
+	if cmd.Brightness != nil {
+		if *cmd.Brightness < 0 || *cmd.Brightness > 100 {
+			return nil, fmt.Errorf("brightness must be between 0 and 100")
+		}
+		ops = append(ops, faded.SetBrightness(govee.Brightness(*cmd.Brightness)))
+	}
+	return ops, nil
+}
+
+// buildHAState converts a device's last reported state into a Home Assistant state payload
+func buildHAState(device *govee.Device) haState {
+	state := haState{
+		State:      "OFF",
+		Brightness: int(device.Brightness()),
+		ColorMode:  "rgb",
+	}
+	if controller.PoweredOn(device) {
+		state.State = "ON"
+	}
+	color := device.Color()
+	state.Color.R, state.Color.G, state.Color.B = color.R, color.G, color.B
+	return state
+}
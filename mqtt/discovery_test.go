@@ -0,0 +1,90 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestObjectID(t *testing.T) {
+	if got := objectID("35:CF:DC:6E:00:86:3C:94"); got != "35cfdc6e00863c94" {
+		t.Errorf("expected 35cfdc6e00863c94, got %s", got)
+	}
+}
+
+func TestDiscoveryTopic(t *testing.T) {
+	got := discoveryTopic("homeassistant", "35:CF:DC:6E:00:86:3C:94")
+	if got != "homeassistant/light/35cfdc6e00863c94/config" {
+		t.Errorf("unexpected discovery topic %s", got)
+	}
+}
+
+func TestBuildDiscoveryConfig(t *testing.T) {
+	cfg := buildDiscoveryConfig("lights", "35:CF:DC:6E:00:86:3C:94", "H6159")
+
+	if cfg.Schema != "json" {
+		t.Errorf("expected json schema, got %s", cfg.Schema)
+	}
+	if cfg.CommandTopic != "lights/35cfdc6e00863c94/set" {
+		t.Errorf("unexpected command topic %s", cfg.CommandTopic)
+	}
+	if cfg.StateTopic != "lights/35cfdc6e00863c94/state" {
+		t.Errorf("unexpected state topic %s", cfg.StateTopic)
+	}
+	if cfg.AvailabilityTopic != "lights/availability" {
+		t.Errorf("unexpected availability topic %s", cfg.AvailabilityTopic)
+	}
+	if !cfg.Brightness || cfg.BrightnessScale != 100 {
+		t.Errorf("expected brightness support on a 0-100 scale")
+	}
+	if len(cfg.SupportedColorModes) != 1 || cfg.SupportedColorModes[0] != "rgb" {
+		t.Errorf("expected rgb color mode, got %v", cfg.SupportedColorModes)
+	}
+	if cfg.Device.Model != "H6159" {
+		t.Errorf("expected model H6159, got %s", cfg.Device.Model)
+	}
+
+	// Ensure the payload serializes with the keys Home Assistant expects
+	payload, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal config: %v", err)
+	}
+	for _, key := range []string{"unique_id", "command_topic", "state_topic", "availability_topic", "supported_color_modes", "device"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("expected key %s in discovery payload", key)
+		}
+	}
+}
+
+func TestParseHACommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantOps int
+		wantErr bool
+	}{
+		{"off", `{"state":"OFF"}`, 1, false},
+		{"off ignores color", `{"state":"OFF","color":{"r":255,"g":0,"b":0}}`, 1, false},
+		{"on", `{"state":"ON"}`, 1, false},
+		{"on with color and brightness", `{"state":"ON","color":{"r":255,"g":0,"b":0},"brightness":40}`, 3, false},
+		{"unknown state", `{"state":"TOGGLE"}`, 0, true},
+		{"color out of range", `{"state":"ON","color":{"r":300,"g":0,"b":0}}`, 0, true},
+		{"brightness out of range", `{"state":"ON","brightness":150}`, 0, true},
+		{"invalid json", `nope`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := parseHACommand([]byte(tt.payload), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHACommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(ops) != tt.wantOps {
+				t.Errorf("expected %d operations, got %d", tt.wantOps, len(ops))
+			}
+		})
+	}
+}
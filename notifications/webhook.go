@@ -0,0 +1,120 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notifications delivers light operation events to an external webhook.
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DefaultQueueSize is the number of pending events kept before the oldest are dropped
+const DefaultQueueSize = 100
+
+// Event is the JSON payload POSTed to the webhook
+type Event struct {
+	Operation   string    `json:"operation"`
+	Result      string    `json:"result"`
+	DeviceCount int       `json:"deviceCount"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Notifier posts events to a webhook from a background worker.
+// A nil *Notifier is valid and drops every event, so callers don't need to check
+// whether a webhook is configured.
+type Notifier struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger
+	queue  chan Event
+	done   chan struct{}
+}
+
+// New creates a Notifier and starts its delivery worker. It returns nil when url is empty.
+func New(url string, queueSize int, logger *slog.Logger) *Notifier {
+	if url == "" {
+		return nil
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	n := &Notifier{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger.With("component", "webhook"),
+		queue:  make(chan Event, queueSize),
+		done:   make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// Notify queues an event without blocking. When the queue is full the oldest event is dropped.
+func (n *Notifier) Notify(event Event) {
+	if n == nil {
+		return
+	}
+	for {
+		select {
+		case n.queue <- event:
+			return
+		default:
+		}
+		// Queue is full: discard the oldest event and try again
+		select {
+		case dropped := <-n.queue:
+			n.logger.Warn("Webhook queue full, dropping oldest event", "operation", dropped.Operation)
+		default:
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued events to be delivered
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	close(n.queue)
+	<-n.done
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+	for event := range n.queue {
+		if err := n.send(event); err != nil {
+			n.logger.Error("Failed to deliver webhook", "operation", event.Operation, "error", err)
+		}
+	}
+}
+
+func (n *Notifier) send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewWithoutURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	n := New("", 10, logger)
+	if n != nil {
+		t.Fatalf("expected nil notifier when no URL is configured")
+	}
+
+	// A nil notifier must be safe to use
+	n.Notify(Event{Operation: "turn_on"})
+	n.Close()
+}
+
+func TestNotifyDelivers(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		received <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	n := New(server.URL, 10, logger)
+	defer n.Close()
+
+	n.Notify(Event{Operation: "turn_on", Result: "success", DeviceCount: 2, Timestamp: time.Now()})
+
+	select {
+	case event := <-received:
+		if event.Operation != "turn_on" || event.Result != "success" || event.DeviceCount != 2 {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for webhook")
+	}
+}
+
+func TestNotifyDropsOldestWhenFull(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	// Build the notifier without starting the worker so the queue fills up
+	n := &Notifier{
+		logger: logger,
+		queue:  make(chan Event, 2),
+	}
+
+	n.Notify(Event{Operation: "first"})
+	n.Notify(Event{Operation: "second"})
+	n.Notify(Event{Operation: "third"})
+
+	if len(n.queue) != 2 {
+		t.Fatalf("expected queue length 2, got %d", len(n.queue))
+	}
+	if got := (<-n.queue).Operation; got != "second" {
+		t.Errorf("expected oldest remaining event to be second, got %s", got)
+	}
+	if got := (<-n.queue).Operation; got != "third" {
+		t.Errorf("expected newest event to be third, got %s", got)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Description summarizes what a route accepts, served in response to OPTIONS so clients
+// can introspect one endpoint without fetching the whole spec
+type Description struct {
+	Path string `json:"path"`
+	// Methods matches the Allow header
+	Methods    []string               `json:"methods"`
+	Operations []OperationDescription `json:"operations"`
+}
+
+// OperationDescription is one method of a route
+type OperationDescription struct {
+	Method  string  `json:"method"`
+	Summary string  `json:"summary,omitempty"`
+	Query   []Field `json:"query,omitempty"`
+	Body    *Body   `json:"body,omitempty"`
+}
+
+// Body describes a JSON request body
+type Body struct {
+	Required bool    `json:"required"`
+	Fields   []Field `json:"fields"`
+}
+
+// Field is one query parameter or body field, with its accepted range or values
+type Field struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Minimum     *float64 `json:"minimum,omitempty"`
+	Maximum     *float64 `json:"maximum,omitempty"`
+	Enum        []any    `json:"enum,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// schema is the subset of a JSON schema that descriptions use
+type schema struct {
+	Ref         string          `json:"$ref"`
+	Type        string          `json:"type"`
+	Description string          `json:"description"`
+	Minimum     *float64        `json:"minimum"`
+	Maximum     *float64        `json:"maximum"`
+	Enum        []any           `json:"enum"`
+	Required    []string        `json:"required"`
+	Properties  json.RawMessage `json:"properties"`
+	OneOf       []schema        `json:"oneOf"`
+}
+
+type parameter struct {
+	Ref         string `json:"$ref"`
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+	Schema      schema `json:"schema"`
+}
+
+type operation struct {
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]schema    `json:"schemas"`
+		Parameters map[string]parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+var (
+	descriptionsOnce sync.Once
+	descriptions     map[string]Description
+)
+
+// Describe returns the description of a path as written in the spec, e.g. "/lights/rgb"
+func Describe(path string) (Description, bool) {
+	descriptionsOnce.Do(func() {
+		var doc document
+		// The spec is checked to parse by the tests, so an error here can't happen at runtime
+		_ = json.Unmarshal(spec, &doc)
+		descriptions = make(map[string]Description, len(doc.Paths))
+		for path, operations := range doc.Paths {
+			descriptions[path] = doc.describe(path, operations)
+		}
+	})
+	description, ok := descriptions[path]
+	return description, ok
+}
+
+// Paths returns the spec's paths that are prefix or below it, sorted
+func Paths(prefix string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, endpoint := range Endpoints() {
+		if seen[endpoint.Path] {
+			continue
+		}
+		if endpoint.Path == prefix || strings.HasPrefix(endpoint.Path, prefix+"/") {
+			seen[endpoint.Path] = true
+			paths = append(paths, endpoint.Path)
+		}
+	}
+	return paths
+}
+
+// DescribeHandler answers OPTIONS for path with an Allow header and its Description
+func DescribeHandler(path string) http.HandlerFunc {
+	description, _ := Describe(path)
+	allow := strings.Join(description.Methods, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(description)
+	}
+}
+
+func (doc *document) describe(path string, operations map[string]operation) Description {
+	description := Description{Path: path, Methods: []string{http.MethodOptions}}
+	for _, method := range methodOrder {
+		op, ok := operations[method]
+		if !ok {
+			continue
+		}
+		method = strings.ToUpper(method)
+		description.Methods = append(description.Methods, method)
+		if _, write := operations["post"]; method == http.MethodGet && !write {
+			// ServeMux answers HEAD for every GET route, except the GET shortcuts for a
+			// POST, which reject it so a HEAD can't change the lights
+			description.Methods = append(description.Methods, http.MethodHead)
+		}
+
+		described := OperationDescription{Method: method, Summary: op.Summary}
+		for _, param := range op.Parameters {
+			if param.Ref != "" {
+				param = doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+			}
+			if param.In != "query" {
+				continue
+			}
+			field := doc.field(param.Name, param.Schema)
+			field.Required = param.Required
+			if param.Description != "" {
+				field.Description = param.Description
+			}
+			described.Query = append(described.Query, field)
+		}
+		if op.RequestBody != nil {
+			body := doc.object(op.RequestBody.Content["application/json"].Schema)
+			described.Body = &Body{Required: op.RequestBody.Required, Fields: doc.fields(body)}
+		}
+		description.Operations = append(description.Operations, described)
+	}
+	slices.Sort(description.Methods)
+	return description
+}
+
+// resolve follows a reference to a named schema
+func (doc *document) resolve(s schema) schema {
+	if s.Ref != "" {
+		return doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// object returns the object schema a body accepts; of alternatives, the first object wins
+func (doc *document) object(s schema) schema {
+	s = doc.resolve(s)
+	for _, alternative := range s.OneOf {
+		if alternative = doc.resolve(alternative); alternative.Properties != nil {
+			return alternative
+		}
+	}
+	return s
+}
+
+// fields lists an object schema's properties in the order the spec declares them
+func (doc *document) fields(s schema) []Field {
+	var properties map[string]schema
+	if err := json.Unmarshal(s.Properties, &properties); err != nil {
+		return nil
+	}
+	fields := make([]Field, 0, len(properties))
+	for _, name := range objectKeys(s.Properties) {
+		field := doc.field(name, properties[name])
+		field.Required = slices.Contains(s.Required, name)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func (doc *document) field(name string, s schema) Field {
+	s = doc.resolve(s)
+	return Field{
+		Name:        name,
+		Type:        s.Type,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		Enum:        s.Enum,
+		Description: s.Description,
+	}
+}
+
+// objectKeys returns the keys of a JSON object in document order, which a map loses
+func objectKeys(raw json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, token.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
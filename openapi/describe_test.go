@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestDescribeHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	DescribeHandler("/lights/rgb")(w, httptest.NewRequest("OPTIONS", "/lights/rgb", nil))
+
+	if allow := w.Header().Get("Allow"); allow != "OPTIONS, POST" {
+		t.Errorf("expected Allow OPTIONS, POST, got %q", allow)
+	}
+	var description Description
+	if err := json.Unmarshal(w.Body.Bytes(), &description); err != nil {
+		t.Fatalf("description is not valid JSON: %v", err)
+	}
+	if len(description.Operations) != 1 || description.Operations[0].Body == nil {
+		t.Fatalf("expected one operation with a body, got %+v", description.Operations)
+	}
+	body := description.Operations[0].Body
+	if !body.Required {
+		t.Error("expected the body to be required")
+	}
+	var names []string
+	for _, field := range body.Fields {
+		names = append(names, field.Name)
+	}
+	// Fields keep the spec's order
+	if !slices.Equal(names, []string{"r", "g", "b", "transition_ms"}) {
+		t.Errorf("expected fields r, g, b, transition_ms, got %v", names)
+	}
+	r := body.Fields[0]
+	if r.Type != "integer" || !r.Required || r.Minimum == nil || *r.Minimum != 0 || r.Maximum == nil || *r.Maximum != 255 {
+		t.Errorf("expected a required integer r in 0-255, got %+v", r)
+	}
+	if body.Fields[3].Required {
+		t.Error("expected transition_ms to be optional")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	description, ok := Describe("/lights/colortemp")
+	if !ok {
+		t.Fatal("expected /lights/colortemp to be described")
+	}
+	// The GET shortcut changes the lights, so HEAD isn't allowed
+	if !slices.Equal(description.Methods, []string{"GET", "OPTIONS", "POST"}) {
+		t.Errorf("expected GET, OPTIONS, POST, got %v", description.Methods)
+	}
+	get := description.Operations[0]
+	if get.Method != "GET" || len(get.Query) == 0 || get.Query[0].Name != "kelvin" || !get.Query[0].Required {
+		t.Errorf("expected GET to take a required kelvin query parameter, got %+v", get)
+	}
+	// Shared parameters are resolved from components
+	if !slices.ContainsFunc(get.Query, func(f Field) bool { return f.Name == "group" && f.Description != "" }) {
+		t.Errorf("expected the shared group parameter, got %+v", get.Query)
+	}
+
+	// A body with alternatives is described by its object form
+	batch, _ := Describe("/lights/batch")
+	if body := batch.Operations[0].Body; body == nil || !slices.ContainsFunc(body.Fields, func(f Field) bool { return f.Name == "operations" }) {
+		t.Errorf("expected the batch body to list operations, got %+v", batch.Operations[0].Body)
+	}
+
+	if _, ok := Describe("/nope"); ok {
+		t.Error("expected an unknown path not to be described")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	paths := Paths("/lights")
+	for _, want := range []string{"/lights", "/lights/on", "/lights/groups/{name}"} {
+		if !slices.Contains(paths, want) {
+			t.Errorf("expected %s in %v", want, paths)
+		}
+	}
+	if slices.Contains(paths, "/schedules") {
+		t.Errorf("expected only light routes, got %v", paths)
+	}
+	for _, path := range paths {
+		if description, ok := Describe(path); !ok || len(description.Operations) == 0 {
+			t.Errorf("expected %s to be described, got %+v", path, description)
+		}
+	}
+}
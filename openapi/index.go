@@ -0,0 +1,103 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jwhitcraft/lights-http/version"
+)
+
+// ServiceName identifies the service in the index response
+const ServiceName = "lights-http"
+
+// Endpoint is a single route listed in the index
+type Endpoint struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// Index is the landing response served at the root path
+type Index struct {
+	Service   string     `json:"service"`
+	Version   string     `json:"version"`
+	Docs      string     `json:"docs"`
+	OpenAPI   string     `json:"openapi"`
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// methodOrder lists methods in the order they're shown for a path
+var methodOrder = []string{"get", "post", "put", "patch", "delete"}
+
+var (
+	endpointsOnce sync.Once
+	endpoints     []Endpoint
+)
+
+// Endpoints returns every route described in the spec, sorted by path
+func Endpoints() []Endpoint {
+	endpointsOnce.Do(func() {
+		var doc struct {
+			Paths map[string]map[string]struct {
+				Summary string `json:"summary"`
+			} `json:"paths"`
+		}
+		// The spec is checked to parse by the tests, so an error here can't happen at runtime
+		_ = json.Unmarshal(spec, &doc)
+
+		paths := make([]string, 0, len(doc.Paths))
+		for path := range doc.Paths {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			for _, method := range methodOrder {
+				if operation, ok := doc.Paths[path][method]; ok {
+					endpoints = append(endpoints, Endpoint{
+						Method:  strings.ToUpper(method),
+						Path:    path,
+						Summary: operation.Summary,
+					})
+				}
+			}
+		}
+	})
+	return endpoints
+}
+
+// IndexHandler returns a handler listing the service's name, version and endpoints, with
+// paths prefixed by basePath so the links work behind a reverse proxy
+func IndexHandler(basePath string) http.HandlerFunc {
+	index := Index{
+		Service: ServiceName,
+		Version: version.Get().Version,
+		Docs:    basePath + "/docs",
+		OpenAPI: basePath + "/openapi.json",
+	}
+	for _, endpoint := range Endpoints() {
+		endpoint.Path = basePath + endpoint.Path
+		index.Endpoints = append(index.Endpoints, endpoint)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(index)
+	}
+}
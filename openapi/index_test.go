@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIndexHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/", nil)
+	w := httptest.NewRecorder()
+
+	IndexHandler("/api/v1")(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+	var index Index
+	if err := json.Unmarshal(w.Body.Bytes(), &index); err != nil {
+		t.Fatalf("index is not valid JSON: %v", err)
+	}
+	if index.Service != ServiceName {
+		t.Errorf("expected service %q, got %q", ServiceName, index.Service)
+	}
+	if index.Docs != "/api/v1/docs" {
+		t.Errorf("expected docs link under the base path, got %q", index.Docs)
+	}
+
+	found := false
+	for _, endpoint := range index.Endpoints {
+		if endpoint.Method == "POST" && endpoint.Path == "/api/v1/lights/on" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected POST /api/v1/lights/on in %v", index.Endpoints)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schedules runs light actions on cron schedules and persists them to a JSON file.
+package schedules
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Action is what a schedule does when it fires. It takes the same form as a batch step,
+// e.g. {"op": "warm", "brightness": 60}.
+type Action struct {
+	Op          string `json:"op"`
+	R           int    `json:"r,omitempty"`
+	G           int    `json:"g,omitempty"`
+	B           int    `json:"b,omitempty"`
+	Brightness  int    `json:"brightness,omitempty"`
+	Temperature int    `json:"temperature,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+// Schedule is a recurring action. Cron is a standard five-field expression such as
+// "0 8 * * *", evaluated in the server's local time zone.
+type Schedule struct {
+	ID        string     `json:"id"`
+	Cron      string     `json:"cron"`
+	Action    Action     `json:"action"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Next      *time.Time `json:"next,omitempty"`
+}
+
+// Runner carries out a schedule's action when it fires
+type Runner func(schedule Schedule) error
+
+// Scheduler runs schedules and saves them to a file after every change so they survive
+// restarts. It is safe for concurrent use, and a nil Scheduler has no schedules and
+// accepts none.
+type Scheduler struct {
+	mu        sync.Mutex
+	cron      *cron.Cron
+	path      string
+	run       Runner
+	logger    *slog.Logger
+	schedules map[string]Schedule
+	entries   map[string]cron.EntryID
+}
+
+// New returns a scheduler that calls run for each schedule that fires, loading any
+// schedules saved at path. An empty path keeps schedules in memory only. Call Start to
+// begin running them.
+func New(path string, run Runner, logger *slog.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		cron:      cron.New(),
+		path:      path,
+		run:       run,
+		logger:    logger.With("component", "schedules"),
+		schedules: make(map[string]Schedule),
+		entries:   make(map[string]cron.EntryID),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules file: %w", err)
+	}
+	var saved []Schedule
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules file: %w", err)
+	}
+	for _, schedule := range saved {
+		if err := s.schedule(schedule); err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", schedule.ID, err)
+		}
+	}
+	return s, nil
+}
+
+// Start runs schedules in the background
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops running schedules and waits for any running action to finish
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// Add validates the cron expression, starts running the schedule and saves it
+func (s *Scheduler) Add(spec string, action Action) (Schedule, error) {
+	if s == nil {
+		return Schedule{}, fmt.Errorf("scheduling is not configured")
+	}
+	schedule := Schedule{ID: newID(), Cron: spec, Action: action, CreatedAt: time.Now().UTC()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.schedule(schedule); err != nil {
+		return Schedule{}, err
+	}
+	if err := s.save(); err != nil {
+		s.remove(schedule.ID)
+		return Schedule{}, err
+	}
+	return s.withNext(schedule), nil
+}
+
+// List returns every schedule sorted by creation time, with the time each next fires
+func (s *Scheduler) List() []Schedule {
+	if s == nil {
+		return []Schedule{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		list = append(list, s.withNext(schedule))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Delete stops and removes a schedule, reporting whether it existed
+func (s *Scheduler) Delete(id string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return false, nil
+	}
+	s.remove(id)
+	if err := s.save(); err != nil {
+		// Keep running it so memory matches the file
+		s.schedule(schedule)
+		return true, err
+	}
+	return true, nil
+}
+
+// schedule registers a schedule with cron; the caller must hold mu or own s exclusively
+func (s *Scheduler) schedule(schedule Schedule) error {
+	entryID, err := s.cron.AddFunc(schedule.Cron, func() {
+		s.logger.Info("Running schedule", "schedule", schedule.ID, "op", schedule.Action.Op)
+		if err := s.run(schedule); err != nil {
+			s.logger.Error("Schedule failed", "schedule", schedule.ID, "error", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("invalid cron expression %q: %w", schedule.Cron, err)
+	}
+	s.schedules[schedule.ID] = schedule
+	s.entries[schedule.ID] = entryID
+	return nil
+}
+
+// remove unregisters a schedule from cron; the caller must hold mu
+func (s *Scheduler) remove(id string) {
+	s.cron.Remove(s.entries[id])
+	delete(s.entries, id)
+	delete(s.schedules, id)
+}
+
+// withNext fills in when the schedule next fires, which is only known once the scheduler
+// has started; the caller must hold mu
+func (s *Scheduler) withNext(schedule Schedule) Schedule {
+	if next := s.cron.Entry(s.entries[schedule.ID]).Next; !next.IsZero() {
+		schedule.Next = &next
+	}
+	return schedule
+}
+
+// save writes every schedule to the file, replacing it atomically; the caller must hold mu
+func (s *Scheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+	list := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		list = append(list, schedule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedules: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".schedules-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return nil
+}
+
+// newID returns a random schedule ID
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,62 @@
+package schedules
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+func TestScheduler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{}))
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	run := func(Schedule) error { return nil }
+
+	scheduler, err := New(path, run, logger)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := scheduler.Add("every morning", Action{Op: "on"}); err == nil {
+		t.Error("expected an error for an invalid cron expression")
+	}
+
+	added, err := scheduler.Add("0 8 * * *", Action{Op: "warm", Brightness: 60})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if added.ID == "" {
+		t.Error("expected the schedule to be given an ID")
+	}
+
+	// A new scheduler picks the schedule up from the file
+	reloaded, err := New(path, run, logger)
+	if err != nil {
+		t.Fatalf("New() after save error = %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 1 || list[0].ID != added.ID || list[0].Action.Brightness != 60 {
+		t.Fatalf("expected the saved schedule after reload, got %+v", list)
+	}
+
+	reloaded.Start()
+	defer reloaded.Stop()
+	if next := reloaded.List()[0].Next; next == nil || next.Hour() != 8 {
+		t.Errorf("expected the next run at 8:00 once started, got %v", next)
+	}
+
+	if ok, err := reloaded.Delete(added.ID); !ok || err != nil {
+		t.Fatalf("Delete() = %v, %v; want true, nil", ok, err)
+	}
+	if ok, _ := reloaded.Delete(added.ID); ok {
+		t.Error("expected deleting twice to report a missing schedule")
+	}
+
+	again, err := New(path, run, logger)
+	if err != nil {
+		t.Fatalf("New() after delete error = %v", err)
+	}
+	if list := again.List(); len(list) != 0 {
+		t.Errorf("expected no schedules after delete, got %+v", list)
+	}
+}
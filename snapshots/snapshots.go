@@ -0,0 +1,136 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshots stores named captures of device state so it can be reapplied later.
+package snapshots
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jwhitcraft/lights-http/controller"
+	govee "github.com/swrm-io/go-vee"
+)
+
+// DeviceState is the power, color and brightness of a device at capture time
+type DeviceState struct {
+	On         bool        `json:"on"`
+	Color      govee.Color `json:"color"`
+	Brightness int         `json:"brightness"`
+}
+
+// Capture reads a device's last reported state; call RequestStatus first for a fresh one
+func Capture(device *govee.Device) DeviceState {
+	return DeviceState{
+		On:         controller.PoweredOn(device),
+		Color:      device.Color(),
+		Brightness: int(device.Brightness()),
+	}
+}
+
+// Snapshot is a named set of device states keyed by device ID
+type Snapshot struct {
+	Name      string                 `json:"name"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Devices   map[string]DeviceState `json:"devices"`
+}
+
+// Store holds snapshots by name. It is safe for concurrent use, and a nil Store behaves
+// as an empty, read-only one.
+type Store struct {
+	mu        sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+// New returns an empty store
+func New() *Store {
+	return &Store{snapshots: make(map[string]Snapshot)}
+}
+
+// Save creates or replaces the snapshot with the same name
+func (s *Store) Save(snapshot Snapshot) error {
+	snapshot.Name = normalize(snapshot.Name)
+	if snapshot.Name == "" {
+		return fmt.Errorf("snapshot name must not be empty")
+	}
+	if s == nil {
+		return fmt.Errorf("snapshot store is not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[snapshot.Name] = copySnapshot(snapshot)
+	return nil
+}
+
+// Get returns the named snapshot
+func (s *Store) Get(name string) (Snapshot, bool) {
+	if s == nil {
+		return Snapshot{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.snapshots[normalize(name)]
+	if !ok {
+		return Snapshot{}, false
+	}
+	return copySnapshot(snapshot), true
+}
+
+// Delete removes a snapshot, reporting whether it existed
+func (s *Store) Delete(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = normalize(name)
+	if _, ok := s.snapshots[name]; !ok {
+		return false
+	}
+	delete(s.snapshots, name)
+	return true
+}
+
+// List returns every snapshot sorted by name
+func (s *Store) List() []Snapshot {
+	list := []Snapshot{}
+	if s == nil {
+		return list
+	}
+	s.mu.RLock()
+	for _, snapshot := range s.snapshots {
+		list = append(list, copySnapshot(snapshot))
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// copySnapshot returns a snapshot that shares no maps with the original
+func copySnapshot(snapshot Snapshot) Snapshot {
+	devices := make(map[string]DeviceState, len(snapshot.Devices))
+	for deviceID, state := range snapshot.Devices {
+		devices[deviceID] = state
+	}
+	snapshot.Devices = devices
+	return snapshot
+}
+
+// normalize trims and lowercases a snapshot name so lookups are case-insensitive
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
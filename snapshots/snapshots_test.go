@@ -0,0 +1,67 @@
+package snapshots
+
+import (
+	"testing"
+
+	govee "github.com/swrm-io/go-vee"
+)
+
+func TestStore(t *testing.T) {
+	store := New()
+
+	if err := store.Save(Snapshot{Name: " "}); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+
+	snapshot := Snapshot{
+		Name:    "Evening",
+		Devices: map[string]DeviceState{"AA": {On: true, Color: govee.Color{R: 255}, Brightness: 40}},
+	}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	snapshot.Devices["BB"] = DeviceState{}
+
+	got, ok := store.Get("evening")
+	if !ok {
+		t.Fatal("expected lookups to be case-insensitive")
+	}
+	if len(got.Devices) != 1 || got.Devices["AA"].Brightness != 40 {
+		t.Errorf("expected the saved device states, got %+v", got.Devices)
+	}
+
+	if err := store.Save(Snapshot{Name: "alert"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	list := store.List()
+	if len(list) != 2 || list[0].Name != "alert" || list[1].Name != "evening" {
+		t.Errorf("expected snapshots sorted by name, got %+v", list)
+	}
+
+	if !store.Delete("EVENING") {
+		t.Error("expected Delete to report the snapshot existed")
+	}
+	if store.Delete("evening") {
+		t.Error("expected a second Delete to report nothing was removed")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	if err := store.Save(Snapshot{Name: "alert"}); err == nil {
+		t.Error("expected an error saving to a nil store")
+	}
+	if _, ok := store.Get("alert"); ok {
+		t.Error("expected a nil store to be empty")
+	}
+	if len(store.List()) != 0 {
+		t.Error("expected a nil store to list nothing")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	state := Capture(&govee.Device{})
+	if state.On || state.Brightness != 0 {
+		t.Errorf("expected a zero device to capture as off, got %+v", state)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solar
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Auto runs an action at every sunrise and sunset at a location while it is enabled. It
+// is safe for concurrent use.
+type Auto struct {
+	latitude  float64
+	longitude float64
+	run       func(event string) error
+	logger    *slog.Logger
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// Status reports whether auto mode is on and, if so, the event it is waiting for
+type Status struct {
+	Enabled   bool    `json:"enabled"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Next      *Event  `json:"next,omitempty"`
+}
+
+// NewAuto returns a disabled auto mode that calls run with Sunrise or Sunset as each
+// happens at the location
+func NewAuto(latitude, longitude float64, run func(event string) error, logger *slog.Logger) *Auto {
+	return &Auto{
+		latitude:  latitude,
+		longitude: longitude,
+		run:       run,
+		logger:    logger.With("component", "solar"),
+	}
+}
+
+// Enable starts waiting for the next sunrise or sunset; it does nothing if already enabled
+func (a *Auto) Enable() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		return
+	}
+	a.stop = make(chan struct{})
+	go a.loop(a.stop)
+	a.logger.Info("Sunrise/sunset mode enabled", "latitude", a.latitude, "longitude", a.longitude)
+}
+
+// Disable stops auto mode; it does nothing if already disabled
+func (a *Auto) Disable() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	a.stop = nil
+	a.logger.Info("Sunrise/sunset mode disabled")
+}
+
+// Status reports the current state
+func (a *Auto) Status() Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	status := Status{Enabled: a.stop != nil, Latitude: a.latitude, Longitude: a.longitude}
+	if status.Enabled {
+		if event, ok := NextEvent(time.Now(), a.latitude, a.longitude); ok {
+			status.Next = &event
+		}
+	}
+	return status
+}
+
+// loop runs each event as it comes until stop is closed
+func (a *Auto) loop(stop chan struct{}) {
+	for {
+		event, ok := NextEvent(time.Now(), a.latitude, a.longitude)
+		if !ok {
+			a.logger.Warn("No sunrise or sunset in the coming year at this location")
+			return
+		}
+		timer := time.NewTimer(time.Until(event.At))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		a.logger.Info("Running sunrise/sunset action", "event", event.Name)
+		if err := a.run(event.Name); err != nil {
+			a.logger.Error("Sunrise/sunset action failed", "event", event.Name, "error", err)
+		}
+	}
+}
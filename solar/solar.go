@@ -0,0 +1,95 @@
+// Copyright 2025 Jon Whitcraft
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package solar computes sunrise and sunset times and switches the lights at them.
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// Events a location sees each day
+const (
+	Sunrise = "sunrise"
+	Sunset  = "sunset"
+)
+
+// j2000 is the Julian date of 2000-01-01 12:00 UTC
+const j2000 = 2451545.0
+
+// Event is a sunrise or sunset at a particular time
+type Event struct {
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+// SunTimes returns sunrise and sunset on the calendar day of date (in date's location) at
+// the given latitude and longitude in degrees, east and north positive. It uses the
+// sunrise equation, which is accurate to a minute or two away from the poles. ok is false
+// when the sun doesn't rise or set that day.
+func SunTimes(date time.Time, latitude, longitude float64) (sunrise, sunset time.Time, ok bool) {
+	year, month, day := date.Date()
+	noon := time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	days := math.Round(julianDate(noon) - j2000)
+
+	// Mean solar time at the location, then the sun's position along the ecliptic
+	meanSolarTime := days - longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanSolarTime, 360)
+	center := 1.9148*sin(anomaly) + 0.0200*sin(2*anomaly) + 0.0003*sin(3*anomaly)
+	eclipticLongitude := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := j2000 + meanSolarTime + 0.0053*sin(anomaly) - 0.0069*sin(2*eclipticLongitude)
+
+	declination := math.Asin(sin(eclipticLongitude) * sin(23.4397))
+	// -0.833 degrees allows for refraction and the size of the sun's disc
+	cosHourAngle := (sin(-0.833) - sin(latitude)*math.Sin(declination)) / (cos(latitude) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+
+	loc := date.Location()
+	return fromJulianDate(transit - hourAngle/360).In(loc), fromJulianDate(transit + hourAngle/360).In(loc), true
+}
+
+// NextEvent returns the first sunrise or sunset after now. ok is false if there is none
+// in the coming year, as near the poles.
+func NextEvent(now time.Time, latitude, longitude float64) (Event, bool) {
+	// Start a day early: west of the observer's time zone a day's sunset can fall on the
+	// next calendar day
+	for offset := -1; offset <= 366; offset++ {
+		sunrise, sunset, ok := SunTimes(now.AddDate(0, 0, offset), latitude, longitude)
+		if !ok {
+			continue
+		}
+		if sunrise.After(now) {
+			return Event{Name: Sunrise, At: sunrise}, true
+		}
+		if sunset.After(now) {
+			return Event{Name: Sunset, At: sunset}, true
+		}
+	}
+	return Event{}, false
+}
+
+func julianDate(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+func fromJulianDate(jd float64) time.Time {
+	return time.Unix(0, int64((jd-2440587.5)*86400*float64(time.Second))).UTC()
+}
+
+func sin(degrees float64) float64 { return math.Sin(degrees * math.Pi / 180) }
+func cos(degrees float64) float64 { return math.Cos(degrees * math.Pi / 180) }
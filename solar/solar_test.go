@@ -0,0 +1,78 @@
+package solar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		date      time.Time
+		latitude  float64
+		longitude float64
+		sunrise   string
+		sunset    string
+	}{
+		// Published times from the NOAA solar calculator, rounded to the minute
+		{"new york summer solstice", time.Date(2024, 6, 21, 0, 0, 0, 0, newYork), 40.7128, -74.0060, "05:25", "20:31"},
+		{"new york winter solstice", time.Date(2024, 12, 21, 0, 0, 0, 0, newYork), 40.7128, -74.0060, "07:17", "16:32"},
+		{"sydney", time.Date(2024, 3, 20, 0, 0, 0, 0, time.FixedZone("AEDT", 11*3600)), -33.8688, 151.2093, "06:58", "19:07"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sunrise, sunset, ok := SunTimes(tt.date, tt.latitude, tt.longitude)
+			if !ok {
+				t.Fatal("expected the sun to rise and set")
+			}
+			assertClock(t, "sunrise", sunrise, tt.date, tt.sunrise)
+			assertClock(t, "sunset", sunset, tt.date, tt.sunset)
+		})
+	}
+}
+
+// assertClock checks that got is within three minutes of the wall-clock time want on day
+func assertClock(t *testing.T, name string, got, day time.Time, want string) {
+	t.Helper()
+	clock, err := time.Parse("15:04", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+	if diff := got.Sub(expected).Abs(); diff > 3*time.Minute {
+		t.Errorf("%s = %s, want %s (off by %s)", name, got.Format("15:04"), want, diff)
+	}
+}
+
+func TestSunTimesPolar(t *testing.T) {
+	// Tromsø has midnight sun at the solstice
+	if _, _, ok := SunTimes(time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), 69.6492, 18.9553); ok {
+		t.Error("expected no sunrise or sunset during the midnight sun")
+	}
+}
+
+func TestNextEvent(t *testing.T) {
+	zone := time.FixedZone("EDT", -4*3600)
+	morning := time.Date(2024, 6, 21, 3, 0, 0, 0, zone)
+
+	event, ok := NextEvent(morning, 40.7128, -74.0060)
+	if !ok || event.Name != Sunrise || event.At.Day() != 21 {
+		t.Fatalf("expected sunrise on the 21st, got %+v", event)
+	}
+
+	event, ok = NextEvent(event.At.Add(time.Minute), 40.7128, -74.0060)
+	if !ok || event.Name != Sunset || event.At.In(zone).Day() != 21 {
+		t.Fatalf("expected sunset on the 21st, got %+v", event)
+	}
+
+	event, ok = NextEvent(event.At.Add(time.Minute), 40.7128, -74.0060)
+	if !ok || event.Name != Sunrise || event.At.In(zone).Day() != 22 {
+		t.Errorf("expected sunrise on the 22nd, got %+v", event)
+	}
+}
@@ -0,0 +1,34 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	Version, Commit, BuildDate = "1.2.3", "abc123", "2025-01-01T00:00:00Z"
+	defer func() { Version, Commit, BuildDate = "dev", "unknown", "unknown" }()
+
+	req := httptest.NewRequest("GET", "/version", nil)
+	w := httptest.NewRecorder()
+	Handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var info Info
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	expected := Info{Version: "1.2.3", Commit: "abc123", BuildDate: "2025-01-01T00:00:00Z", GoVersion: runtime.Version()}
+	if info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+}